@@ -0,0 +1,71 @@
+// Package main demonstrates the usage of the xgen verification and coupon code functionality.
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hotfixfirst/go-xgen"
+)
+
+func main() {
+	fmt.Println("=== Code Examples ===")
+	fmt.Println()
+
+	opts := xgen.DefaultCodeOptions()
+
+	// Example 1: Generate Code
+	fmt.Println("1. Generate Code (12 symbols, check symbol)")
+	fmt.Println("-------------------------------------------")
+	code, err := xgen.GenerateCode(opts)
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	fmt.Printf("   Code: %s\n", code)
+	fmt.Println()
+
+	// Example 2: Short Email Verification Code
+	fmt.Println("2. Short Verification Code")
+	fmt.Println("--------------------------")
+	short, _ := xgen.GenerateCode(xgen.CodeOptions{Length: 6, GroupSize: 3})
+	fmt.Printf("   Code: %s\n", short)
+	fmt.Println()
+
+	// Example 3: Normalize User Input
+	fmt.Println("3. Normalize User Input")
+	fmt.Println("-----------------------")
+	typed := strings.ToLower(strings.ReplaceAll(code, "-", " "))
+	normalized, err := xgen.NormalizeCode(typed, opts)
+	fmt.Printf("   Typed:      %q\n", typed)
+	fmt.Printf("   Normalized: %s (err=%v) ✓\n", normalized, err)
+	fmt.Println()
+
+	// Example 4: Detect Typos
+	fmt.Println("4. Detect Typos (check symbol)")
+	fmt.Println("------------------------------")
+	typo := []byte(code)
+	if typo[0] == 'A' {
+		typo[0] = 'B'
+	} else {
+		typo[0] = 'A'
+	}
+	fmt.Printf("   Input: %s\n", typo)
+	fmt.Printf("   Valid: %t ✗\n", xgen.IsValidCode(string(typo), opts))
+	fmt.Println()
+
+	// Example 5: Batch of Unique Codes
+	fmt.Println("5. Batch of Unique Gift Card Codes")
+	fmt.Println("----------------------------------")
+	codes, err := xgen.GenerateCodes(5, opts)
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	for i, c := range codes {
+		fmt.Printf("   [%d] %s\n", i+1, c)
+	}
+	fmt.Println()
+
+	fmt.Println("=== End of Examples ===")
+}
@@ -0,0 +1,99 @@
+// Package main demonstrates the usage of the xgen encryption functionality.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hotfixfirst/go-xgen"
+)
+
+func main() {
+	fmt.Println("=== Encryption Examples ===")
+	fmt.Println()
+
+	// Configuration: encryption keys derived from a master secret
+	master, err := xgen.NewMasterKey("0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0", xgen.MasterKeyOptions{})
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	janKey, _ := master.DeriveKey(xgen.KeyPurposeTokenEncryption+"/2024-01", 32)
+	junKey, _ := master.DeriveKey(xgen.KeyPurposeTokenEncryption+"/2024-06", 32)
+	partnerSecret, _ := xgen.GenerateSecretKey()
+	ad := []byte("partners/42/secret") // binds the envelope to its database row
+
+	// Example 1: AES-256-GCM
+	fmt.Println("1. AES-256-GCM")
+	fmt.Println("--------------")
+	keyring, err := xgen.NewEncryptionKeyring(xgen.EncryptionKey{ID: "2024-01", Key: janKey, State: xgen.KeyStatePrimary})
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	envelope, _ := keyring.Seal([]byte(partnerSecret), ad)
+	fmt.Printf("   Envelope: %s\n", shorten(envelope))
+	plaintext, err := keyring.Open(envelope, ad)
+	fmt.Printf("   Opened:   %t ✓\n", err == nil && string(plaintext) == partnerSecret)
+	fmt.Println()
+
+	// Example 2: XChaCha20-Poly1305
+	fmt.Println("2. XChaCha20-Poly1305")
+	fmt.Println("---------------------")
+	xKey, _ := master.DeriveKey(xgen.KeyPurposeTokenEncryption+"/x-2024-01", 32)
+	xkeyring, _ := xgen.NewEncryptionKeyring(xgen.EncryptionKey{
+		ID: "x-2024-01", Algorithm: xgen.EncryptionXChaCha20Poly1305, Key: xKey, State: xgen.KeyStatePrimary,
+	})
+	xenvelope, _ := xkeyring.Seal([]byte(partnerSecret), ad)
+	fmt.Printf("   Envelope: %s\n", shorten(xenvelope))
+	plaintext, err = xkeyring.Open(xenvelope, ad)
+	fmt.Printf("   Opened:   %t ✓\n", err == nil && string(plaintext) == partnerSecret)
+	fmt.Println()
+
+	// Example 3: Associated Data
+	fmt.Println("3. Associated Data")
+	fmt.Println("------------------")
+	_, err = keyring.Open(envelope, []byte("partners/43/secret"))
+	fmt.Printf("   Copied to another row: %v ✗\n", err)
+	fmt.Println()
+
+	// Example 4: Tampered Envelope
+	fmt.Println("4. Tampered Envelope")
+	fmt.Println("--------------------")
+	tampered := envelope[:len(envelope)-2] + "AA"
+	_, err = keyring.Open(tampered, ad)
+	fmt.Printf("   Tampered: %v ✗\n", err)
+	_, err = keyring.Open("v1.aes-256-gcm.2023-01.AAAA.AAAA", ad)
+	fmt.Printf("   Unknown key: %t ✗\n", errors.Is(err, xgen.ErrUnknownEncryptionKey))
+	fmt.Println()
+
+	// Example 5: Key Rotation with Re-encryption on Read
+	fmt.Println("5. Key Rotation with Re-encryption on Read")
+	fmt.Println("------------------------------------------")
+	_ = keyring.Rotate(xgen.EncryptionKey{ID: "2024-06", Algorithm: xgen.EncryptionXChaCha20Poly1305, Key: junKey})
+	plaintext, current, resealed, err := keyring.OpenAndReseal(envelope, ad)
+	fmt.Printf("   Opened old envelope: %t ✓\n", err == nil && string(plaintext) == partnerSecret)
+	fmt.Printf("   Resealed: %t -> %s\n", resealed, shorten(current))
+	_, _, resealed, _ = keyring.OpenAndReseal(current, ad)
+	fmt.Printf("   Resealed again: %t\n", resealed)
+	fmt.Println()
+
+	// Example 6: Retire the Old Key
+	fmt.Println("6. Retire the Old Key")
+	fmt.Println("---------------------")
+	keyring.Remove("2024-01")
+	_, err = keyring.Open(envelope, ad)
+	fmt.Printf("   Old envelope: %v ✗\n", err)
+	_, err = keyring.Open(current, ad)
+	fmt.Printf("   Resealed envelope: %v ✓\n", err)
+	fmt.Println()
+
+	fmt.Println("=== End of Examples ===")
+}
+
+// shorten returns the envelope header and the start of its ciphertext.
+func shorten(envelope string) string {
+	parts := strings.Split(envelope, ".")
+	return strings.Join(parts[:3], ".") + ".<nonce>." + parts[4][:12] + "..."
+}
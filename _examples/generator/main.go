@@ -89,5 +89,34 @@ func main() {
 	}
 	fmt.Println()
 
+	// Example 8: Random String (Custom Alphabets)
+	fmt.Println("8. Random String (Custom Alphabets)")
+	fmt.Println("-----------------------------------")
+	base62, _ := xgen.RandomString(xgen.AlphabetBase62, 16)
+	fmt.Printf("   Base62 (16 chars): %s\n", base62)
+	digits, _ := xgen.RandomString(xgen.AlphabetDigits, 8)
+	fmt.Printf("   Digits (8 chars):  %s\n", digits)
+	custom, _ := xgen.RandomString("ACGT", 12)
+	fmt.Printf("   Custom \"ACGT\":    %s\n", custom)
+	_, err = xgen.RandomString("aa", 8)
+	fmt.Printf("   Invalid alphabet:  %v ✗\n", err)
+	fmt.Println()
+
+	// Example 9: Secure Random Numbers
+	fmt.Println("9. Secure Random Numbers")
+	fmt.Println("------------------------")
+	roll, _ := xgen.RandomInt(1, 6)
+	fmt.Printf("   Dice roll [1, 6]:  %d\n", roll)
+	f, _ := xgen.RandomFloat64()
+	fmt.Printf("   Float64 [0, 1):    %.6f\n", f)
+	deck := []string{"A", "K", "Q", "J", "10"}
+	_ = xgen.RandomShuffle(deck)
+	fmt.Printf("   Shuffled deck:     %v\n", deck)
+	winners, _ := xgen.RandomSample([]string{"alice", "bob", "carol", "dave", "erin"}, 2)
+	fmt.Printf("   Lottery winners:   %v\n", winners)
+	variant, _ := xgen.RandomWeightedChoice([]string{"control", "treatment"}, []int{90, 10})
+	fmt.Printf("   A/B variant 90/10: %s\n", variant)
+	fmt.Println()
+
 	fmt.Println("=== End of Examples ===")
 }
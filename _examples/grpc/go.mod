@@ -0,0 +1,24 @@
+module github.com/hotfixfirst/go-xgen/_examples/grpc
+
+go 1.25.5
+
+require (
+	github.com/hotfixfirst/go-xgen v0.0.0-00010101000000-000000000000
+	github.com/hotfixfirst/go-xgen/xgengrpc v0.0.0-00010101000000-000000000000
+	google.golang.org/grpc v1.84.0
+)
+
+require (
+	github.com/google/uuid v1.6.0 // indirect
+	golang.org/x/crypto v0.54.0 // indirect
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+)
+
+replace (
+	github.com/hotfixfirst/go-xgen => ../../
+	github.com/hotfixfirst/go-xgen/xgengrpc => ../../xgengrpc
+)
@@ -0,0 +1,125 @@
+// Package main demonstrates the usage of the xgen gRPC signing interceptors.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/hotfixfirst/go-xgen"
+	"github.com/hotfixfirst/go-xgen/xgengrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// healthServer reports the key ID that signed each call.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+}
+
+func (healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	keyID, _ := xgen.SignatureKeyIDFromContext(ctx)
+	fmt.Printf("   Server: %s verified by %s\n", req.Service, keyID)
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	keyID, _ := xgen.SignatureKeyIDFromContext(stream.Context())
+	fmt.Printf("   Server: stream verified by %s\n", keyID)
+	return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+}
+
+func main() {
+	fmt.Println("=== gRPC Examples ===")
+	fmt.Println()
+
+	// Example 1: Server Interceptors
+	fmt.Println("1. Server Interceptors")
+	fmt.Println("----------------------")
+	serverOpts := xgengrpc.ServerOptions{
+		LookupSecret:     xgen.MapSecretLookup(map[string]string{"client-1": "secret-1"}),
+		NonceStore:       xgen.NewMemoryNonceStore(xgen.MemoryNonceStoreOptions{}),
+		RequiredMetadata: []string{"x-tenant-id"},
+	}
+	unary, _ := xgengrpc.NewUnaryServerInterceptor(serverOpts)
+	stream, _ := xgengrpc.NewStreamServerInterceptor(serverOpts)
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream))
+	healthpb.RegisterHealthServer(server, healthServer{})
+	go server.Serve(listener)
+	defer server.Stop()
+	fmt.Println("   Listening on an in-process bufconn listener")
+	fmt.Println()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "acme")
+	req := &healthpb.HealthCheckRequest{Service: "orders"}
+
+	// Example 2: Signed Unary Call
+	fmt.Println("2. Signed Unary Call")
+	fmt.Println("--------------------")
+	client := dial(listener, &xgengrpc.ClientOptions{
+		KeyID:          "client-1",
+		Secret:         "secret-1",
+		Nonce:          true,
+		SignedMetadata: []string{"x-tenant-id"},
+	})
+	resp, err := client.Check(ctx, req)
+	printResult(resp, err)
+	fmt.Println()
+
+	// Example 3: Signed Stream
+	fmt.Println("3. Signed Stream")
+	fmt.Println("----------------")
+	watch, err := client.Watch(ctx, req)
+	if err == nil {
+		resp, err = watch.Recv()
+	}
+	printResult(resp, err)
+	fmt.Println()
+
+	// Example 4: Rejected Calls
+	fmt.Println("4. Rejected Calls")
+	fmt.Println("-----------------")
+	fmt.Println("   Unsigned:")
+	_, err = dial(listener, nil).Check(ctx, req)
+	printResult(nil, err)
+	fmt.Println("   Wrong secret:")
+	_, err = dial(listener, &xgengrpc.ClientOptions{
+		KeyID: "client-1", Secret: "wrong", Nonce: true, SignedMetadata: []string{"x-tenant-id"},
+	}).Check(ctx, req)
+	printResult(nil, err)
+	fmt.Println("   Tenant not signed:")
+	_, err = dial(listener, &xgengrpc.ClientOptions{KeyID: "client-1", Secret: "secret-1", Nonce: true}).Check(ctx, req)
+	printResult(nil, err)
+	fmt.Println()
+
+	fmt.Println("=== End of Examples ===")
+}
+
+// dial connects to the bufconn listener, signing calls when opts is not nil.
+func dial(listener *bufconn.Listener, opts *xgengrpc.ClientOptions) healthpb.HealthClient {
+	dialOpts := []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+	if opts != nil {
+		unary, _ := xgengrpc.NewUnaryClientInterceptor(*opts)
+		stream, _ := xgengrpc.NewStreamClientInterceptor(*opts)
+		dialOpts = append(dialOpts, grpc.WithUnaryInterceptor(unary), grpc.WithStreamInterceptor(stream))
+	}
+	conn, _ := grpc.NewClient("passthrough:///bufnet", dialOpts...)
+	return healthpb.NewHealthClient(conn)
+}
+
+// printResult prints the response status or the gRPC error code.
+func printResult(resp *healthpb.HealthCheckResponse, err error) {
+	if err != nil {
+		fmt.Printf("   Client: %s\n", status.Code(err))
+		return
+	}
+	fmt.Printf("   Client: %s\n", resp.Status)
+}
@@ -0,0 +1,130 @@
+// Package main demonstrates the usage of the xgen JWT functionality.
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hotfixfirst/go-xgen"
+)
+
+func main() {
+	fmt.Println("=== JWT Examples ===")
+	fmt.Println()
+
+	// Configuration: a fixed Ed25519 key and clock so the output is reproducible
+	seed := make([]byte, ed25519.SeedSize)
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	now := time.Unix(1704067200, 0)
+	clock := func() time.Time { return now }
+	claims := xgen.JWTClaims{
+		Issuer:    "https://auth.example.com",
+		Subject:   "user-42",
+		Audience:  []string{"web"},
+		IssuedAt:  now,
+		ExpiresAt: now.Add(15 * time.Minute),
+		Extra:     map[string]any{"role": "admin"},
+	}
+
+	// Example 1: HS256 with a Keyring Secret
+	fmt.Println("1. HS256 with a Keyring Secret")
+	fmt.Println("------------------------------")
+	keyring, _ := xgen.NewKeyring(xgen.SigningKey{ID: "hs-1", Secret: "shared-secret", State: xgen.KeyStatePrimary})
+	token, err := xgen.SignJWT(claims, xgen.SignJWTOptions{Keyring: keyring})
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	fmt.Printf("   Header: %s\n", decodeSegment(token, 0))
+	fmt.Printf("   Claims: %s\n", decodeSegment(token, 1))
+	verified, err := xgen.VerifyJWT(token, xgen.VerifyJWTOptions{
+		Algorithms: []xgen.JWTAlgorithm{xgen.JWTAlgorithmHS256},
+		Keyring:    keyring,
+		Issuer:     "https://auth.example.com",
+		Audience:   "web",
+		Now:        clock,
+	})
+	fmt.Printf("   Verified: %v, subject %s ✓\n", err, verified.Subject)
+	fmt.Println()
+
+	// Example 2: HS512
+	fmt.Println("2. HS512")
+	fmt.Println("--------")
+	hs512, _ := xgen.NewHMACSigner(xgen.SignatureAlgorithmHMACSHA512, "shared-secret")
+	token, _ = xgen.SignJWT(claims, xgen.SignJWTOptions{Signer: hs512, KeyID: "hs-512"})
+	fmt.Printf("   Header: %s\n", decodeSegment(token, 0))
+	_, err = xgen.VerifyJWT(token, xgen.VerifyJWTOptions{
+		Algorithms: []xgen.JWTAlgorithm{xgen.JWTAlgorithmHS512}, Verifier: hs512, Audience: "web", Now: clock,
+	})
+	fmt.Printf("   Verified: %v ✓\n", err)
+	fmt.Println()
+
+	// Example 3: EdDSA with a Public Key
+	fmt.Println("3. EdDSA with a Public Key")
+	fmt.Println("--------------------------")
+	edSigner, _ := xgen.NewEd25519Signer(privateKey)
+	edVerifier, _ := xgen.NewEd25519Verifier(privateKey.Public().(ed25519.PublicKey))
+	issuer, _ := xgen.NewKeyring(xgen.SigningKey{ID: "ed-1", Signer: edSigner, State: xgen.KeyStatePrimary})
+	api, _ := xgen.NewKeyring(xgen.SigningKey{ID: "ed-1", Verifier: edVerifier})
+	edToken, _ := xgen.SignJWT(claims, xgen.SignJWTOptions{Keyring: issuer})
+	fmt.Printf("   Header: %s\n", decodeSegment(edToken, 0))
+	eddsa := xgen.VerifyJWTOptions{Algorithms: []xgen.JWTAlgorithm{xgen.JWTAlgorithmEdDSA}, Keyring: api, Audience: "web", Now: clock}
+	_, err = xgen.VerifyJWT(edToken, eddsa)
+	fmt.Printf("   Verified with public key only: %v ✓\n", err)
+	fmt.Println()
+
+	// Example 4: Algorithm Allow-List
+	fmt.Println("4. Algorithm Allow-List")
+	fmt.Println("-----------------------")
+	none := encodeSegment(`{"alg":"none"}`) + "." + encodeSegment(`{"sub":"admin","aud":"web","exp":1704068100}`) + "."
+	_, err = xgen.VerifyJWT(none, eddsa)
+	fmt.Printf("   alg none: %v ✗\n", err)
+	_, err = xgen.VerifyJWT(token, eddsa)
+	fmt.Printf("   HS512 when only EdDSA is allowed: %v ✗\n", err)
+	fmt.Println()
+
+	// Example 5: Claim Validation
+	fmt.Println("5. Claim Validation")
+	fmt.Println("-------------------")
+	later := eddsa
+	later.Now = func() time.Time { return now.Add(20 * time.Minute) }
+	_, err = xgen.VerifyJWT(edToken, later)
+	fmt.Printf("   After exp: %v ✗\n", err)
+	later.Leeway = 10 * time.Minute
+	_, err = xgen.VerifyJWT(edToken, later)
+	fmt.Printf("   With 10m leeway: %v ✓\n", err)
+	mobile := eddsa
+	mobile.Audience = "mobile"
+	_, err = xgen.VerifyJWT(edToken, mobile)
+	fmt.Printf("   Wrong audience: %v ✗\n", err)
+	wrongIssuer := eddsa
+	wrongIssuer.Issuer = "https://evil.example.com"
+	_, err = xgen.VerifyJWT(edToken, wrongIssuer)
+	fmt.Printf("   Wrong issuer: %v ✗\n", err)
+	fmt.Println()
+
+	// Example 6: Tampered Claims
+	fmt.Println("6. Tampered Claims")
+	fmt.Println("------------------")
+	parts := strings.Split(edToken, ".")
+	parts[1] = encodeSegment(strings.Replace(decodeSegment(edToken, 1), `"user-42"`, `"user-1"`, 1))
+	_, err = xgen.VerifyJWT(strings.Join(parts, "."), eddsa)
+	fmt.Printf("   Changed subject: %v ✗\n", err)
+	fmt.Println()
+
+	fmt.Println("=== End of Examples ===")
+}
+
+// decodeSegment returns the decoded header (0) or claims (1) of a token.
+func decodeSegment(token string, i int) string {
+	data, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[i])
+	return string(data)
+}
+
+// encodeSegment base64url-encodes a JSON segment.
+func encodeSegment(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
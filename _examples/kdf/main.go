@@ -0,0 +1,80 @@
+// Package main demonstrates the usage of the xgen key derivation functionality.
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/hotfixfirst/go-xgen"
+)
+
+func main() {
+	fmt.Println("=== Key Derivation Examples ===")
+	fmt.Println()
+
+	// Configuration: a fixed master secret so the output is reproducible.
+	// In production, use xgen.GenerateSecretKey() once and store the result.
+	masterSecret := "0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0"
+
+	// Example 1: HKDF-SHA256
+	fmt.Println("1. HKDF-SHA256")
+	fmt.Println("--------------")
+	key, err := xgen.DeriveKey(xgen.KeyDerivationSHA256, []byte(masterSecret), []byte("salt"), "orders/encryption", 32)
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	fmt.Printf("   Key: %s\n", hex.EncodeToString(key))
+	fmt.Println()
+
+	// Example 2: HKDF-SHA512
+	fmt.Println("2. HKDF-SHA512")
+	fmt.Println("--------------")
+	key, _ = xgen.DeriveKey(xgen.KeyDerivationSHA512, []byte(masterSecret), []byte("salt"), "orders/encryption", 32)
+	fmt.Printf("   Key: %s\n", hex.EncodeToString(key))
+	fmt.Println()
+
+	// Example 3: Purpose-Bound Keys
+	fmt.Println("3. Purpose-Bound Keys")
+	fmt.Println("---------------------")
+	master, err := xgen.NewMasterKey(masterSecret, xgen.MasterKeyOptions{})
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	signingSecret, _ := master.DeriveSecret(xgen.KeyPurposeSigning)
+	pepper, _ := master.DeriveSecret(xgen.KeyPurposePasswordPepper)
+	encryptionKey, _ := master.DeriveKey(xgen.KeyPurposeTokenEncryption, 32)
+	fmt.Printf("   Signing:          %s\n", signingSecret)
+	fmt.Printf("   Password pepper:  %s\n", pepper)
+	fmt.Printf("   Token encryption: %s\n", hex.EncodeToString(encryptionKey))
+	fmt.Println()
+
+	// Example 4: Using Derived Secrets
+	fmt.Println("4. Using Derived Secrets")
+	fmt.Println("------------------------")
+	signature, _ := xgen.GenerateSignature(signingSecret, "POST", "/api/v1/orders", "1704067200", `{"id":1}`)
+	fmt.Printf("   Signature: %s...\n", signature[:16])
+	hash, _ := xgen.GeneratePasswordHash(pepper, "user-password-123")
+	fmt.Printf("   Password valid: %t ✓\n", xgen.ComparePasswordHash(pepper, "user-password-123", hash))
+	fmt.Printf("   Master secret as pepper: %t ✗\n", xgen.ComparePasswordHash(masterSecret, "user-password-123", hash))
+	fmt.Println()
+
+	// Example 5: Salted Master Key
+	fmt.Println("5. Salted Master Key")
+	fmt.Println("--------------------")
+	staging, _ := xgen.NewMasterKey(masterSecret, xgen.MasterKeyOptions{Salt: []byte("staging")})
+	stagingSecret, _ := staging.DeriveSecret(xgen.KeyPurposeSigning)
+	fmt.Printf("   Staging signing:  %s\n", stagingSecret)
+	fmt.Printf("   Same as default:  %t\n", stagingSecret == signingSecret)
+	fmt.Println()
+
+	// Example 6: Short Master Secret
+	fmt.Println("6. Short Master Secret")
+	fmt.Println("----------------------")
+	_, err = xgen.NewMasterKey("too-short", xgen.MasterKeyOptions{})
+	fmt.Printf("   Error: %v ✗\n", err)
+	fmt.Println()
+
+	fmt.Println("=== End of Examples ===")
+}
@@ -0,0 +1,143 @@
+// Package main demonstrates the usage of the xgen keyring functionality.
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/hotfixfirst/go-xgen"
+)
+
+func main() {
+	fmt.Println("=== Keyring Examples ===")
+	fmt.Println()
+
+	// Example 1: Create a Keyring
+	fmt.Println("1. Create a Keyring")
+	fmt.Println("-------------------")
+	clientKeys, err := xgen.NewKeyring(xgen.SigningKey{
+		ID:     "2024-01",
+		Secret: "old-secret-key",
+		State:  xgen.KeyStatePrimary,
+	})
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	primary, _ := clientKeys.Primary()
+	fmt.Printf("   Primary: %s\n", primary.ID)
+	fmt.Println()
+
+	// Example 2: Sign and Verify
+	fmt.Println("2. Sign and Verify")
+	fmt.Println("------------------")
+	keyID, signature, _ := clientKeys.Sign("POST", "/api/v1/users", "1700000000", `{"name":"John"}`)
+	fmt.Printf("   Key ID:    %s\n", keyID)
+	fmt.Printf("   Signature: %s\n", signature)
+	matched, ok := clientKeys.Verify(keyID, "POST", "/api/v1/users", "1700000000", `{"name":"John"}`, signature)
+	fmt.Printf("   Verified:  %v (key %s)\n", ok, matched)
+	fmt.Println()
+
+	// Example 3: Server Accepting Old and New Keys
+	fmt.Println("3. Server Accepting Old and New Keys")
+	fmt.Println("------------------------------------")
+	serverKeys, _ := xgen.NewKeyring(
+		xgen.SigningKey{ID: "2024-01", Secret: "old-secret-key"},
+		xgen.SigningKey{ID: "2024-06", Secret: "new-secret-key"},
+	)
+	mw, _ := xgen.NewSignatureMiddleware(xgen.SignatureMiddlewareOptions{Keyring: serverKeys})
+	server := httptest.NewServer(mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyID, _ := xgen.SignatureKeyIDFromContext(r.Context())
+		fmt.Fprintf(w, "verified with %s", keyID)
+	})))
+	defer server.Close()
+	transport, _ := xgen.NewSignatureTransport(xgen.SignatureTransportOptions{Keyring: clientKeys})
+	client := &http.Client{Transport: transport}
+	for _, key := range serverKeys.Keys() {
+		fmt.Printf("   %s: %s\n", key.ID, key.State)
+	}
+	post(client, server.URL)
+	fmt.Println()
+
+	// Example 4: Rotate the Client Key
+	fmt.Println("4. Rotate the Client Key")
+	fmt.Println("------------------------")
+	err = clientKeys.Rotate(xgen.SigningKey{ID: "2024-06", Secret: "new-secret-key"}, 24*time.Hour)
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	for _, key := range clientKeys.Keys() {
+		fmt.Printf("   %s: %s\n", key.ID, key.State)
+	}
+	post(client, server.URL)
+	fmt.Println()
+
+	// Example 5: Retire the Old Key
+	fmt.Println("5. Retire the Old Key")
+	fmt.Println("---------------------")
+	serverKeys.Remove("2024-01")
+	oldClient, _ := xgen.NewSignatureTransport(xgen.SignatureTransportOptions{
+		KeyID:  "2024-01",
+		Secret: "old-secret-key",
+	})
+	fmt.Println("   New key:")
+	post(client, server.URL)
+	fmt.Println("   Old key:")
+	post(&http.Client{Transport: oldClient}, server.URL)
+	fmt.Println()
+
+	// Example 6: Scoped Daily Signing Keys
+	fmt.Println("6. Scoped Daily Signing Keys")
+	fmt.Println("----------------------------")
+	scope := xgen.CredentialScope{
+		Date:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Region:  "eu-west-1",
+		Service: "orders",
+	}
+	daily, _ := xgen.NewScopedKey("partner-1", "partner-root-secret", scope)
+	fmt.Printf("   Credential: %s\n", daily.Credential())
+	signature, _ = daily.Sign("POST", "/api/v1/orders", "1704067200", "", `{"sku":"A-1"}`)
+	fmt.Printf("   Signature:  %s\n", signature)
+	_, err = daily.Sign("POST", "/api/v1/orders", "1704153600", "", `{"sku":"A-1"}`)
+	fmt.Printf("   Next day:   %v\n", err)
+
+	scopedMW, _ := xgen.NewSignatureMiddleware(xgen.SignatureMiddlewareOptions{
+		LookupSecret:      xgen.MapSecretLookup(map[string]string{"partner-1": "partner-root-secret"}),
+		CredentialRegion:  "eu-west-1",
+		CredentialService: "orders",
+	})
+	scopedServer := httptest.NewServer(scopedMW(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyID, _ := xgen.SignatureKeyIDFromContext(r.Context())
+		fmt.Fprintf(w, "verified with %s", keyID)
+	})))
+	defer scopedServer.Close()
+	// The partner fetches today's key from its key service; the root secret stays there.
+	scopedTransport, _ := xgen.NewSignatureTransport(xgen.SignatureTransportOptions{
+		ScopedKey: func(now time.Time) (*xgen.ScopedKey, error) {
+			return xgen.NewScopedKey("partner-1", "partner-root-secret",
+				xgen.CredentialScope{Date: now, Region: "eu-west-1", Service: "orders"})
+		},
+	})
+	post(&http.Client{Transport: scopedTransport}, scopedServer.URL)
+	fmt.Println()
+
+	fmt.Println("=== End of Examples ===")
+}
+
+// post sends a signed request and prints the status and body.
+func post(client *http.Client, baseURL string) {
+	resp, err := client.Post(baseURL+"/api/v1/users", "application/json", strings.NewReader(`{"name":"John"}`))
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Printf("   Status: %d\n", resp.StatusCode)
+	fmt.Printf("   Body:   %s\n", strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,171 @@
+// Package main demonstrates the usage of the xgen signature middleware functionality.
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hotfixfirst/go-xgen"
+)
+
+func main() {
+	fmt.Println("=== Middleware Examples ===")
+	fmt.Println()
+
+	secrets := map[string]string{"client-1": "my-api-secret-key"}
+
+	// Example 1: Protect a Handler
+	fmt.Println("1. Protect a Handler")
+	fmt.Println("--------------------")
+	mw, err := xgen.NewSignatureMiddleware(xgen.SignatureMiddlewareOptions{
+		LookupSecret: xgen.MapSecretLookup(secrets),
+	})
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyID, _ := xgen.SignatureKeyIDFromContext(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "hello %s, got %s", keyID, body)
+	})
+	server := httptest.NewServer(mw(handler))
+	defer server.Close()
+	fmt.Printf("   Server: %s\n", server.URL)
+	fmt.Println()
+
+	// Example 2: Signed Request
+	fmt.Println("2. Signed Request")
+	fmt.Println("-----------------")
+	body := `{"name":"John"}`
+	req := signedRequest(server.URL, "client-1", "my-api-secret-key", body)
+	printResponse(req)
+	fmt.Println()
+
+	// Example 3: Unsigned Request
+	fmt.Println("3. Unsigned Request")
+	fmt.Println("-------------------")
+	req, _ = http.NewRequest("POST", server.URL+"/api/v1/users", strings.NewReader(body))
+	printResponse(req)
+	fmt.Println()
+
+	// Example 4: Wrong Secret
+	fmt.Println("4. Wrong Secret")
+	fmt.Println("---------------")
+	req = signedRequest(server.URL, "client-1", "wrong-secret", body)
+	printResponse(req)
+	fmt.Println()
+
+	// Example 5: Custom Error Handler
+	fmt.Println("5. Custom Error Handler")
+	fmt.Println("-----------------------")
+	mw, _ = xgen.NewSignatureMiddleware(xgen.SignatureMiddlewareOptions{
+		LookupSecret: xgen.MapSecretLookup(secrets),
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintf(w, `{"error":%q}`, err.Error())
+		},
+	})
+	jsonServer := httptest.NewServer(mw(handler))
+	defer jsonServer.Close()
+	req = signedRequest(jsonServer.URL, "client-9", "my-api-secret-key", body)
+	printResponse(req)
+	fmt.Println()
+
+	// Example 6: Replay Protection
+	fmt.Println("6. Replay Protection")
+	fmt.Println("--------------------")
+	mw, _ = xgen.NewSignatureMiddleware(xgen.SignatureMiddlewareOptions{
+		LookupSecret: xgen.MapSecretLookup(secrets),
+		NonceStore:   xgen.NewMemoryNonceStore(xgen.MemoryNonceStoreOptions{}),
+	})
+	nonceServer := httptest.NewServer(mw(handler))
+	defer nonceServer.Close()
+	nonce, _ := xgen.GenerateSignatureNonce()
+	fmt.Printf("   Nonce:  %s\n", nonce)
+	for _, label := range []string{"First", "Replay"} {
+		fmt.Printf("   %s:\n", label)
+		printResponse(nonceRequest(nonceServer.URL, "client-1", "my-api-secret-key", nonce, body))
+	}
+	fmt.Println()
+
+	// Example 7: Content-Digest for Large Uploads
+	fmt.Println("7. Content-Digest for Large Uploads")
+	fmt.Println("-----------------------------------")
+	mw, _ = xgen.NewSignatureMiddleware(xgen.SignatureMiddlewareOptions{
+		LookupSecret:  xgen.MapSecretLookup(secrets),
+		ContentDigest: true,
+	})
+	uploadServer := httptest.NewServer(mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The body is streamed; a mismatch is reported when it is read to EOF.
+		n, err := io.Copy(io.Discard, r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, "stored %d bytes", n)
+	})))
+	defer uploadServer.Close()
+	transport, _ := xgen.NewSignatureTransport(xgen.SignatureTransportOptions{
+		KeyID:         "client-1",
+		Secret:        "my-api-secret-key",
+		ContentDigest: xgen.DigestAlgorithmSHA256,
+	})
+	upload := strings.Repeat("x", 1<<20)
+	digest, _ := xgen.ContentDigest(xgen.DigestAlgorithmSHA256, strings.NewReader(upload))
+	fmt.Printf("   Content-Digest: %s\n", digest)
+	req, _ = http.NewRequest("PUT", uploadServer.URL+"/files/1", strings.NewReader(upload))
+	printResponseWith(&http.Client{Transport: transport}, req)
+	fmt.Println()
+
+	fmt.Println("=== End of Examples ===")
+}
+
+// signedRequest builds a POST request signed with GenerateSignature.
+func signedRequest(baseURL, keyID, secret, body string) *http.Request {
+	path := "/api/v1/users"
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	sig, _ := xgen.GenerateSignature(secret, "POST", path, timestamp, body)
+	req, _ := http.NewRequest("POST", baseURL+path, strings.NewReader(body))
+	req.Header.Set(xgen.DefaultSignatureHeader, sig)
+	req.Header.Set(xgen.DefaultSignatureTimestampHeader, timestamp)
+	req.Header.Set(xgen.DefaultSignatureKeyIDHeader, keyID)
+	return req
+}
+
+// nonceRequest builds a POST request signed with GenerateSignatureWithNonce.
+func nonceRequest(baseURL, keyID, secret, nonce, body string) *http.Request {
+	path := "/api/v1/users"
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	sig, _ := xgen.GenerateSignatureWithNonce(secret, "POST", path, timestamp, nonce, body)
+	req, _ := http.NewRequest("POST", baseURL+path, strings.NewReader(body))
+	req.Header.Set(xgen.DefaultSignatureHeader, sig)
+	req.Header.Set(xgen.DefaultSignatureTimestampHeader, timestamp)
+	req.Header.Set(xgen.DefaultSignatureKeyIDHeader, keyID)
+	req.Header.Set(xgen.DefaultSignatureNonceHeader, nonce)
+	return req
+}
+
+// printResponse sends req and prints the status and body.
+func printResponse(req *http.Request) {
+	printResponseWith(http.DefaultClient, req)
+}
+
+// printResponseWith sends req with client and prints the status and body.
+func printResponseWith(client *http.Client, req *http.Request) {
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	fmt.Printf("   Status: %d\n", resp.StatusCode)
+	fmt.Printf("   Body:   %s\n", strings.TrimSpace(string(respBody)))
+}
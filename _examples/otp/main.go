@@ -0,0 +1,82 @@
+// Package main demonstrates the usage of the xgen one-time password functionality.
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hotfixfirst/go-xgen"
+)
+
+func main() {
+	fmt.Println("=== OTP Examples ===")
+	fmt.Println()
+
+	// Example 1: Generate OTP Secret
+	fmt.Println("1. Generate OTP Secret")
+	fmt.Println("----------------------")
+	secret, err := xgen.GenerateOTPSecret(0)
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	fmt.Printf("   Secret (Base32): %s\n", secret)
+	fmt.Println()
+
+	// Example 2: Provisioning URI
+	fmt.Println("2. Provisioning URI (QR code payload)")
+	fmt.Println("-------------------------------------")
+	opts := xgen.DefaultOTPOptions()
+	uri := xgen.BuildTOTPURI(secret, "Acme", "alice@example.com", opts)
+	fmt.Printf("   URI: %s\n", uri)
+	fmt.Println()
+
+	// Example 3: Generate TOTP
+	fmt.Println("3. Generate TOTP")
+	fmt.Println("----------------")
+	now := time.Now()
+	code, err := xgen.GenerateTOTP(secret, now, opts)
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	fmt.Printf("   Code: %s\n", code)
+	fmt.Println()
+
+	// Example 4: Verify TOTP with Clock Skew
+	fmt.Println("4. Verify TOTP (±1 step skew)")
+	fmt.Println("-----------------------------")
+	_, ok := xgen.VerifyTOTP(secret, code, now.Add(25*time.Second), opts)
+	fmt.Printf("   25s later:  %t ✓\n", ok)
+	_, ok = xgen.VerifyTOTP(secret, code, now.Add(2*time.Minute), opts)
+	fmt.Printf("   2min later: %t ✗\n", ok)
+	fmt.Println()
+
+	// Example 5: Replay Protection
+	fmt.Println("5. Replay Protection")
+	fmt.Println("--------------------")
+	lastUsed, ok := xgen.VerifyTOTPAfter(secret, code, now, 0, opts)
+	fmt.Printf("   First use:  %t ✓ (store step %d)\n", ok, lastUsed)
+	_, ok = xgen.VerifyTOTPAfter(secret, code, now, lastUsed, opts)
+	fmt.Printf("   Replayed:   %t ✗\n", ok)
+	fmt.Println()
+
+	// Example 6: HOTP (Counter-based)
+	fmt.Println("6. HOTP (Counter-based)")
+	fmt.Println("-----------------------")
+	hotpCode, _ := xgen.GenerateHOTP(secret, 42, opts)
+	fmt.Printf("   Code (counter 42): %s\n", hotpCode)
+	next, ok := xgen.VerifyHOTP(secret, hotpCode, 41, opts)
+	fmt.Printf("   Verify from 41:    %t ✓ (next counter %d)\n", ok, next)
+	fmt.Println()
+
+	// Example 7: SHA-256, 8 Digits
+	fmt.Println("7. SHA-256 with 8 Digits")
+	fmt.Println("------------------------")
+	strong := xgen.OTPOptions{Digits: 8, Algorithm: xgen.OTPAlgorithmSHA256, Period: 60 * time.Second}
+	code, _ = xgen.GenerateTOTP(secret, now, strong)
+	fmt.Printf("   Code: %s\n", code)
+	fmt.Println()
+
+	fmt.Println("=== End of Examples ===")
+}
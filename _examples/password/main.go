@@ -0,0 +1,83 @@
+// Package main demonstrates the usage of the xgen password functionality.
+package main
+
+import (
+	"fmt"
+
+	"github.com/hotfixfirst/go-xgen"
+)
+
+func main() {
+	fmt.Println("=== Password Examples ===")
+	fmt.Println()
+
+	// Example 1: Default Password
+	fmt.Println("1. Default Password")
+	fmt.Println("-------------------")
+	pw, err := xgen.GeneratePasswordDefault()
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	fmt.Printf("   Password: %s (len=%d)\n", pw, len(pw))
+	fmt.Println()
+
+	// Example 2: Custom Policy
+	fmt.Println("2. Custom Policy (no ambiguous characters)")
+	fmt.Println("------------------------------------------")
+	policy := xgen.PasswordPolicy{
+		Length:           20,
+		Lowercase:        true,
+		Uppercase:        true,
+		Digits:           true,
+		ExcludeAmbiguous: true,
+	}
+	pw, _ = xgen.GeneratePassword(policy)
+	fmt.Printf("   Password: %s (len=%d)\n", pw, len(pw))
+	fmt.Println()
+
+	// Example 3: Custom Alphabet
+	fmt.Println("3. Custom Alphabet (PIN)")
+	fmt.Println("------------------------")
+	pin, _ := xgen.GeneratePassword(xgen.PasswordPolicy{Length: 6, Alphabet: xgen.PasswordDigits})
+	fmt.Printf("   PIN: %s\n", pin)
+	fmt.Println()
+
+	// Example 4: Policy Validation
+	fmt.Println("4. Policy Validation")
+	fmt.Println("--------------------")
+	_, err = xgen.GeneratePassword(xgen.PasswordPolicy{Length: 2, Lowercase: true, Uppercase: true, Digits: true})
+	fmt.Printf("   Error: %v ✗\n", err)
+	fmt.Println()
+
+	// Example 5: Default Passphrase
+	fmt.Println("5. Default Passphrase (EFF wordlist)")
+	fmt.Println("------------------------------------")
+	phrase, err := xgen.GeneratePassphraseDefault()
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	fmt.Printf("   Passphrase: %s\n", phrase.Value)
+	fmt.Printf("   Entropy:    %.1f bits\n", phrase.EntropyBits)
+	fmt.Println()
+
+	// Example 6: Passphrase Options
+	fmt.Println("6. Passphrase Options")
+	fmt.Println("---------------------")
+	phrase, _ = xgen.GeneratePassphrase(xgen.PassphraseOptions{
+		Words:     4,
+		Separator: " ",
+		Case:      xgen.PassphraseTitle,
+	})
+	fmt.Printf("   Title Case: %s (%.1f bits)\n", phrase.Value, phrase.EntropyBits)
+	phrase, _ = xgen.GeneratePassphrase(xgen.PassphraseOptions{
+		Words:     5,
+		Separator: ".",
+		Case:      xgen.PassphraseRandomTitle,
+	})
+	fmt.Printf("   Random Case: %s (%.1f bits)\n", phrase.Value, phrase.EntropyBits)
+	fmt.Println()
+
+	fmt.Println("=== End of Examples ===")
+}
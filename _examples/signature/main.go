@@ -2,8 +2,14 @@
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
 	"fmt"
+	"io"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hotfixfirst/go-xgen"
@@ -121,5 +127,111 @@ func main() {
 	}
 	fmt.Println()
 
+	// Example 10: HMAC-SHA512
+	fmt.Println("10. HMAC-SHA512")
+	fmt.Println("---------------")
+	hmacSigner, _ := xgen.NewHMACSigner(xgen.SignatureAlgorithmHMACSHA512, secret)
+	sha512Sig, _ := xgen.GenerateSignatureWithSigner(hmacSigner, method, path, timestamp, "", rawBody)
+	fmt.Printf("   Algorithm: %s\n", hmacSigner.Algorithm())
+	fmt.Printf("   Signature: %s\n", sha512Sig)
+	fmt.Printf("   Valid: %t ✓\n", xgen.VerifySignatureWithVerifier(hmacSigner, method, path, timestamp, "", rawBody, sha512Sig))
+	fmt.Println()
+
+	// Example 11: Ed25519 (Public Key Verification)
+	fmt.Println("11. Ed25519 (Public Key Verification)")
+	fmt.Println("-------------------------------------")
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	edSigner, _ := xgen.NewEd25519Signer(privateKey)
+	edVerifier, _ := xgen.NewEd25519Verifier(publicKey)
+	edSig, _ := xgen.GenerateSignatureWithSigner(edSigner, method, path, timestamp, "", rawBody)
+	fmt.Printf("   Algorithm: %s\n", edSigner.Algorithm())
+	fmt.Printf("   Signature: %s...\n", edSig[:32])
+	fmt.Printf("   Valid with public key: %t ✓\n", xgen.VerifySignatureWithVerifier(edVerifier, method, path, timestamp, "", rawBody, edSig))
+	fmt.Println()
+
+	// Example 12: Downgrade Protection
+	fmt.Println("12. Downgrade Protection")
+	fmt.Println("------------------------")
+	sha256Signer, _ := xgen.NewHMACSigner(xgen.SignatureAlgorithmHMACSHA256, secret)
+	fmt.Println("   Canonical string starts with the algorithm:")
+	fmt.Printf("   %q\n", xgen.BuildSignatureCanonicalStringWithAlgorithm(sha256Signer.Algorithm(), "GET", "/", timestamp, "", ""))
+	downgraded := xgen.VerifySignatureWithVerifier(sha256Signer, method, path, timestamp, "", rawBody, sha512Sig[:64])
+	fmt.Printf("   Truncated HMAC-SHA512 signature rejected as HMAC-SHA256: %t ✓\n", !downgraded)
+	fmt.Println()
+
+	// Example 13: V2 Canonical String (Query and Headers)
+	fmt.Println("13. V2 Canonical String (Query and Headers)")
+	fmt.Println("-------------------------------------------")
+	req := xgen.SignatureRequest{
+		Method:        "POST",
+		Path:          "/api/v1/./payments",
+		RawQuery:      "currency=EUR&amount=10",
+		Host:          "api.example.com",
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		SignedHeaders: []string{"Host", "Content-Type"},
+		Timestamp:     "1704067200",
+		Body:          `{"to":"alice"}`,
+	}
+	canonicalV2, _ := xgen.BuildSignatureCanonicalStringV2(sha256Signer.Algorithm(), req)
+	for _, line := range strings.Split(canonicalV2, "\n") {
+		fmt.Printf("   %s\n", line)
+	}
+	v2Sig, _ := xgen.GenerateSignatureV2(sha256Signer, req)
+	fmt.Printf("   Valid: %t ✓\n", xgen.VerifySignatureV2(sha256Signer, req, v2Sig))
+	req.RawQuery = "currency=EUR&amount=1000"
+	fmt.Printf("   Tampered amount valid: %t ✗\n", xgen.VerifySignatureV2(sha256Signer, req, v2Sig))
+	fmt.Println()
+
+	// Example 14: RFC 9421 HTTP Message Signatures
+	fmt.Println("14. RFC 9421 HTTP Message Signatures")
+	fmt.Println("------------------------------------")
+	httpReq, _ := http.NewRequest("POST", "https://api.example.com/api/v1/payments?amount=10", strings.NewReader(`{"to":"alice"}`))
+	httpReq.Header.Set("Content-Type", "application/json")
+	_ = xgen.SignHTTPMessage(httpReq, edSigner, xgen.HTTPSignatureOptions{
+		KeyID:      "partner-1",
+		Components: []string{"@method", "@target-uri", "content-type"},
+		Created:    time.Unix(1704067200, 0),
+	})
+	fmt.Printf("   Signature-Input: %s\n", httpReq.Header.Get(xgen.HTTPSignatureInputHeader))
+	fmt.Printf("   Signature:       %s...\n", httpReq.Header.Get(xgen.HTTPSignatureHeader)[:24])
+	verified, err := xgen.VerifyHTTPMessageSignature(httpReq, xgen.HTTPSignatureVerifyOptions{
+		ResolveKey: func(_ context.Context, keyID string, _ xgen.SignatureAlgorithm) (xgen.Verifier, error) {
+			return edVerifier, nil
+		},
+		RequiredComponents: []string{"@method", "@target-uri"},
+		Now:                func() time.Time { return time.Unix(1704067230, 0) }, // 30 seconds after Created
+	})
+	fmt.Printf("   Valid: %t ✓ (keyid %s, %s)\n", err == nil, verified.KeyID, verified.Algorithm)
+	fmt.Println()
+
+	// Example 15: Streaming Signature
+	fmt.Println("15. Streaming Signature")
+	fmt.Println("-----------------------")
+	upload := strings.Repeat("chunk-", 100000)
+	sw, _ := xgen.NewSignatureWriter(secret, "PUT", "/files/1", timestamp)
+	_, _ = io.Copy(sw, strings.NewReader(upload))
+	streamSig := sw.Signature()
+	wholeSig, _ := xgen.GenerateSignature(secret, "PUT", "/files/1", timestamp, upload)
+	fmt.Printf("   Streamed %d bytes, same as GenerateSignature: %t ✓\n", len(upload), streamSig == wholeSig)
+	sr, _ := xgen.NewSignatureReader(strings.NewReader(upload), secret, "PUT", "/files/1", timestamp, streamSig)
+	_, err = io.Copy(io.Discard, sr)
+	fmt.Printf("   Verifying reader: %v ✓\n", err)
+	sr, _ = xgen.NewSignatureReader(strings.NewReader(upload+"!"), secret, "PUT", "/files/1", timestamp, streamSig)
+	_, err = io.Copy(io.Discard, sr)
+	fmt.Printf("   Tampered body: %v ✗\n", err)
+	fmt.Println()
+
+	// Example 16: Canonical JSON Signature
+	fmt.Println("16. Canonical JSON Signature")
+	fmt.Println("----------------------------")
+	canonicalJSON, _ := xgen.CanonicalizeJSON([]byte(`{"id": "A-1", "amount": 10.0}`))
+	fmt.Printf("   Canonical: %s\n", canonicalJSON)
+	jsonSig, _ := xgen.GenerateSignatureJSON(secret, "POST", "/api/v1/payments", timestamp, `{"id":"A-1","amount":10}`)
+	fmt.Printf("   Re-serialized body valid: %t ✓\n",
+		xgen.VerifySignatureJSON(secret, "POST", "/api/v1/payments", timestamp, "{\n  \"amount\": 1e1,\n  \"id\": \"A-1\"\n}", jsonSig))
+	_, err = xgen.CanonicalizeJSON([]byte(`{"id":"A-1","id":"A-2"}`))
+	fmt.Printf("   Duplicate keys: %v ✗\n", err)
+	fmt.Println()
+
 	fmt.Println("=== End of Examples ===")
 }
@@ -0,0 +1,56 @@
+// Package main demonstrates the usage of the xgen signed URL functionality.
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hotfixfirst/go-xgen"
+)
+
+func main() {
+	fmt.Println("=== Signed URL Examples ===")
+	fmt.Println()
+
+	secret := "my-url-signing-secret"
+	expires := time.Unix(1893456000, 0) // 2030-01-01
+
+	// Example 1: Download Link
+	fmt.Println("1. Download Link")
+	fmt.Println("----------------")
+	link, _ := xgen.SignURL("https://files.example.com/reports/q3.pdf", expires, xgen.SignURLOptions{Secret: secret})
+	fmt.Printf("   URL:   %s\n", link)
+	fmt.Printf("   Valid: %t ✓\n", xgen.VerifyURL(link, xgen.VerifyURLOptions{Secret: secret}) == nil)
+	fmt.Println()
+
+	// Example 2: Tampered Link
+	fmt.Println("2. Tampered Link")
+	fmt.Println("----------------")
+	tampered := strings.Replace(link, "q3.pdf", "q4.pdf", 1)
+	fmt.Printf("   Error: %v ✗\n", xgen.VerifyURL(tampered, xgen.VerifyURLOptions{Secret: secret}))
+	fmt.Println()
+
+	// Example 3: Expired Link
+	fmt.Println("3. Expired Link")
+	fmt.Println("---------------")
+	old, _ := xgen.SignURL("https://files.example.com/reports/q3.pdf", time.Now().Add(-time.Minute), xgen.SignURLOptions{Secret: secret})
+	fmt.Printf("   Error: %v ✗\n", xgen.VerifyURL(old, xgen.VerifyURLOptions{Secret: secret}))
+	fmt.Println()
+
+	// Example 4: Upload Link Restricted to PUT and One Client
+	fmt.Println("4. Upload Link Restricted to PUT and One Client")
+	fmt.Println("-----------------------------------------------")
+	upload, _ := xgen.SignURL("https://files.example.com/uploads/42", expires, xgen.SignURLOptions{
+		Secret:   secret,
+		Method:   "PUT",
+		ClientIP: "203.0.113.10",
+	})
+	fmt.Printf("   URL: %s\n", upload)
+	fmt.Printf("   PUT from client: %t ✓\n", xgen.VerifyURL(upload, xgen.VerifyURLOptions{Secret: secret, Method: "PUT", ClientIP: "203.0.113.10"}) == nil)
+	fmt.Printf("   GET from client: %v ✗\n", xgen.VerifyURL(upload, xgen.VerifyURLOptions{Secret: secret, Method: "GET", ClientIP: "203.0.113.10"}))
+	fmt.Printf("   PUT from other:  %v ✗\n", xgen.VerifyURL(upload, xgen.VerifyURLOptions{Secret: secret, Method: "PUT", ClientIP: "198.51.100.7"}))
+	fmt.Println()
+
+	fmt.Println("=== End of Examples ===")
+}
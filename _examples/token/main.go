@@ -0,0 +1,122 @@
+// Package main demonstrates the usage of the xgen token functionality.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hotfixfirst/go-xgen"
+)
+
+func main() {
+	fmt.Println("=== Token Examples ===")
+	fmt.Println()
+
+	// Configuration
+	ctx := context.Background()
+	secret := "my-token-secret"
+	verifyOpts := xgen.VerifyTokenOptions{Purpose: "password-reset", LookupSecret: xgen.StaticSecretLookup(secret)}
+
+	// Example 1: Issue a Token
+	fmt.Println("1. Issue a Token")
+	fmt.Println("----------------")
+	token, err := xgen.IssueToken(xgen.TokenClaims{
+		Subject:   "user-42",
+		Purpose:   "password-reset",
+		ExpiresAt: time.Now().Add(30 * time.Minute),
+	}, xgen.IssueTokenOptions{Secret: secret})
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	fmt.Printf("   Token: %s...\n", token[:40])
+	fmt.Printf("   Link:  https://app.example.com/reset?token=<%d chars>\n", len(token))
+	fmt.Println()
+
+	// Example 2: Verify a Token
+	fmt.Println("2. Verify a Token")
+	fmt.Println("-----------------")
+	claims, err := xgen.VerifyToken(ctx, token, verifyOpts)
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	fmt.Printf("   Subject: %s, Purpose: %s ✓\n", claims.Subject, claims.Purpose)
+	fmt.Println()
+
+	// Example 3: Purpose Binding
+	fmt.Println("3. Purpose Binding")
+	fmt.Println("------------------")
+	_, err = xgen.VerifyToken(ctx, token, xgen.VerifyTokenOptions{Purpose: "email-verification", LookupSecret: xgen.StaticSecretLookup(secret)})
+	fmt.Printf("   Used for email verification: %v ✗\n", err)
+	fmt.Println()
+
+	// Example 4: Expired Token
+	fmt.Println("4. Expired Token")
+	fmt.Println("----------------")
+	expired, _ := xgen.IssueToken(xgen.TokenClaims{Subject: "user-42", Purpose: "password-reset", ExpiresAt: time.Now().Add(-time.Minute)},
+		xgen.IssueTokenOptions{Secret: secret})
+	_, err = xgen.VerifyToken(ctx, expired, verifyOpts)
+	fmt.Printf("   Expired: %v ✗\n", err)
+	fmt.Println()
+
+	// Example 5: Tampered Token
+	fmt.Println("5. Tampered Token")
+	fmt.Println("-----------------")
+	_, err = xgen.VerifyToken(ctx, token, xgen.VerifyTokenOptions{Purpose: "password-reset", LookupSecret: xgen.StaticSecretLookup("wrong-secret")})
+	fmt.Printf("   Wrong secret: %v ✗\n", err)
+	_, err = xgen.VerifyToken(ctx, strings.TrimSuffix(token, token[len(token)-4:]), verifyOpts)
+	fmt.Printf("   Truncated: %v ✗\n", err)
+	fmt.Println()
+
+	// Example 6: Single-Use Tokens
+	fmt.Println("6. Single-Use Tokens")
+	fmt.Println("--------------------")
+	singleUse := verifyOpts
+	singleUse.NonceStore = xgen.NewMemoryNonceStore(xgen.MemoryNonceStoreOptions{})
+	_, err = xgen.VerifyToken(ctx, token, singleUse)
+	fmt.Printf("   First use:  %v ✓\n", err)
+	_, err = xgen.VerifyToken(ctx, token, singleUse)
+	fmt.Printf("   Second use: %v ✗\n", err)
+	fmt.Println()
+
+	// Example 7: Key IDs with a Keyring
+	fmt.Println("7. Key IDs with a Keyring")
+	fmt.Println("-------------------------")
+	keyring, _ := xgen.NewKeyring(
+		xgen.SigningKey{ID: "2024-01", Secret: "old-secret"},
+		xgen.SigningKey{ID: "2024-06", Secret: "new-secret", State: xgen.KeyStatePrimary},
+	)
+	invite, _ := xgen.IssueToken(xgen.TokenClaims{Subject: "user-42", Purpose: "invite", ExpiresAt: time.Now().Add(time.Hour)},
+		xgen.IssueTokenOptions{Keyring: keyring})
+	fmt.Printf("   Token: %s...\n", invite[:16])
+	_, err = xgen.VerifyToken(ctx, invite, xgen.VerifyTokenOptions{Purpose: "invite", Keyring: keyring})
+	fmt.Printf("   Verified: %v ✓\n", err)
+	fmt.Println()
+
+	// Example 8: Encrypted Claims
+	fmt.Println("8. Encrypted Claims")
+	fmt.Println("-------------------")
+	master, _ := xgen.NewMasterKey("0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0", xgen.MasterKeyOptions{})
+	key, _ := master.DeriveKey(xgen.KeyPurposeTokenEncryption, 32)
+	encryption, _ := xgen.NewEncryptionKeyring(xgen.EncryptionKey{ID: "e1", Key: key, State: xgen.KeyStatePrimary})
+	sealed, _ := xgen.IssueToken(xgen.TokenClaims{
+		Subject:   "user-42",
+		Purpose:   "email-verification",
+		ExpiresAt: time.Now().Add(time.Hour),
+		Data:      map[string]string{"email": "alice@example.com"},
+	}, xgen.IssueTokenOptions{Secret: secret, Encryption: encryption})
+	fmt.Printf("   Token: %s...\n", sealed[:24])
+	fmt.Printf("   Email readable in token: %t\n", strings.Contains(sealed, "alice"))
+	claims, err = xgen.VerifyToken(ctx, sealed, xgen.VerifyTokenOptions{
+		Purpose: "email-verification", LookupSecret: xgen.StaticSecretLookup(secret), Encryption: encryption,
+	})
+	if err == nil {
+		fmt.Printf("   Email: %s ✓\n", claims.Data["email"])
+	}
+	fmt.Println()
+
+	fmt.Println("=== End of Examples ===")
+}
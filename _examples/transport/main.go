@@ -0,0 +1,83 @@
+// Package main demonstrates the usage of the xgen signing transport functionality.
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/hotfixfirst/go-xgen"
+)
+
+func main() {
+	fmt.Println("=== Transport Examples ===")
+	fmt.Println()
+
+	// Server verifying signatures with the middleware
+	mw, _ := xgen.NewSignatureMiddleware(xgen.SignatureMiddlewareOptions{
+		LookupSecret: xgen.MapSecretLookup(map[string]string{"client-1": "my-api-secret-key"}),
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/users", func(w http.ResponseWriter, r *http.Request) {
+		keyID, _ := xgen.SignatureKeyIDFromContext(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "hello %s, got %s", keyID, body)
+	})
+	mux.HandleFunc("/api/v1/people", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/api/v1/users", http.StatusTemporaryRedirect)
+	})
+	server := httptest.NewServer(mw(mux))
+	defer server.Close()
+
+	// Example 1: Create Signing Client
+	fmt.Println("1. Create Signing Client")
+	fmt.Println("------------------------")
+	transport, err := xgen.NewSignatureTransport(xgen.SignatureTransportOptions{
+		KeyID:  "client-1",
+		Secret: "my-api-secret-key",
+	})
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	client := &http.Client{Transport: transport}
+	fmt.Println("   client := &http.Client{Transport: transport}")
+	fmt.Println()
+
+	// Example 2: Signed POST
+	fmt.Println("2. Signed POST")
+	fmt.Println("--------------")
+	resp, err := client.Post(server.URL+"/api/v1/users", "application/json", strings.NewReader(`{"name":"John"}`))
+	printResponse(resp, err)
+	fmt.Println()
+
+	// Example 3: Redirect (re-signed for the new path)
+	fmt.Println("3. Redirect (307, body replayed and re-signed)")
+	fmt.Println("----------------------------------------------")
+	resp, err = client.Post(server.URL+"/api/v1/people", "application/json", strings.NewReader(`{"name":"Jane"}`))
+	printResponse(resp, err)
+	fmt.Println()
+
+	// Example 4: Plain Client (unsigned)
+	fmt.Println("4. Plain Client (unsigned)")
+	fmt.Println("--------------------------")
+	resp, err = http.Post(server.URL+"/api/v1/users", "application/json", strings.NewReader(`{"name":"John"}`))
+	printResponse(resp, err)
+	fmt.Println()
+
+	fmt.Println("=== End of Examples ===")
+}
+
+// printResponse prints the status and body of resp.
+func printResponse(resp *http.Response, err error) {
+	if err != nil {
+		fmt.Printf("   Error: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Printf("   Status: %d\n", resp.StatusCode)
+	fmt.Printf("   Body:   %s\n", strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,67 @@
+// Package main demonstrates the usage of the xgen webhook signing functionality.
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hotfixfirst/go-xgen"
+)
+
+func main() {
+	fmt.Println("=== Webhook Examples ===")
+	fmt.Println()
+
+	payload := []byte(`{"event":"invoice.paid","id":"inv_123"}`)
+	now := time.Now()
+
+	// Example 1: Sign a Webhook
+	fmt.Println("1. Sign a Webhook")
+	fmt.Println("-----------------")
+	header, _ := xgen.SignWebhook(payload, now, "whsec_current")
+	fmt.Printf("   %s: %s\n", xgen.WebhookSignatureHeader, header)
+	fmt.Printf("   Valid: %t ✓\n", xgen.VerifyWebhook(header, payload, 5*time.Minute, "whsec_current") == nil)
+	fmt.Println()
+
+	// Example 2: Secret Rotation
+	fmt.Println("2. Secret Rotation")
+	fmt.Println("------------------")
+	header, _ = xgen.SignWebhook(payload, now, "whsec_old", "whsec_new")
+	fmt.Printf("   Header: %s...\n", header[:40])
+	fmt.Printf("   Receiver with old secret: %t ✓\n", xgen.VerifyWebhook(header, payload, 0, "whsec_old") == nil)
+	fmt.Printf("   Receiver with new secret: %t ✓\n", xgen.VerifyWebhook(header, payload, 0, "whsec_new") == nil)
+	fmt.Println()
+
+	// Example 3: Rejected Deliveries
+	fmt.Println("3. Rejected Deliveries")
+	fmt.Println("----------------------")
+	fmt.Printf("   Tampered payload: %v ✗\n", xgen.VerifyWebhook(header, []byte(`{"event":"invoice.void"}`), 0, "whsec_new"))
+	stale, _ := xgen.SignWebhook(payload, now.Add(-time.Hour), "whsec_new")
+	fmt.Printf("   Replayed after an hour: %v ✗\n", xgen.VerifyWebhook(stale, payload, 0, "whsec_new"))
+	fmt.Println()
+
+	// Example 4: Standard Webhooks
+	fmt.Println("4. Standard Webhooks")
+	fmt.Println("--------------------")
+	secret := "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+	sig, _ := xgen.SignStandardWebhook("msg_p5jXN8AQM9LWM0D4loKWxJek", now, payload, secret)
+	h := http.Header{}
+	h.Set(xgen.StandardWebhookIDHeader, "msg_p5jXN8AQM9LWM0D4loKWxJek")
+	h.Set(xgen.StandardWebhookTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	h.Set(xgen.StandardWebhookSignatureHeader, sig)
+	fmt.Printf("   %s: %s\n", xgen.StandardWebhookSignatureHeader, sig)
+	fmt.Printf("   Valid: %t ✓\n", xgen.VerifyStandardWebhook(h, payload, 0, secret) == nil)
+	fmt.Println()
+
+	// Example 5: GitHub
+	fmt.Println("5. GitHub")
+	fmt.Println("---------")
+	ghSig, _ := xgen.SignGitHubWebhook([]byte("Hello, World!"), "It's a Secret to Everybody")
+	fmt.Printf("   %s: %s\n", xgen.GitHubSignatureHeader, ghSig)
+	fmt.Printf("   Valid: %t ✓\n", xgen.VerifyGitHubWebhook(ghSig, []byte("Hello, World!"), "It's a Secret to Everybody") == nil)
+	fmt.Println()
+
+	fmt.Println("=== End of Examples ===")
+}
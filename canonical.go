@@ -0,0 +1,287 @@
+package xgen
+
+import (
+	"cmp"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// SignatureVersion selects the canonical string format of signed HTTP requests.
+type SignatureVersion int
+
+const (
+	// SignatureVersion1 signs method, path, timestamp, optional nonce and body
+	// (BuildSignatureCanonicalString).
+	SignatureVersion1 SignatureVersion = iota
+	// SignatureVersion2 also signs the query string and declared headers and
+	// normalizes the path (BuildSignatureCanonicalStringV2).
+	SignatureVersion2
+)
+
+// signatureV2Prefix is the first line of every v2 canonical string.
+const signatureV2Prefix = "xgen-v2"
+
+// SignatureRequest holds the request components covered by a v2 signature.
+type SignatureRequest struct {
+	// Method is the HTTP method. It is upper-cased.
+	Method string
+	// Path is the URL path, escaped or not. Empty means "/".
+	Path string
+	// RawQuery is the encoded query string without the leading '?'.
+	RawQuery string
+	// Host is the value signed for the "host" header, which net/http keeps outside Header.
+	Host string
+	// Header holds the request headers.
+	Header http.Header
+	// SignedHeaders lists the header names to sign, in any case and order.
+	// Listed headers that are absent are signed with an empty value, so they
+	// cannot be added later.
+	SignedHeaders []string
+	// Timestamp is the request timestamp. Required.
+	Timestamp string
+	// Nonce is an optional single-use nonce.
+	Nonce string
+	// Body is the raw request body.
+	Body string
+}
+
+// NewSignatureRequest collects the v2 signature components of r.
+// Host is taken from r.Host, falling back to r.URL.Host for client requests.
+func NewSignatureRequest(r *http.Request, signedHeaders []string, timestamp, nonce, rawBody string) SignatureRequest {
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	return SignatureRequest{
+		Method:        r.Method,
+		Path:          r.URL.EscapedPath(),
+		RawQuery:      r.URL.RawQuery,
+		Host:          host,
+		Header:        r.Header,
+		SignedHeaders: signedHeaders,
+		Timestamp:     timestamp,
+		Nonce:         nonce,
+		Body:          rawBody,
+	}
+}
+
+// BuildSignatureCanonicalStringV2 formats a request into the v2 canonical string:
+//
+//	xgen-v2
+//	ALGORITHM
+//	METHOD
+//	/normalized/path
+//	sorted=query&with=strict%20encoding
+//	signed;header;names
+//	signed:header
+//	header:values
+//	names:...
+//	TIMESTAMP
+//	NONCE
+//	BODY
+//
+// The path has dot segments and repeated slashes removed, and every path
+// segment, query key and query value is decoded and re-encoded so that only
+// unreserved characters (A-Z a-z 0-9 - . _ ~) stay literal. Query parameters
+// are sorted by key, then value. Header names are lower-cased and sorted;
+// repeated values are joined with "," and runs of whitespace become one space.
+func BuildSignatureCanonicalStringV2(algorithm SignatureAlgorithm, req SignatureRequest) (string, error) {
+	if req.Method == "" || req.Timestamp == "" {
+		return "", errors.New("missing required fields for signature")
+	}
+	path, err := canonicalPath(req.Path)
+	if err != nil {
+		return "", err
+	}
+	query, err := canonicalQuery(req.RawQuery)
+	if err != nil {
+		return "", err
+	}
+	names, err := CanonicalHeaderNames(req.SignedHeaders)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(signatureV2Prefix + "\n")
+	b.WriteString(string(algorithm) + "\n")
+	b.WriteString(strings.ToUpper(req.Method) + "\n")
+	b.WriteString(path + "\n")
+	b.WriteString(query + "\n")
+	b.WriteString(strings.Join(names, ";") + "\n")
+	for _, name := range names {
+		b.WriteString(name + ":" + canonicalHeaderValue(req, name) + "\n")
+	}
+	b.WriteString(req.Timestamp + "\n")
+	b.WriteString(req.Nonce + "\n")
+	b.WriteString(req.Body)
+	return b.String(), nil
+}
+
+// GenerateSignatureV2 signs the v2 canonical string of req with signer and
+// returns the signature in hex format.
+//
+// Example:
+//
+//	signer, _ := NewHMACSigner(SignatureAlgorithmHMACSHA256, secret)
+//	req := NewSignatureRequest(httpReq, []string{"content-type", "host"}, timestamp, "", body)
+//	sig, err := GenerateSignatureV2(signer, req)
+func GenerateSignatureV2(signer Signer, req SignatureRequest) (string, error) {
+	if signer == nil {
+		return "", errors.New("missing required fields for signature")
+	}
+	canonical, err := BuildSignatureCanonicalStringV2(signer.Algorithm(), req)
+	if err != nil {
+		return "", err
+	}
+	sig, err := signer.Sign([]byte(canonical))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sig), nil
+}
+
+// VerifySignatureV2 checks a signature created by GenerateSignatureV2.
+// The verifier's own algorithm is used to build the canonical string.
+func VerifySignatureV2(verifier Verifier, req SignatureRequest, receivedSig string) bool {
+	if verifier == nil {
+		return false
+	}
+	received, err := hex.DecodeString(receivedSig)
+	if err != nil {
+		return false
+	}
+	canonical, err := BuildSignatureCanonicalStringV2(verifier.Algorithm(), req)
+	if err != nil {
+		return false
+	}
+	return verifier.Verify([]byte(canonical), received)
+}
+
+// canonicalPath decodes and strictly re-encodes each segment of p and removes
+// empty and dot segments. A trailing slash is kept.
+func canonicalPath(p string) (string, error) {
+	if p == "" {
+		return "/", nil
+	}
+	var segments []string
+	for _, seg := range strings.Split(p, "/") {
+		decoded, err := url.PathUnescape(seg)
+		if err != nil {
+			return "", fmt.Errorf("invalid path escape: %w", err)
+		}
+		switch decoded {
+		case "", ".":
+		case "..":
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+		default:
+			segments = append(segments, escapeStrict(decoded))
+		}
+	}
+	out := "/" + strings.Join(segments, "/")
+	if len(segments) > 0 && (strings.HasSuffix(p, "/") || strings.HasSuffix(p, "/.") || strings.HasSuffix(p, "/..")) {
+		out += "/"
+	}
+	return out, nil
+}
+
+// canonicalQuery decodes and strictly re-encodes every query parameter and
+// sorts them by key, then value. Parameters without '=' get an empty value.
+func canonicalQuery(rawQuery string) (string, error) {
+	if rawQuery == "" {
+		return "", nil
+	}
+	var params [][2]string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		k, err := url.QueryUnescape(key)
+		if err != nil {
+			return "", fmt.Errorf("invalid query escape: %w", err)
+		}
+		v, err := url.QueryUnescape(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid query escape: %w", err)
+		}
+		params = append(params, [2]string{escapeStrict(k), escapeStrict(v)})
+	}
+	slices.SortFunc(params, func(a, b [2]string) int {
+		return cmp.Or(strings.Compare(a[0], b[0]), strings.Compare(a[1], b[1]))
+	})
+	encoded := make([]string, len(params))
+	for i, p := range params {
+		encoded[i] = p[0] + "=" + p[1]
+	}
+	return strings.Join(encoded, "&"), nil
+}
+
+// CanonicalHeaderNames lower-cases, sorts and de-duplicates header names.
+// Empty names are skipped; names with characters outside the HTTP token set
+// are rejected so they cannot be confused with the ";" and ":" separators.
+func CanonicalHeaderNames(names []string) ([]string, error) {
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if strings.IndexFunc(name, func(r rune) bool { return !isHeaderTokenChar(r) }) >= 0 {
+			return nil, fmt.Errorf("invalid signed header name %q", name)
+		}
+		out = append(out, name)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// isHeaderTokenChar reports whether r may appear in an HTTP header name (RFC 9110 token).
+func isHeaderTokenChar(r rune) bool {
+	return 'a' <= r && r <= 'z' || '0' <= r && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
+
+// canonicalHeaderValue returns the values of the named header joined with ","
+// with surrounding whitespace trimmed and inner whitespace runs collapsed.
+func canonicalHeaderValue(req SignatureRequest, name string) string {
+	values := []string{req.Host}
+	if name != "host" {
+		values = req.Header.Values(name)
+	}
+	normalized := make([]string, len(values))
+	for i, v := range values {
+		normalized[i] = strings.Join(strings.Fields(v), " ")
+	}
+	return strings.Join(normalized, ",")
+}
+
+// escapeStrict percent-encodes every byte of s except RFC 3986 unreserved
+// characters, using upper-case hex digits.
+func escapeStrict(s string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isUnreserved(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&0x0F])
+	}
+	return b.String()
+}
+
+// isUnreserved reports whether c is an RFC 3986 unreserved character.
+func isUnreserved(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
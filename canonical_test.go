@@ -0,0 +1,244 @@
+package xgen
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSignatureCanonicalStringV2(t *testing.T) {
+	req := SignatureRequest{
+		Method:        "post",
+		Path:          "/api//v1/./users/../orders",
+		RawQuery:      "b=2&a=3&a=1&c",
+		Host:          "api.example.com",
+		Header:        http.Header{"Content-Type": {"  application/json;   charset=utf-8 "}, "X-Multi": {"a", "b"}},
+		SignedHeaders: []string{"X-Multi", "host", "Content-Type", "content-type", "x-absent"},
+		Timestamp:     "1700000000",
+		Nonce:         "n-1",
+		Body:          `{"amount":10}`,
+	}
+	canonical, err := BuildSignatureCanonicalStringV2(SignatureAlgorithmHMACSHA256, req)
+	require.NoError(t, err)
+	expected := "xgen-v2\n" +
+		"hmac-sha256\n" +
+		"POST\n" +
+		"/api/v1/orders\n" +
+		"a=1&a=3&b=2&c=\n" +
+		"content-type;host;x-absent;x-multi\n" +
+		"content-type:application/json; charset=utf-8\n" +
+		"host:api.example.com\n" +
+		"x-absent:\n" +
+		"x-multi:a,b\n" +
+		"1700000000\n" +
+		"n-1\n" +
+		`{"amount":10}`
+	assert.Equal(t, expected, canonical)
+
+	// The caller's header values are not modified.
+	assert.Equal(t, "  application/json;   charset=utf-8 ", req.Header.Get("Content-Type"))
+}
+
+func TestBuildSignatureCanonicalStringV2_Errors(t *testing.T) {
+	valid := SignatureRequest{Method: "GET", Path: "/", Timestamp: "1700000000"}
+
+	req := valid
+	req.Method = ""
+	_, err := BuildSignatureCanonicalStringV2(SignatureAlgorithmHMACSHA256, req)
+	assert.Error(t, err)
+
+	req = valid
+	req.Timestamp = ""
+	_, err = BuildSignatureCanonicalStringV2(SignatureAlgorithmHMACSHA256, req)
+	assert.Error(t, err)
+
+	req = valid
+	req.Path = "/bad%zz"
+	_, err = BuildSignatureCanonicalStringV2(SignatureAlgorithmHMACSHA256, req)
+	assert.Error(t, err)
+
+	req = valid
+	req.RawQuery = "a=%zz"
+	_, err = BuildSignatureCanonicalStringV2(SignatureAlgorithmHMACSHA256, req)
+	assert.Error(t, err)
+
+	req = valid
+	req.SignedHeaders = []string{"host:evil"}
+	_, err = BuildSignatureCanonicalStringV2(SignatureAlgorithmHMACSHA256, req)
+	assert.Error(t, err)
+}
+
+func TestCanonicalPath(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"/a/b", "/a/b"},
+		{"/a/b/", "/a/b/"},
+		{"//a///b", "/a/b"},
+		{"/a/./b/../c", "/a/c"},
+		{"/a/b/..", "/a/"},
+		{"/../../a", "/a"},
+		{"/hello world", "/hello%20world"},
+		{"/hello%20world", "/hello%20world"},
+		{"/%7euser", "/~user"},
+		{"/a%2fb", "/a%2Fb"},
+		{"/%2E%2E/a", "/a"},
+		{"/caf%C3%A9", "/caf%C3%A9"},
+		{"/café", "/caf%C3%A9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := canonicalPath(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"b=2&a=1", "a=1&b=2"},
+		{"a=2&a=1", "a=1&a=2"},
+		{"a", "a="},
+		{"a=&&b=", "a=&b="},
+		{"q=hello+world", "q=hello%20world"},
+		{"q=hello%20world", "q=hello%20world"},
+		{"q=%7e", "q=~"},
+		{"q=a%2bb", "q=a%2Bb"},
+		{"a-b=1&a=2", "a=2&a-b=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := canonicalQuery(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGenerateSignatureV2(t *testing.T) {
+	signer, err := NewHMACSigner(SignatureAlgorithmHMACSHA256, "secret")
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("POST", "http://api.example.com/pay?amount=10&to=alice", strings.NewReader("{}"))
+	r.Header.Set("Content-Type", "application/json")
+	headers := []string{"host", "content-type"}
+	sig, err := GenerateSignatureV2(signer, NewSignatureRequest(r, headers, "1700000000", "", "{}"))
+	require.NoError(t, err)
+
+	verify := func(r *http.Request) bool {
+		return VerifySignatureV2(signer, NewSignatureRequest(r, headers, "1700000000", "", "{}"), sig)
+	}
+	assert.True(t, verify(r))
+
+	// Equivalent encodings verify.
+	same := httptest.NewRequest("post", "http://api.example.com/./pay?to=alice&amount=10", nil)
+	same.Header.Set("content-type", "application/json")
+	assert.True(t, verify(same))
+
+	// Query and signed headers are protected.
+	tampered := r.Clone(r.Context())
+	tampered.URL.RawQuery = "amount=1000&to=alice"
+	assert.False(t, verify(tampered))
+
+	tampered = r.Clone(r.Context())
+	tampered.Header.Set("Content-Type", "text/plain")
+	assert.False(t, verify(tampered))
+
+	tampered = r.Clone(r.Context())
+	tampered.Host = "evil.example.com"
+	assert.False(t, verify(tampered))
+
+	// v1 and v2 signatures are not interchangeable.
+	v1, err := GenerateSignature("secret", "POST", "/pay", "1700000000", "{}")
+	require.NoError(t, err)
+	assert.False(t, VerifySignatureV2(signer, NewSignatureRequest(r, headers, "1700000000", "", "{}"), v1))
+	assert.False(t, VerifySignature("secret", "POST", "/pay", "1700000000", "{}", sig))
+
+	_, err = GenerateSignatureV2(nil, NewSignatureRequest(r, headers, "1700000000", "", "{}"))
+	assert.Error(t, err)
+	assert.False(t, VerifySignatureV2(nil, NewSignatureRequest(r, headers, "1700000000", "", "{}"), sig))
+	assert.False(t, VerifySignatureV2(signer, NewSignatureRequest(r, headers, "1700000000", "", "{}"), "zz"))
+}
+
+func TestSignatureV2_MiddlewareTransport(t *testing.T) {
+	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret:          MapSecretLookup(map[string]string{"client-1": "secret-1"}),
+		Version:               SignatureVersion2,
+		RequiredSignedHeaders: []string{"Host"},
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(mw(echoHandler))
+	defer server.Close()
+
+	transport, err := NewSignatureTransport(SignatureTransportOptions{
+		KeyID:         "client-1",
+		Secret:        "secret-1",
+		Version:       SignatureVersion2,
+		SignedHeaders: []string{"Host", "Content-Type"},
+	})
+	require.NoError(t, err)
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Post(server.URL+"/pay?amount=10&note=a%20b", "text/plain", strings.NewReader("data"))
+	require.NoError(t, err)
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, string(got))
+	assert.Equal(t, "client-1:data", string(got))
+
+	// A changed query string is rejected.
+	send := func(rawQuery, signedHeaders string) int {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		signer, err := NewHMACSigner(SignatureAlgorithmHMACSHA256, "secret-1")
+		require.NoError(t, err)
+		req := httptest.NewRequest("GET", "/pay?amount=10", nil)
+		sig, err := GenerateSignatureV2(signer, NewSignatureRequest(req, strings.Split(signedHeaders, ";"), timestamp, "", ""))
+		require.NoError(t, err)
+		req.URL.RawQuery = rawQuery
+		req.Header.Set(DefaultSignatureHeader, sig)
+		req.Header.Set(DefaultSignatureTimestampHeader, timestamp)
+		req.Header.Set(DefaultSignatureKeyIDHeader, "client-1")
+		req.Header.Set(DefaultSignatureSignedHeadersHeader, signedHeaders)
+		rec := httptest.NewRecorder()
+		mw(echoHandler).ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, send("amount=10", "host"))
+	assert.Equal(t, http.StatusUnauthorized, send("amount=1000", "host"))
+
+	// Required headers must be signed.
+	assert.Equal(t, http.StatusUnauthorized, send("amount=10", ""))
+
+	// Unsupported versions are rejected.
+	_, err = NewSignatureMiddleware(SignatureMiddlewareOptions{LookupSecret: StaticSecretLookup("s"), Version: 9})
+	assert.Error(t, err)
+	_, err = NewSignatureTransport(SignatureTransportOptions{Secret: "s", Version: 9})
+	assert.Error(t, err)
+	_, err = NewSignatureTransport(SignatureTransportOptions{Secret: "s", Version: SignatureVersion2, SignedHeaders: []string{"bad header"}})
+	assert.Error(t, err)
+}
+
+func TestRequireSignedHeaders(t *testing.T) {
+	signed, err := RequireSignedHeaders("Host;content-type", []string{"host"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"content-type", "host"}, signed)
+
+	_, err = RequireSignedHeaders("content-type", []string{"Host"})
+	assert.ErrorIs(t, err, ErrUnsignedHeader)
+
+	_, err = RequireSignedHeaders("a b", nil)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
@@ -0,0 +1,232 @@
+package xgen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// defaultCodeLength is the number of symbols used by DefaultCodeOptions.
+	defaultCodeLength = 12
+	// defaultCodeGroupSize is the group size used by DefaultCodeOptions.
+	defaultCodeGroupSize = 4
+	// defaultCodeSeparator separates groups when CodeOptions.Separator is empty.
+	defaultCodeSeparator = "-"
+	// maxCodeBatchAttempts bounds the number of draws per code in GenerateCodes.
+	maxCodeBatchAttempts = 100
+)
+
+// codeSymbolValues maps every accepted input character to its Base32 Crockford
+// value, including lowercase letters and the look-alikes O→0 and I/L→1.
+var codeSymbolValues = func() map[rune]int {
+	values := make(map[rune]int, 2*len(Base32Crockford)+6)
+	for i, r := range Base32Crockford {
+		values[r] = i
+		values[toLowerASCII(r)] = i
+	}
+	for _, r := range "Oo" {
+		values[r] = 0
+	}
+	for _, r := range "IiLl" {
+		values[r] = 1
+	}
+	return values
+}()
+
+// CodeOptions configures human-friendly codes such as "K7QM-4XZP-9T25".
+type CodeOptions struct {
+	// Length is the number of symbols in the code, including the check symbol.
+	Length int
+	// GroupSize splits the code into groups of this many symbols. 0 disables grouping.
+	GroupSize int
+	// Separator is placed between groups. Defaults to "-". It must not
+	// contain code symbols or their look-alikes, so codes parse back.
+	Separator string
+	// CheckSymbol appends a Luhn mod 32 check symbol that catches every
+	// single mistyped symbol and most swaps of adjacent symbols.
+	CheckSymbol bool
+}
+
+// DefaultCodeOptions returns options for a 12 symbol code in groups of four
+// with a check symbol, e.g. "K7QM-4XZP-9T25" (55 random bits).
+func DefaultCodeOptions() CodeOptions {
+	return CodeOptions{
+		Length:      defaultCodeLength,
+		GroupSize:   defaultCodeGroupSize,
+		Separator:   defaultCodeSeparator,
+		CheckSymbol: true,
+	}
+}
+
+// GenerateCode generates a random uppercase code over the Base32 Crockford
+// alphabet, which has no ambiguous characters (I, L, O and U are excluded).
+//
+// Example:
+//
+//	code, err := GenerateCode(DefaultCodeOptions())
+//	// code = "K7QM-4XZP-9T25"
+func GenerateCode(opts CodeOptions) (string, error) {
+	randomLen, err := opts.randomLength()
+	if err != nil {
+		return "", err
+	}
+	symbols, err := RandomString(AlphabetBase32Crockford, randomLen)
+	if err != nil {
+		return "", err
+	}
+	if opts.CheckSymbol {
+		symbols += string(codeCheckSymbol(symbols))
+	}
+	return opts.format(symbols), nil
+}
+
+// GenerateCodes generates count distinct codes, for example a batch of gift cards.
+//
+// Returns an error if the code space is too small to produce count distinct codes.
+func GenerateCodes(count int, opts CodeOptions) ([]string, error) {
+	randomLen, err := opts.randomLength()
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("code count must not be negative, got %d", count)
+	}
+	if space := math.Pow(32, float64(randomLen)); float64(count) > space {
+		return nil, fmt.Errorf("cannot generate %d distinct codes with %d random symbols", count, randomLen)
+	}
+
+	codes := make([]string, 0, count)
+	seen := make(map[string]bool, count)
+	for attempts := 0; len(codes) < count; attempts++ {
+		if attempts >= count*maxCodeBatchAttempts {
+			return nil, errors.New("too many collisions generating distinct codes; increase Length")
+		}
+		code, err := GenerateCode(opts)
+		if err != nil {
+			return nil, err
+		}
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// NormalizeCode converts user input into the canonical form of a code.
+//
+// Input is case-insensitive, separators and whitespace are ignored, and the
+// look-alikes O, I and L are read as 0, 1 and 1. Returns an error if the input
+// contains other characters, has the wrong length or fails the check symbol.
+//
+// Example:
+//
+//	code, err := NormalizeCode("k7qm 4xzp-9t25", DefaultCodeOptions())
+//	// code = "K7QM-4XZP-9T25"
+func NormalizeCode(input string, opts CodeOptions) (string, error) {
+	if _, err := opts.randomLength(); err != nil {
+		return "", err
+	}
+	separator := opts.separator()
+	var b strings.Builder
+	b.Grow(len(input))
+	for _, r := range input {
+		if r == ' ' || r == '\t' || r == '-' || strings.ContainsRune(separator, r) {
+			continue
+		}
+		v, ok := codeSymbolValues[r]
+		if !ok {
+			return "", fmt.Errorf("invalid code character %q", r)
+		}
+		b.WriteRune(Base32Crockford[v])
+	}
+	symbols := b.String()
+	if len(symbols) != opts.Length {
+		return "", fmt.Errorf("code must have %d symbols, got %d", opts.Length, len(symbols))
+	}
+	if opts.CheckSymbol && !validCodeCheckSymbol(symbols) {
+		return "", errors.New("code check symbol mismatch")
+	}
+	return opts.format(symbols), nil
+}
+
+// IsValidCode reports whether input is a well-formed code for the options.
+func IsValidCode(input string, opts CodeOptions) bool {
+	_, err := NormalizeCode(input, opts)
+	return err == nil
+}
+
+// randomLength validates the options and returns the number of random symbols.
+func (o CodeOptions) randomLength() (int, error) {
+	if o.GroupSize < 0 {
+		return 0, fmt.Errorf("code group size must not be negative, got %d", o.GroupSize)
+	}
+	for _, r := range o.Separator {
+		if _, ok := codeSymbolValues[r]; ok {
+			return 0, fmt.Errorf("code separator %q must not contain the code symbol %q", o.Separator, r)
+		}
+	}
+	n := o.Length
+	if o.CheckSymbol {
+		n--
+	}
+	if n < 1 {
+		return 0, fmt.Errorf("code length %d leaves no random symbols", o.Length)
+	}
+	return n, nil
+}
+
+// separator returns the group separator, applying the default.
+func (o CodeOptions) separator() string {
+	if o.Separator == "" {
+		return defaultCodeSeparator
+	}
+	return o.Separator
+}
+
+// format splits symbols into groups joined by the separator.
+func (o CodeOptions) format(symbols string) string {
+	if o.GroupSize == 0 || len(symbols) <= o.GroupSize {
+		return symbols
+	}
+	groups := make([]string, 0, (len(symbols)+o.GroupSize-1)/o.GroupSize)
+	for i := 0; i < len(symbols); i += o.GroupSize {
+		groups = append(groups, symbols[i:min(i+o.GroupSize, len(symbols))])
+	}
+	return strings.Join(groups, o.separator())
+}
+
+// codeCheckSymbol computes the Luhn mod 32 check symbol for Base32 Crockford symbols.
+func codeCheckSymbol(symbols string) rune {
+	const n = 32
+	factor, sum := 2, 0
+	for i := len(symbols) - 1; i >= 0; i-- {
+		addend := factor * codeSymbolValues[rune(symbols[i])]
+		factor = 3 - factor
+		sum += addend/n + addend%n
+	}
+	return Base32Crockford[(n-sum%n)%n]
+}
+
+// validCodeCheckSymbol reports whether the last symbol is the Luhn mod 32 check symbol.
+func validCodeCheckSymbol(symbols string) bool {
+	const n = 32
+	factor, sum := 1, 0
+	for i := len(symbols) - 1; i >= 0; i-- {
+		addend := factor * codeSymbolValues[rune(symbols[i])]
+		factor = 3 - factor
+		sum += addend/n + addend%n
+	}
+	return sum%n == 0
+}
+
+// toLowerASCII lower-cases ASCII letters and leaves other runes unchanged.
+func toLowerASCII(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r + ('a' - 'A')
+	}
+	return r
+}
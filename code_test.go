@@ -0,0 +1,181 @@
+package xgen
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    CodeOptions
+		pattern string
+		wantErr bool
+	}{
+		{"default", DefaultCodeOptions(), `^[0-9A-HJKMNP-TV-Z]{4}-[0-9A-HJKMNP-TV-Z]{4}-[0-9A-HJKMNP-TV-Z]{4}$`, false},
+		{"no grouping", CodeOptions{Length: 8}, `^[0-9A-HJKMNP-TV-Z]{8}$`, false},
+		{"uneven groups", CodeOptions{Length: 7, GroupSize: 3}, `^[0-9A-HJKMNP-TV-Z]{3}-[0-9A-HJKMNP-TV-Z]{3}-[0-9A-HJKMNP-TV-Z]$`, false},
+		{"custom separator", CodeOptions{Length: 6, GroupSize: 3, Separator: " "}, `^[0-9A-HJKMNP-TV-Z]{3} [0-9A-HJKMNP-TV-Z]{3}$`, false},
+		{"zero length", CodeOptions{}, "", true},
+		{"only check symbol", CodeOptions{Length: 1, CheckSymbol: true}, "", true},
+		{"negative group size", CodeOptions{Length: 8, GroupSize: -1}, "", true},
+		{"separator in alphabet", CodeOptions{Length: 8, GroupSize: 4, Separator: "X"}, "", true},
+		{"separator look-alike", CodeOptions{Length: 8, GroupSize: 4, Separator: "o"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateCode(tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Regexp(t, regexp.MustCompile(tt.pattern), got)
+		})
+	}
+}
+
+func TestGenerateCode_CheckSymbol(t *testing.T) {
+	opts := DefaultCodeOptions()
+	for i := 0; i < 100; i++ {
+		code, err := GenerateCode(opts)
+		require.NoError(t, err)
+		normalized, err := NormalizeCode(code, opts)
+		require.NoError(t, err)
+		assert.Equal(t, code, normalized)
+	}
+}
+
+func TestNormalizeCode(t *testing.T) {
+	opts := DefaultCodeOptions()
+	code, err := GenerateCode(opts)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"canonical", code, code, false},
+		{"lowercase", strings.ToLower(code), code, false},
+		{"no separators", strings.ReplaceAll(code, "-", ""), code, false},
+		{"spaces", strings.ReplaceAll(code, "-", " "), code, false},
+		{"surrounding whitespace", "  " + code + "\t", code, false},
+		{"invalid character", "K7QM-4XZP-9T2U", "", true},
+		{"too short", code[:len(code)-1], "", true},
+		{"too long", code + "0", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeCode(tt.input, opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalizeCode_LookAlikes(t *testing.T) {
+	opts := CodeOptions{Length: 6, GroupSize: 3}
+
+	got, err := NormalizeCode("o0i-1Ll", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "001-111", got)
+
+	// With a check symbol, look-alikes normalize before validation.
+	withCheck := CodeOptions{Length: 5, CheckSymbol: true}
+	symbols := "0011"
+	code := symbols + string(codeCheckSymbol(symbols))
+	got, err = NormalizeCode("OoIl"+code[4:], withCheck)
+	require.NoError(t, err)
+	assert.Equal(t, code, got)
+}
+
+func TestNormalizeCode_DetectsTypos(t *testing.T) {
+	opts := DefaultCodeOptions()
+	for i := 0; i < 50; i++ {
+		code, err := GenerateCode(opts)
+		require.NoError(t, err)
+		symbols := strings.ReplaceAll(code, "-", "")
+
+		// Every single-symbol substitution must be rejected.
+		for pos := range symbols {
+			for _, r := range Base32Crockford {
+				if byte(r) == symbols[pos] {
+					continue
+				}
+				typo := symbols[:pos] + string(r) + symbols[pos+1:]
+				assert.False(t, IsValidCode(typo, opts), "substitution %q accepted for %q", typo, symbols)
+			}
+		}
+	}
+}
+
+func TestIsValidCode(t *testing.T) {
+	opts := DefaultCodeOptions()
+	code, err := GenerateCode(opts)
+	require.NoError(t, err)
+
+	assert.True(t, IsValidCode(code, opts))
+	assert.False(t, IsValidCode("", opts))
+	assert.False(t, IsValidCode("not-a-code", opts))
+	assert.False(t, IsValidCode(code, CodeOptions{}))
+
+	_, err = NormalizeCode("K7QM1XZP", CodeOptions{Length: 8, GroupSize: 4, Separator: "1"})
+	assert.Error(t, err)
+}
+
+func TestGenerateCodes(t *testing.T) {
+	t.Run("unique batch", func(t *testing.T) {
+		codes, err := GenerateCodes(1000, DefaultCodeOptions())
+		require.NoError(t, err)
+		assert.Len(t, codes, 1000)
+		seen := make(map[string]bool)
+		for _, c := range codes {
+			assert.False(t, seen[c], "duplicate code %s", c)
+			seen[c] = true
+		}
+	})
+
+	t.Run("exhausts small space", func(t *testing.T) {
+		// One random symbol gives exactly 32 possible codes.
+		codes, err := GenerateCodes(32, CodeOptions{Length: 2, CheckSymbol: true})
+		require.NoError(t, err)
+		assert.Len(t, codes, 32)
+	})
+
+	t.Run("space too small", func(t *testing.T) {
+		_, err := GenerateCodes(33, CodeOptions{Length: 1})
+		assert.Error(t, err)
+	})
+
+	t.Run("zero count", func(t *testing.T) {
+		codes, err := GenerateCodes(0, DefaultCodeOptions())
+		require.NoError(t, err)
+		assert.Empty(t, codes)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := GenerateCodes(5, CodeOptions{})
+		assert.Error(t, err)
+		_, err = GenerateCodes(-1, DefaultCodeOptions())
+		assert.Error(t, err)
+	})
+}
+
+func BenchmarkGenerateCode(b *testing.B) {
+	opts := DefaultCodeOptions()
+	for i := 0; i < b.N; i++ {
+		GenerateCode(opts)
+	}
+}
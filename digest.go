@@ -0,0 +1,167 @@
+package xgen
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// ContentDigestHeader is the RFC 9530 header carrying a digest of the request body.
+const ContentDigestHeader = "Content-Digest"
+
+// DigestAlgorithm names an RFC 9530 digest algorithm.
+type DigestAlgorithm string
+
+// Supported digest algorithms.
+const (
+	DigestAlgorithmSHA256 DigestAlgorithm = "sha-256"
+	DigestAlgorithmSHA512 DigestAlgorithm = "sha-512"
+)
+
+var (
+	// ErrMissingContentDigest is returned when a request has no Content-Digest
+	// with a supported algorithm.
+	ErrMissingContentDigest = errors.New("missing content digest")
+	// ErrContentDigestMismatch is returned at the end of the body when it does
+	// not match its Content-Digest.
+	ErrContentDigestMismatch = errors.New("content digest mismatch")
+)
+
+// newDigestHash returns the hash function of alg.
+func newDigestHash(alg DigestAlgorithm) (hash.Hash, error) {
+	switch alg {
+	case DigestAlgorithmSHA256:
+		return sha256.New(), nil
+	case DigestAlgorithmSHA512:
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported digest algorithm %q", alg)
+	}
+}
+
+// formatContentDigest formats a digest as a Content-Digest header value.
+func formatContentDigest(alg DigestAlgorithm, sum []byte) string {
+	return string(alg) + "=:" + base64.StdEncoding.EncodeToString(sum) + ":"
+}
+
+// DigestReader computes the Content-Digest of everything read through it,
+// so a body can be hashed while it is streamed instead of buffered.
+type DigestReader struct {
+	r   io.Reader
+	alg DigestAlgorithm
+	h   hash.Hash
+}
+
+// NewDigestReader returns a DigestReader that reads from r.
+//
+// Example:
+//
+//	dr, _ := NewDigestReader(file, DigestAlgorithmSHA256)
+//	io.Copy(dst, dr)
+//	digest := dr.ContentDigest() // "sha-256=:...:"
+func NewDigestReader(r io.Reader, alg DigestAlgorithm) (*DigestReader, error) {
+	h, err := newDigestHash(alg)
+	if err != nil {
+		return nil, err
+	}
+	return &DigestReader{r: r, alg: alg, h: h}, nil
+}
+
+// Read reads from the underlying reader and hashes the bytes read.
+func (d *DigestReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	d.h.Write(p[:n])
+	return n, err
+}
+
+// ContentDigest returns the Content-Digest header value of the bytes read so far.
+func (d *DigestReader) ContentDigest() string {
+	return formatContentDigest(d.alg, d.h.Sum(nil))
+}
+
+// ContentDigest reads r to EOF and returns its Content-Digest header value,
+// e.g. "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:".
+func ContentDigest(alg DigestAlgorithm, r io.Reader) (string, error) {
+	dr, err := NewDigestReader(r, alg)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(io.Discard, dr); err != nil {
+		return "", err
+	}
+	return dr.ContentDigest(), nil
+}
+
+// contentDigestVerifier checks a body against its Content-Digest at EOF.
+type contentDigestVerifier struct {
+	r       io.ReadCloser
+	hashes  []hash.Hash
+	digests [][]byte
+	err     error
+}
+
+// NewContentDigestVerifier returns a reader that hashes body as it is read and,
+// at EOF, returns ErrContentDigestMismatch instead of io.EOF unless the body
+// matches every sha-256 and sha-512 digest in contentDigest. Other algorithms
+// are ignored; ErrMissingContentDigest is returned when none is supported.
+//
+// Consumers must not act on the body before reading it to EOF.
+func NewContentDigestVerifier(body io.ReadCloser, contentDigest string) (io.ReadCloser, error) {
+	members, err := parseSFDictionary(contentDigest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMissingContentDigest, err)
+	}
+	v := &contentDigestVerifier{r: body}
+	for _, m := range members {
+		item, ok := m.value.(sfItem)
+		if !ok {
+			continue
+		}
+		h, err := newDigestHash(DigestAlgorithm(m.key))
+		if err != nil {
+			continue
+		}
+		sum, ok := item.value.([]byte)
+		if !ok || len(sum) != h.Size() {
+			return nil, fmt.Errorf("%w: malformed %s value", ErrMissingContentDigest, m.key)
+		}
+		v.hashes = append(v.hashes, h)
+		v.digests = append(v.digests, sum)
+	}
+	if len(v.hashes) == 0 {
+		return nil, ErrMissingContentDigest
+	}
+	return v, nil
+}
+
+// Read reads and hashes the body, checking the digests at EOF.
+func (v *contentDigestVerifier) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	n, err := v.r.Read(p)
+	for _, h := range v.hashes {
+		h.Write(p[:n])
+	}
+	if err == io.EOF {
+		for i, h := range v.hashes {
+			if subtle.ConstantTimeCompare(h.Sum(nil), v.digests[i]) != 1 {
+				err = ErrContentDigestMismatch
+			}
+		}
+	}
+	if err != nil {
+		v.err = err
+	}
+	return n, err
+}
+
+// Close closes the underlying body.
+func (v *contentDigestVerifier) Close() error {
+	return v.r.Close()
+}
@@ -0,0 +1,181 @@
+package xgen
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentDigest(t *testing.T) {
+	// RFC 9530 appendix B.1
+	digest, err := ContentDigest(DigestAlgorithmSHA256, strings.NewReader(`{"hello": "world"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:", digest)
+
+	digest, err = ContentDigest(DigestAlgorithmSHA512, strings.NewReader(`{"hello": "world"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:", digest)
+
+	_, err = ContentDigest("md5", strings.NewReader(""))
+	assert.Error(t, err)
+	_, err = ContentDigest(DigestAlgorithmSHA256, errReader{})
+	assert.Error(t, err)
+}
+
+func TestDigestReader(t *testing.T) {
+	dr, err := NewDigestReader(strings.NewReader(`{"hello": "world"}`), DigestAlgorithmSHA256)
+	require.NoError(t, err)
+	var out strings.Builder
+	_, err = io.Copy(&out, dr)
+	require.NoError(t, err)
+	assert.Equal(t, `{"hello": "world"}`, out.String())
+	assert.Equal(t, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:", dr.ContentDigest())
+
+	_, err = NewDigestReader(strings.NewReader(""), "sha-1")
+	assert.Error(t, err)
+}
+
+func TestNewContentDigestVerifier(t *testing.T) {
+	const body = `{"hello": "world"}`
+	read := func(digest, body string) error {
+		v, err := NewContentDigestVerifier(io.NopCloser(strings.NewReader(body)), digest)
+		if err != nil {
+			return err
+		}
+		got, err := io.ReadAll(v)
+		assert.Equal(t, body, string(got))
+		return err
+	}
+
+	sha256 := "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:"
+	sha512 := "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:"
+	assert.NoError(t, read(sha256, body))
+	assert.NoError(t, read(sha512+", "+sha256, body))
+	assert.NoError(t, read("unixsum=:AA==:, "+sha256, body))
+	assert.ErrorIs(t, read(sha256, `{"hello": "there"}`), ErrContentDigestMismatch)
+	assert.ErrorIs(t, read(sha256, ""), ErrContentDigestMismatch)
+
+	// Every supported digest must match.
+	assert.ErrorIs(t, read(sha256+", sha-512=:"+strings.Repeat("A", 86)+"==:", body), ErrContentDigestMismatch)
+
+	for _, bad := range []string{"", "unixsum=:AA==:", "sha-256=:AA==:", "sha-256=1", "sha-256=:AA"} {
+		assert.ErrorIs(t, read(bad, body), ErrMissingContentDigest, bad)
+	}
+}
+
+func TestSignatureMiddleware_ContentDigest(t *testing.T) {
+	var handlerErr error
+	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret:  StaticSecretLookup("secret"),
+		ContentDigest: true,
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		handlerErr = err
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	})))
+	defer server.Close()
+
+	transport, err := NewSignatureTransport(SignatureTransportOptions{Secret: "secret", ContentDigest: DigestAlgorithmSHA512})
+	require.NoError(t, err)
+	client := &http.Client{Transport: transport}
+
+	// Requests with GetBody are hashed from a copy; others are buffered.
+	for _, body := range []io.Reader{strings.NewReader("upload"), io.MultiReader(strings.NewReader("upload"))} {
+		resp, err := client.Post(server.URL+"/upload", "text/plain", body)
+		require.NoError(t, err)
+		got, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "upload", string(got))
+	}
+
+	// A body that does not match the signed digest fails when read to EOF.
+	digest, err := ContentDigest(DigestAlgorithmSHA256, strings.NewReader("upload"))
+	require.NoError(t, err)
+	req := newSignedTestRequest(t, "secret", "", "POST", "/upload", digest)
+	req.Body = io.NopCloser(strings.NewReader("tampered"))
+	req.Header.Set(ContentDigestHeader, digest)
+	rec := httptest.NewRecorder()
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, handlerErr = io.ReadAll(r.Body)
+	})).ServeHTTP(rec, req)
+	assert.ErrorIs(t, handlerErr, ErrContentDigestMismatch)
+
+	// A changed digest header breaks the signature.
+	other, err := ContentDigest(DigestAlgorithmSHA256, strings.NewReader("tampered"))
+	require.NoError(t, err)
+	req = newSignedTestRequest(t, "secret", "", "POST", "/upload", digest)
+	req.Body = io.NopCloser(strings.NewReader("tampered"))
+	req.Header.Set(ContentDigestHeader, other)
+	rec = httptest.NewRecorder()
+	mw(echoHandler).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// The digest header is required.
+	rec = httptest.NewRecorder()
+	mw(echoHandler).ServeHTTP(rec, newSignedTestRequest(t, "secret", "", "POST", "/upload", "upload"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Bodies over MaxBodySize fail while the handler reads them.
+	small, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret:  StaticSecretLookup("secret"),
+		ContentDigest: true,
+		MaxBodySize:   3,
+	})
+	require.NoError(t, err)
+	req = newSignedTestRequest(t, "secret", "", "POST", "/upload", digest)
+	req.Body = io.NopCloser(strings.NewReader("upload"))
+	req.Header.Set(ContentDigestHeader, digest)
+	small(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, handlerErr = io.ReadAll(r.Body)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+	var maxBytesErr *http.MaxBytesError
+	assert.True(t, errors.As(handlerErr, &maxBytesErr))
+}
+
+func TestSignatureTransport_ContentDigestStreaming(t *testing.T) {
+	var sent *http.Request
+	transport, err := NewSignatureTransport(SignatureTransportOptions{
+		Secret:        "secret",
+		ContentDigest: DigestAlgorithmSHA256,
+		Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			sent = r
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+		}),
+	})
+	require.NoError(t, err)
+
+	// A precomputed Content-Digest is signed and the body is passed through unread.
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	req, err := http.NewRequest("PUT", "http://example.com/files/1", pr)
+	require.NoError(t, err)
+	req.Header.Set(ContentDigestHeader, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:")
+	_, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Same(t, req.Body, sent.Body)
+	assert.True(t, VerifySignature("secret", "PUT", "/files/1", sent.Header.Get(DefaultSignatureTimestampHeader),
+		"sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:", sent.Header.Get(DefaultSignatureHeader)))
+
+	// Requests without a body sign the digest of the empty body.
+	req, err = http.NewRequest("GET", "http://example.com/", nil)
+	require.NoError(t, err)
+	_, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, "sha-256=:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=:", sent.Header.Get(ContentDigestHeader))
+
+	_, err = NewSignatureTransport(SignatureTransportOptions{Secret: "secret", ContentDigest: "md5"})
+	assert.Error(t, err)
+}
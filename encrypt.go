@@ -0,0 +1,353 @@
+package xgen
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// EncryptionAlgorithm names an authenticated encryption algorithm. The name is
+// part of every envelope sealed with it.
+type EncryptionAlgorithm string
+
+// Supported encryption algorithms. Both take 32-byte keys.
+const (
+	EncryptionAES256GCM         EncryptionAlgorithm = "aes-256-gcm"
+	EncryptionXChaCha20Poly1305 EncryptionAlgorithm = "xchacha20-poly1305"
+)
+
+const (
+	// envelopeVersion is the first field of every envelope.
+	envelopeVersion = "v1"
+	// envelopeSeparator separates the envelope fields.
+	envelopeSeparator = "."
+	// encryptionKeyLength is the key length in bytes of every algorithm.
+	encryptionKeyLength = 32
+)
+
+// Errors returned by EncryptionKeyring.
+var (
+	// ErrInvalidEnvelope is returned when an envelope is malformed or has an
+	// unsupported version.
+	ErrInvalidEnvelope = errors.New("invalid encrypted envelope")
+	// ErrDecryptionFailed is returned when an envelope does not authenticate,
+	// e.g. because it was tampered with or the associated data differs.
+	ErrDecryptionFailed = errors.New("decryption failed")
+	// ErrUnknownEncryptionKey is returned when an envelope names a key that is
+	// not in the keyring or is disabled.
+	ErrUnknownEncryptionKey = errors.New("unknown encryption key")
+)
+
+// newAEAD returns the AEAD of alg for key.
+func newAEAD(alg EncryptionAlgorithm, key []byte) (cipher.AEAD, error) {
+	if len(key) != encryptionKeyLength {
+		return nil, fmt.Errorf("%s requires a %d-byte key", alg, encryptionKeyLength)
+	}
+	switch alg {
+	case EncryptionAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case EncryptionXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	default:
+		return nil, fmt.Errorf("unsupported encryption algorithm %q", alg)
+	}
+}
+
+// EncryptionKey is a key held by an EncryptionKeyring.
+type EncryptionKey struct {
+	// ID identifies the key in envelopes. Required, unique within a keyring,
+	// and must not contain '.'.
+	ID string
+	// Algorithm is the encryption algorithm. Defaults to EncryptionAES256GCM.
+	Algorithm EncryptionAlgorithm
+	// Key is the 32-byte key, e.g. from MasterKey.DeriveKey with
+	// KeyPurposeTokenEncryption.
+	Key []byte
+	// State selects whether the key seals, only opens, or is disabled.
+	State KeyState
+}
+
+// encryptionKeyEntry is an EncryptionKey with its AEAD.
+type encryptionKeyEntry struct {
+	EncryptionKey
+	aead cipher.AEAD
+}
+
+// EncryptionKeyring seals data with authenticated encryption under its
+// primary key and opens envelopes sealed with any of its enabled keys.
+//
+// Envelopes are text of the form "v1.<algorithm>.<key ID>.<nonce>.<ciphertext>"
+// with base64url nonce and ciphertext, so they can be stored in any string
+// column. The version, algorithm and key ID are authenticated along with the
+// caller's associated data. Nonces are random: rotate AES-256-GCM keys well
+// before 2^32 envelopes, or use XChaCha20-Poly1305 for higher volumes.
+// A keyring holds at most one primary key: making a key primary demotes the
+// previous primary to accepted.
+// An EncryptionKeyring is safe for concurrent use.
+type EncryptionKeyring struct {
+	mu   sync.RWMutex
+	keys []encryptionKeyEntry
+}
+
+// NewEncryptionKeyring creates a keyring holding keys.
+//
+// Example:
+//
+//	master, _ := NewMasterKey(masterSecret, MasterKeyOptions{})
+//	key, _ := master.DeriveKey(KeyPurposeTokenEncryption+"/2024-06", 32)
+//	keyring, err := NewEncryptionKeyring(EncryptionKey{ID: "2024-06", Key: key, State: KeyStatePrimary})
+func NewEncryptionKeyring(keys ...EncryptionKey) (*EncryptionKeyring, error) {
+	k := &EncryptionKeyring{}
+	for _, key := range keys {
+		if err := k.Add(key); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Add adds a key to the keyring. The key ID must not already be present.
+// Adding a primary key demotes the current primary to accepted.
+func (k *EncryptionKeyring) Add(key EncryptionKey) error {
+	if key.ID == "" {
+		return errors.New("encryption key requires ID")
+	}
+	if strings.Contains(key.ID, envelopeSeparator) {
+		return fmt.Errorf("encryption key ID %q must not contain %q", key.ID, envelopeSeparator)
+	}
+	if key.State < KeyStateAccepted || key.State > KeyStateDisabled {
+		return fmt.Errorf("invalid key state %d", int(key.State))
+	}
+	if key.Algorithm == "" {
+		key.Algorithm = EncryptionAES256GCM
+	}
+	key.Key = append([]byte(nil), key.Key...)
+	aead, err := newAEAD(key.Algorithm, key.Key)
+	if err != nil {
+		return fmt.Errorf("encryption key %q: %w", key.ID, err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.indexOf(key.ID) >= 0 {
+		return fmt.Errorf("encryption key %q already exists", key.ID)
+	}
+	if key.State == KeyStatePrimary {
+		k.demotePrimary()
+	}
+	k.keys = append(k.keys, encryptionKeyEntry{EncryptionKey: key, aead: aead})
+	return nil
+}
+
+// Remove deletes the key with the given ID and reports whether it was present.
+// Envelopes sealed with a removed key can no longer be opened.
+func (k *EncryptionKeyring) Remove(id string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	i := k.indexOf(id)
+	if i < 0 {
+		return false
+	}
+	k.keys = append(k.keys[:i], k.keys[i+1:]...)
+	return true
+}
+
+// SetState changes the state of the key with the given ID. Making a key
+// primary demotes the current primary to accepted.
+func (k *EncryptionKeyring) SetState(id string, state KeyState) error {
+	if state < KeyStateAccepted || state > KeyStateDisabled {
+		return fmt.Errorf("invalid key state %d", int(state))
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	i := k.indexOf(id)
+	if i < 0 {
+		return fmt.Errorf("%w: %q", ErrUnknownEncryptionKey, id)
+	}
+	if state == KeyStatePrimary {
+		k.demotePrimary()
+	}
+	k.keys[i].State = state
+	return nil
+}
+
+// Rotate adds key as the primary key and demotes the current primary key to
+// accepted, so existing envelopes still open and are resealed by OpenAndReseal.
+func (k *EncryptionKeyring) Rotate(key EncryptionKey) error {
+	key.State = KeyStatePrimary
+	return k.Add(key)
+}
+
+// Keys returns a copy of all keys in the order they were added.
+func (k *EncryptionKeyring) Keys() []EncryptionKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	keys := make([]EncryptionKey, len(k.keys))
+	for i, entry := range k.keys {
+		keys[i] = entry.EncryptionKey
+		keys[i].Key = append([]byte(nil), entry.Key...)
+	}
+	return keys
+}
+
+// Seal encrypts plaintext under the primary key and returns the envelope.
+// The associated data is authenticated but not stored; the same value must
+// be passed to Open, which binds the envelope to its context, e.g. the
+// database row and column it is stored in.
+//
+// Example:
+//
+//	envelope, err := keyring.Seal([]byte(partnerSecret), []byte("partners/42/secret"))
+func (k *EncryptionKeyring) Seal(plaintext, associatedData []byte) (string, error) {
+	k.mu.RLock()
+	primary, err := k.primary()
+	k.mu.RUnlock()
+	if err != nil {
+		return "", err
+	}
+	return sealEnvelope(primary, plaintext, associatedData)
+}
+
+// Open decrypts an envelope sealed by Seal with any enabled key of the keyring.
+// It returns ErrInvalidEnvelope for malformed envelopes, ErrUnknownEncryptionKey
+// when the key is missing or disabled, and ErrDecryptionFailed when the
+// envelope or associated data does not authenticate.
+func (k *EncryptionKeyring) Open(envelope string, associatedData []byte) ([]byte, error) {
+	plaintext, _, err := k.open(envelope, associatedData)
+	return plaintext, err
+}
+
+// OpenAndReseal opens an envelope and, when it was not sealed with the current
+// primary key, reseals the plaintext under the primary key. It returns the
+// envelope to keep and whether it changed, so callers can write resealed
+// values back and migrate stored data to a new key as it is read.
+//
+// Example:
+//
+//	secret, envelope, resealed, err := keyring.OpenAndReseal(row.Secret, ad)
+//	if err == nil && resealed {
+//		db.UpdateSecret(row.ID, envelope)
+//	}
+func (k *EncryptionKeyring) OpenAndReseal(envelope string, associatedData []byte) (plaintext []byte, current string, resealed bool, err error) {
+	plaintext, keyID, err := k.open(envelope, associatedData)
+	if err != nil {
+		return nil, "", false, err
+	}
+	k.mu.RLock()
+	primary, err := k.primary()
+	k.mu.RUnlock()
+	if err != nil {
+		return nil, "", false, err
+	}
+	if primary.ID == keyID {
+		return plaintext, envelope, false, nil
+	}
+	current, err = sealEnvelope(primary, plaintext, associatedData)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return plaintext, current, true, nil
+}
+
+// open decrypts envelope and returns the plaintext and the ID of its key.
+func (k *EncryptionKeyring) open(envelope string, associatedData []byte) ([]byte, string, error) {
+	parts := strings.Split(envelope, envelopeSeparator)
+	if len(parts) != 5 || parts[0] != envelopeVersion || parts[2] == "" {
+		return nil, "", ErrInvalidEnvelope
+	}
+	nonce, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, "", ErrInvalidEnvelope
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, "", ErrInvalidEnvelope
+	}
+
+	k.mu.RLock()
+	i := k.indexOf(parts[2])
+	var key encryptionKeyEntry
+	if i >= 0 {
+		key = k.keys[i]
+	}
+	k.mu.RUnlock()
+	if i < 0 || key.State == KeyStateDisabled {
+		return nil, "", fmt.Errorf("%w: %q", ErrUnknownEncryptionKey, parts[2])
+	}
+	// The algorithm comes from the key; the envelope only has to agree.
+	if parts[1] != string(key.Algorithm) || len(nonce) != key.aead.NonceSize() {
+		return nil, "", ErrDecryptionFailed
+	}
+	plaintext, err := key.aead.Open(nil, nonce, ciphertext, envelopeAdditionalData(key.EncryptionKey, associatedData))
+	if err != nil {
+		return nil, "", ErrDecryptionFailed
+	}
+	return plaintext, key.ID, nil
+}
+
+// primary returns the primary key. Add and SetState keep at most one key
+// primary. The caller must hold the lock.
+func (k *EncryptionKeyring) primary() (encryptionKeyEntry, error) {
+	for _, key := range k.keys {
+		if key.State == KeyStatePrimary {
+			return key, nil
+		}
+	}
+	return encryptionKeyEntry{}, ErrNoPrimaryKey
+}
+
+// demotePrimary demotes the primary key, if any, to accepted. The caller must
+// hold the write lock.
+func (k *EncryptionKeyring) demotePrimary() {
+	for i := range k.keys {
+		if k.keys[i].State == KeyStatePrimary {
+			k.keys[i].State = KeyStateAccepted
+		}
+	}
+}
+
+// indexOf returns the position of the key with the given ID, or -1.
+// The caller must hold the lock.
+func (k *EncryptionKeyring) indexOf(id string) int {
+	for i, key := range k.keys {
+		if key.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// sealEnvelope encrypts plaintext with key under a random nonce.
+func sealEnvelope(key encryptionKeyEntry, plaintext, associatedData []byte) (string, error) {
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	ciphertext := key.aead.Seal(nil, nonce, plaintext, envelopeAdditionalData(key.EncryptionKey, associatedData))
+	return envelopeHeader(key.EncryptionKey) +
+		base64.RawURLEncoding.EncodeToString(nonce) + envelopeSeparator +
+		base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+// envelopeHeader returns "v1.<algorithm>.<key ID>." for key. Key IDs cannot
+// contain the separator, so the header is unambiguous.
+func envelopeHeader(key EncryptionKey) string {
+	return envelopeVersion + envelopeSeparator + string(key.Algorithm) + envelopeSeparator + key.ID + envelopeSeparator
+}
+
+// envelopeAdditionalData binds the envelope header to the caller's
+// associated data, so the version, algorithm and key ID cannot be swapped.
+func envelopeAdditionalData(key EncryptionKey, associatedData []byte) []byte {
+	return append([]byte(envelopeHeader(key)), associatedData...)
+}
@@ -0,0 +1,202 @@
+package xgen
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEncryptionKey(t *testing.T, id string, alg EncryptionAlgorithm, state KeyState) EncryptionKey {
+	t.Helper()
+	master, err := NewMasterKey(strings.Repeat("m", 32), MasterKeyOptions{})
+	require.NoError(t, err)
+	key, err := master.DeriveKey(KeyPurposeTokenEncryption+"/"+KeyPurpose(id), 32)
+	require.NoError(t, err)
+	return EncryptionKey{ID: id, Algorithm: alg, Key: key, State: state}
+}
+
+func TestEncryptionKeyring_SealOpen(t *testing.T) {
+	for _, alg := range []EncryptionAlgorithm{EncryptionAES256GCM, EncryptionXChaCha20Poly1305} {
+		t.Run(string(alg), func(t *testing.T) {
+			keyring, err := NewEncryptionKeyring(newTestEncryptionKey(t, "k1", alg, KeyStatePrimary))
+			require.NoError(t, err)
+			ad := []byte("partners/42/secret")
+
+			envelope, err := keyring.Seal([]byte("partner-secret"), ad)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(envelope, "v1."+string(alg)+".k1."), envelope)
+			assert.NotContains(t, envelope, "partner-secret")
+
+			plaintext, err := keyring.Open(envelope, ad)
+			require.NoError(t, err)
+			assert.Equal(t, "partner-secret", string(plaintext))
+
+			// Random nonces: the same plaintext never seals to the same envelope.
+			again, err := keyring.Seal([]byte("partner-secret"), ad)
+			require.NoError(t, err)
+			assert.NotEqual(t, envelope, again)
+
+			_, err = keyring.Open(envelope, []byte("partners/43/secret"))
+			assert.ErrorIs(t, err, ErrDecryptionFailed)
+			_, err = keyring.Open(envelope, nil)
+			assert.ErrorIs(t, err, ErrDecryptionFailed)
+
+			parts := strings.Split(envelope, ".")
+			ciphertext, _ := base64.RawURLEncoding.DecodeString(parts[4])
+			ciphertext[0] ^= 1
+			parts[4] = base64.RawURLEncoding.EncodeToString(ciphertext)
+			_, err = keyring.Open(strings.Join(parts, "."), ad)
+			assert.ErrorIs(t, err, ErrDecryptionFailed)
+
+			empty, err := keyring.Seal(nil, nil)
+			require.NoError(t, err)
+			plaintext, err = keyring.Open(empty, nil)
+			require.NoError(t, err)
+			assert.Empty(t, plaintext)
+		})
+	}
+}
+
+func TestEncryptionKeyring_Envelope(t *testing.T) {
+	aes := newTestEncryptionKey(t, "k1", EncryptionAES256GCM, KeyStatePrimary)
+	xchacha := newTestEncryptionKey(t, "k2", EncryptionXChaCha20Poly1305, KeyStateAccepted)
+	keyring, err := NewEncryptionKeyring(aes, xchacha)
+	require.NoError(t, err)
+	envelope, err := keyring.Seal([]byte("data"), nil)
+	require.NoError(t, err)
+	parts := strings.Split(envelope, ".")
+
+	for _, tt := range []struct {
+		envelope string
+		err      error
+	}{
+		{"", ErrInvalidEnvelope},
+		{"v2." + strings.Join(parts[1:], "."), ErrInvalidEnvelope},
+		{strings.Join(parts[:4], "."), ErrInvalidEnvelope},
+		{envelope + ".x", ErrInvalidEnvelope},
+		{strings.Join([]string{parts[0], parts[1], parts[2], "!", parts[4]}, "."), ErrInvalidEnvelope},
+		{strings.Join([]string{parts[0], parts[1], parts[2], parts[3], "!"}, "."), ErrInvalidEnvelope},
+		{strings.Join([]string{parts[0], parts[1], "", parts[3], parts[4]}, "."), ErrInvalidEnvelope},
+		{strings.Join([]string{parts[0], parts[1], "k9", parts[3], parts[4]}, "."), ErrUnknownEncryptionKey},
+		// The header is authenticated: swapping the key ID or algorithm fails.
+		{strings.Join([]string{parts[0], string(EncryptionXChaCha20Poly1305), parts[2], parts[3], parts[4]}, "."), ErrDecryptionFailed},
+		{strings.Join([]string{parts[0], string(EncryptionXChaCha20Poly1305), "k2", parts[3], parts[4]}, "."), ErrDecryptionFailed},
+		{strings.Join([]string{parts[0], parts[1], parts[2], "AAAA", parts[4]}, "."), ErrDecryptionFailed},
+	} {
+		_, err := keyring.Open(tt.envelope, nil)
+		assert.ErrorIs(t, err, tt.err, tt.envelope)
+	}
+
+	// A keyring with the same AES key under another ID cannot open the envelope.
+	renamed := aes
+	renamed.ID = "k3"
+	other, err := NewEncryptionKeyring(renamed)
+	require.NoError(t, err)
+	_, err = other.Open(strings.Replace(envelope, ".k1.", ".k3.", 1), nil)
+	assert.ErrorIs(t, err, ErrDecryptionFailed)
+
+	require.NoError(t, keyring.SetState("k1", KeyStateDisabled))
+	_, err = keyring.Open(envelope, nil)
+	assert.ErrorIs(t, err, ErrUnknownEncryptionKey)
+	_, err = keyring.Seal([]byte("data"), nil)
+	assert.ErrorIs(t, err, ErrNoPrimaryKey)
+}
+
+func TestEncryptionKeyring_Rotation(t *testing.T) {
+	keyring, err := NewEncryptionKeyring(newTestEncryptionKey(t, "2024-01", "", KeyStatePrimary))
+	require.NoError(t, err)
+	ad := []byte("row-1")
+	old, err := keyring.Seal([]byte("secret"), ad)
+	require.NoError(t, err)
+
+	plaintext, current, resealed, err := keyring.OpenAndReseal(old, ad)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+	assert.Equal(t, old, current)
+	assert.False(t, resealed)
+
+	require.NoError(t, keyring.Rotate(newTestEncryptionKey(t, "2024-06", EncryptionXChaCha20Poly1305, KeyStateAccepted)))
+	assert.Equal(t, []KeyState{KeyStateAccepted, KeyStatePrimary}, []KeyState{keyring.Keys()[0].State, keyring.Keys()[1].State})
+
+	// Old envelopes still open and are resealed under the new primary key.
+	plaintext, current, resealed, err = keyring.OpenAndReseal(old, ad)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+	assert.True(t, resealed)
+	assert.True(t, strings.HasPrefix(current, "v1.xchacha20-poly1305.2024-06."), current)
+
+	_, again, resealed, err := keyring.OpenAndReseal(current, ad)
+	require.NoError(t, err)
+	assert.Equal(t, current, again)
+	assert.False(t, resealed)
+
+	_, _, _, err = keyring.OpenAndReseal(old, []byte("row-2"))
+	assert.ErrorIs(t, err, ErrDecryptionFailed)
+
+	// Once every value is resealed the old key can be removed.
+	assert.True(t, keyring.Remove("2024-01"))
+	assert.False(t, keyring.Remove("2024-01"))
+	_, err = keyring.Open(old, ad)
+	assert.ErrorIs(t, err, ErrUnknownEncryptionKey)
+	plaintext, err = keyring.Open(current, ad)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+}
+
+func TestEncryptionKeyring_SinglePrimary(t *testing.T) {
+	keyring, err := NewEncryptionKeyring(
+		newTestEncryptionKey(t, "k1", "", KeyStatePrimary),
+		newTestEncryptionKey(t, "k2", "", KeyStatePrimary),
+	)
+	require.NoError(t, err)
+	states := func() []KeyState {
+		var states []KeyState
+		for _, key := range keyring.Keys() {
+			states = append(states, key.State)
+		}
+		return states
+	}
+	assert.Equal(t, []KeyState{KeyStateAccepted, KeyStatePrimary}, states())
+
+	envelope, err := keyring.Seal([]byte("data"), nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(envelope, "v1.aes-256-gcm.k2."), envelope)
+
+	require.NoError(t, keyring.SetState("k1", KeyStatePrimary))
+	assert.Equal(t, []KeyState{KeyStatePrimary, KeyStateAccepted}, states())
+
+	require.NoError(t, keyring.Add(newTestEncryptionKey(t, "k3", "", KeyStatePrimary)))
+	assert.Equal(t, []KeyState{KeyStateAccepted, KeyStateAccepted, KeyStatePrimary}, states())
+}
+
+func TestEncryptionKeyring_Keys(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	keyring, err := NewEncryptionKeyring(EncryptionKey{ID: "k1", Key: key})
+	require.NoError(t, err)
+	// Keys are copied in and out of the keyring.
+	key[0] = 2
+	keys := keyring.Keys()
+	assert.Equal(t, EncryptionAES256GCM, keys[0].Algorithm)
+	assert.Equal(t, byte(1), keys[0].Key[0])
+	keys[0].Key[0] = 3
+	assert.Equal(t, byte(1), keyring.Keys()[0].Key[0])
+
+	for _, bad := range []EncryptionKey{
+		{Key: key},
+		{ID: "a.b", Key: key},
+		{ID: "k2", Key: key[:16]},
+		{ID: "k2", Key: key, Algorithm: "aes-128-cbc"},
+		{ID: "k2", Key: key, State: KeyState(7)},
+		{ID: "k1", Key: key},
+	} {
+		assert.Error(t, keyring.Add(bad), bad.ID)
+	}
+	assert.Error(t, keyring.SetState("k1", KeyState(-1)))
+	assert.ErrorIs(t, keyring.SetState("k9", KeyStatePrimary), ErrUnknownEncryptionKey)
+	_, err = NewEncryptionKeyring(EncryptionKey{ID: "k1"})
+	assert.Error(t, err)
+}
@@ -2,9 +2,15 @@ package xgen
 
 import (
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -12,6 +18,19 @@ import (
 // Base32Crockford is the alphabet used for Base32 Crockford encoding.
 var Base32Crockford = []rune("0123456789ABCDEFGHJKMNPQRSTVWXYZ")
 
+// Preset alphabets for RandomString.
+const (
+	AlphabetDigits          = "0123456789"
+	AlphabetHex             = "0123456789abcdef"
+	AlphabetHexUpper        = "0123456789ABCDEF"
+	AlphabetBase32Crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	AlphabetBase58          = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	AlphabetBase62          = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	AlphabetAlphaLower      = "abcdefghijklmnopqrstuvwxyz"
+	AlphabetAlphaUpper      = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	AlphabetURLSafe         = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+)
+
 // GenerateUUID returns a UUID (v7 if possible, otherwise v4).
 func GenerateUUID() uuid.UUID {
 	if id, err := uuid.NewV7(); err == nil {
@@ -50,6 +69,7 @@ func GenerateNanosID(suffixLength int) string {
 }
 
 // RandomBase32String generates a random Base32 string of specified length.
+// Since 32 divides 256, mapping each random byte with % 32 is unbiased.
 func RandomBase32String(n int) string {
 	if n <= 0 {
 		return ""
@@ -67,6 +87,248 @@ func RandomBase32String(n int) string {
 	return string(result)
 }
 
+// RandomString generates a random string of n symbols drawn uniformly from alphabet.
+//
+// The alphabet must be valid UTF-8 with at least two distinct symbols; see the
+// Alphabet* constants for common presets. Random bytes are read from crypto/rand
+// in batches and masked to the smallest power of two covering the alphabet;
+// values outside the alphabet are rejected, so there is no modulo bias.
+// Returns an empty string when n <= 0.
+//
+// Example:
+//
+//	s, err := RandomString(AlphabetBase62, 12)
+//	// s = "4fQz9LbXc0Ta"
+func RandomString(alphabet string, n int) (string, error) {
+	symbols, err := alphabetSymbols(alphabet)
+	if err != nil {
+		return "", err
+	}
+	if n <= 0 {
+		return "", nil
+	}
+	result := make([]rune, n)
+	if len(symbols) > 256 {
+		// Large alphabets cannot be sampled a byte at a time.
+		for i := range result {
+			idx, err := randomIndex(len(symbols))
+			if err != nil {
+				return "", err
+			}
+			result[i] = symbols[idx]
+		}
+		return string(result), nil
+	}
+
+	mask := 1<<bits.Len(uint(len(symbols)-1)) - 1
+	// On average (mask+1)/len(symbols) bytes are needed per symbol; the 1.6
+	// headroom means a single read is almost always enough.
+	step := int(math.Ceil(1.6 * float64(mask+1) * float64(n) / float64(len(symbols))))
+	buf := make([]byte, step)
+	i := 0
+	for {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			idx := int(b) & mask
+			if idx >= len(symbols) {
+				continue
+			}
+			result[i] = symbols[idx]
+			i++
+			if i == n {
+				return string(result), nil
+			}
+		}
+	}
+}
+
+// alphabetSymbols splits alphabet into runes and checks it is usable for RandomString.
+func alphabetSymbols(alphabet string) ([]rune, error) {
+	if !utf8.ValidString(alphabet) {
+		return nil, errors.New("alphabet must be valid UTF-8")
+	}
+	symbols := []rune(alphabet)
+	if len(symbols) < 2 {
+		return nil, errors.New("alphabet must contain at least two symbols")
+	}
+	seen := make(map[rune]bool, len(symbols))
+	for _, r := range symbols {
+		if seen[r] {
+			return nil, fmt.Errorf("alphabet contains duplicate symbol %q", r)
+		}
+		seen[r] = true
+	}
+	return symbols, nil
+}
+
+// RandomInt returns a uniformly distributed integer in the inclusive range [min, max]
+// using crypto/rand.
+//
+// Returns an error if min > max.
+//
+// Example:
+//
+//	roll, err := RandomInt(1, 6)
+//	// roll = 4
+func RandomInt(min, max int) (int, error) {
+	if min > max {
+		return 0, fmt.Errorf("invalid random range: min %d > max %d", min, max)
+	}
+	// The span wraps to 0 when the range covers every int64 value.
+	span := uint64(max) - uint64(min) + 1
+	var v uint64
+	var err error
+	if span == 0 {
+		v, err = randomUint64()
+	} else {
+		v, err = randomUint64n(span)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int(uint64(min) + v), nil
+}
+
+// RandomFloat64 returns a uniformly distributed float64 in [0.0, 1.0) using crypto/rand.
+func RandomFloat64() (float64, error) {
+	v, err := randomUint64()
+	if err != nil {
+		return 0, err
+	}
+	// Keep the top 53 bits, the precision of a float64 mantissa.
+	return float64(v>>11) / (1 << 53), nil
+}
+
+// RandomShuffle shuffles items in place using a Fisher-Yates shuffle driven by crypto/rand.
+// Every permutation is equally likely.
+func RandomShuffle[T any](items []T) error {
+	for i := len(items) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return err
+		}
+		items[i], items[j] = items[j], items[i]
+	}
+	return nil
+}
+
+// RandomSample returns k distinct elements of items chosen uniformly at random
+// without replacement, in random order. The input slice is not modified.
+//
+// Returns an error if k is negative or greater than len(items).
+//
+// Example:
+//
+//	winners, err := RandomSample(entrants, 3)
+func RandomSample[T any](items []T, k int) ([]T, error) {
+	if k < 0 || k > len(items) {
+		return nil, fmt.Errorf("sample size %d out of range [0, %d]", k, len(items))
+	}
+	pool := make([]T, len(items))
+	copy(pool, items)
+	// Partial Fisher-Yates: only the first k positions need to be settled.
+	for i := range k {
+		j, err := randomIndex(len(pool) - i)
+		if err != nil {
+			return nil, err
+		}
+		pool[i], pool[i+j] = pool[i+j], pool[i]
+	}
+	return pool[:k], nil
+}
+
+// RandomWeightedIndex returns an index into weights chosen with probability
+// proportional to its weight, e.g. weights {90, 10} picks index 0 90% of the time.
+//
+// Weights must be non-negative with a positive total; zero-weight entries are never picked.
+func RandomWeightedIndex(weights []int) (int, error) {
+	var total uint64
+	for i, w := range weights {
+		if w < 0 {
+			return 0, fmt.Errorf("weight at index %d is negative: %d", i, w)
+		}
+		if total+uint64(w) < total {
+			return 0, errors.New("sum of weights overflows")
+		}
+		total += uint64(w)
+	}
+	if total == 0 {
+		return 0, errors.New("weights must have a positive total")
+	}
+	r, err := randomUint64n(total)
+	if err != nil {
+		return 0, err
+	}
+	for i, w := range weights {
+		if r < uint64(w) {
+			return i, nil
+		}
+		r -= uint64(w)
+	}
+	// Unreachable: r < total.
+	return len(weights) - 1, nil
+}
+
+// RandomWeightedChoice returns an element of items chosen with probability
+// proportional to the weight at the same index.
+//
+// Example:
+//
+//	variant, err := RandomWeightedChoice([]string{"control", "treatment"}, []int{90, 10})
+func RandomWeightedChoice[T any](items []T, weights []int) (T, error) {
+	var zero T
+	if len(items) != len(weights) {
+		return zero, fmt.Errorf("got %d items but %d weights", len(items), len(weights))
+	}
+	idx, err := RandomWeightedIndex(weights)
+	if err != nil {
+		return zero, err
+	}
+	return items[idx], nil
+}
+
+// randomIndex returns a uniformly distributed integer in [0, n) read from crypto/rand.
+func randomIndex(n int) (int, error) {
+	if n <= 0 {
+		return 0, fmt.Errorf("random index bound %d out of range", n)
+	}
+	v, err := randomUint64n(uint64(n))
+	if err != nil {
+		return 0, err
+	}
+	return int(v), nil
+}
+
+// randomUint64n returns a uniformly distributed integer in [0, bound) read from crypto/rand.
+// Values from the biased low end of the uint64 range are rejected and redrawn.
+func randomUint64n(bound uint64) (uint64, error) {
+	if bound == 0 {
+		return 0, errors.New("random bound must be positive")
+	}
+	// threshold = 2^64 mod bound; values below it would over-represent small results.
+	threshold := -bound % bound
+	for {
+		v, err := randomUint64()
+		if err != nil {
+			return 0, err
+		}
+		if v >= threshold {
+			return v % bound, nil
+		}
+	}
+}
+
+// randomUint64 returns 64 random bits read from crypto/rand.
+func randomUint64() (uint64, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
 // encodeTimestampMicrosBase32 encodes current timestamp to Base32 with specified length.
 func encodeTimestampMicrosBase32(prefixLength int) string {
 	ts := timestampMicros()
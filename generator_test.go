@@ -1,9 +1,12 @@
 package xgen
 
 import (
+	"math"
 	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestGenerateUUID(t *testing.T) {
@@ -211,6 +214,358 @@ func TestRandomBase32String(t *testing.T) {
 	})
 }
 
+func TestRandomString(t *testing.T) {
+	tests := []struct {
+		name     string
+		alphabet string
+		length   int
+		wantLen  int
+		wantErr  bool
+	}{
+		{"digits", AlphabetDigits, 10, 10, false},
+		{"hex", AlphabetHex, 32, 32, false},
+		{"base58", AlphabetBase58, 20, 20, false},
+		{"base62", AlphabetBase62, 100, 100, false},
+		{"url safe", AlphabetURLSafe, 43, 43, false},
+		{"binary", "01", 64, 64, false},
+		{"unicode", "αβγδ", 8, 8, false},
+		{"zero length", AlphabetBase62, 0, 0, false},
+		{"negative length", AlphabetBase62, -1, 0, false},
+		{"empty alphabet", "", 10, 0, true},
+		{"single symbol", "a", 10, 0, true},
+		{"duplicate symbols", "abca", 10, 0, true},
+		{"invalid utf-8", "a\xffb", 10, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RandomString(tt.alphabet, tt.length)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RandomString(%q, %d) error = %v, wantErr %v", tt.alphabet, tt.length, err, tt.wantErr)
+				return
+			}
+			if n := utf8.RuneCountInString(got); n != tt.wantLen {
+				t.Errorf("RandomString(%q, %d) length = %d, want %d", tt.alphabet, tt.length, n, tt.wantLen)
+			}
+			for _, r := range got {
+				if !strings.ContainsRune(tt.alphabet, r) {
+					t.Errorf("RandomString(%q, %d) = %v, contains symbol %q outside alphabet", tt.alphabet, tt.length, got, r)
+				}
+			}
+		})
+	}
+
+	t.Run("large alphabet", func(t *testing.T) {
+		var b strings.Builder
+		for r := rune(0x4E00); r < 0x4E00+300; r++ {
+			b.WriteRune(r)
+		}
+		got, err := RandomString(b.String(), 50)
+		if err != nil {
+			t.Fatalf("RandomString() error = %v", err)
+		}
+		if n := utf8.RuneCountInString(got); n != 50 {
+			t.Errorf("RandomString() length = %d, want 50", n)
+		}
+	})
+
+	// Base62 is not a power of two, so a modulo mapping would favour the first
+	// 256 % 62 = 8 symbols by roughly 25%. A chi-squared test catches that.
+	t.Run("uniformity", func(t *testing.T) {
+		const samples = 620000
+		got, err := RandomString(AlphabetBase62, samples)
+		if err != nil {
+			t.Fatalf("RandomString() error = %v", err)
+		}
+		counts := make([]int, len(AlphabetBase62))
+		for i := 0; i < len(got); i++ {
+			counts[strings.IndexByte(AlphabetBase62, got[i])]++
+		}
+		// Critical value for 61 degrees of freedom at p = 0.0001 is about 109.
+		if chi := chiSquared(counts, samples); chi > 109 {
+			t.Errorf("RandomString() chi-squared = %.1f, distribution is not uniform: %v", chi, counts)
+		}
+	})
+}
+
+func TestRandomInt(t *testing.T) {
+	tests := []struct {
+		name    string
+		min     int
+		max     int
+		wantErr bool
+	}{
+		{"dice", 1, 6, false},
+		{"single value", 5, 5, false},
+		{"negative range", -10, -1, false},
+		{"spans zero", -3, 3, false},
+		{"full range", math.MinInt, math.MaxInt, false},
+		{"min greater than max", 6, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				got, err := RandomInt(tt.min, tt.max)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("RandomInt(%d, %d) error = %v, wantErr %v", tt.min, tt.max, err, tt.wantErr)
+				}
+				if tt.wantErr {
+					return
+				}
+				if got < tt.min || got > tt.max {
+					t.Fatalf("RandomInt(%d, %d) = %d, out of range", tt.min, tt.max, got)
+				}
+			}
+		})
+	}
+
+	t.Run("uniformity", func(t *testing.T) {
+		const samples = 60000
+		counts := make([]int, 6)
+		for i := 0; i < samples; i++ {
+			v, err := RandomInt(1, 6)
+			if err != nil {
+				t.Fatalf("RandomInt() error = %v", err)
+			}
+			counts[v-1]++
+		}
+		// Critical value for 5 degrees of freedom at p = 0.0001 is about 25.7.
+		if chi := chiSquared(counts, samples); chi > 25.7 {
+			t.Errorf("RandomInt() chi-squared = %.1f, distribution is not uniform: %v", chi, counts)
+		}
+	})
+}
+
+func TestRandomFloat64(t *testing.T) {
+	const samples = 50000
+	counts := make([]int, 10)
+	for i := 0; i < samples; i++ {
+		f, err := RandomFloat64()
+		if err != nil {
+			t.Fatalf("RandomFloat64() error = %v", err)
+		}
+		if f < 0 || f >= 1 {
+			t.Fatalf("RandomFloat64() = %v, out of range [0, 1)", f)
+		}
+		counts[int(f*10)]++
+	}
+	// Critical value for 9 degrees of freedom at p = 0.0001 is about 33.7.
+	if chi := chiSquared(counts, samples); chi > 33.7 {
+		t.Errorf("RandomFloat64() chi-squared = %.1f, distribution is not uniform: %v", chi, counts)
+	}
+}
+
+func TestRandomShuffle(t *testing.T) {
+	t.Run("keeps elements", func(t *testing.T) {
+		items := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		if err := RandomShuffle(items); err != nil {
+			t.Fatalf("RandomShuffle() error = %v", err)
+		}
+		sorted := slices.Clone(items)
+		slices.Sort(sorted)
+		if !slices.Equal(sorted, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}) {
+			t.Errorf("RandomShuffle() = %v, lost or duplicated elements", items)
+		}
+	})
+
+	t.Run("empty and single", func(t *testing.T) {
+		if err := RandomShuffle([]string{}); err != nil {
+			t.Errorf("RandomShuffle(empty) error = %v", err)
+		}
+		one := []string{"a"}
+		if err := RandomShuffle(one); err != nil || one[0] != "a" {
+			t.Errorf("RandomShuffle(single) = %v, %v", one, err)
+		}
+	})
+
+	// All 6 permutations of three elements should be equally likely.
+	t.Run("uniformity", func(t *testing.T) {
+		const samples = 60000
+		perms := map[string]int{}
+		for i := 0; i < samples; i++ {
+			items := []byte("abc")
+			if err := RandomShuffle(items); err != nil {
+				t.Fatalf("RandomShuffle() error = %v", err)
+			}
+			perms[string(items)]++
+		}
+		if len(perms) != 6 {
+			t.Fatalf("RandomShuffle() produced %d permutations, want 6", len(perms))
+		}
+		counts := make([]int, 0, 6)
+		for _, c := range perms {
+			counts = append(counts, c)
+		}
+		if chi := chiSquared(counts, samples); chi > 25.7 {
+			t.Errorf("RandomShuffle() chi-squared = %.1f, permutations are not uniform: %v", chi, perms)
+		}
+	})
+}
+
+func TestRandomSample(t *testing.T) {
+	items := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	tests := []struct {
+		name    string
+		k       int
+		wantErr bool
+	}{
+		{"zero", 0, false},
+		{"three", 3, false},
+		{"all", 10, false},
+		{"negative", -1, true},
+		{"too many", 11, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RandomSample(items, tt.k)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RandomSample(%d) error = %v, wantErr %v", tt.k, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != tt.k {
+				t.Errorf("RandomSample(%d) length = %d", tt.k, len(got))
+			}
+			seen := map[int]bool{}
+			for _, v := range got {
+				if seen[v] {
+					t.Errorf("RandomSample(%d) = %v, contains duplicate %d", tt.k, got, v)
+				}
+				seen[v] = true
+			}
+		})
+	}
+
+	t.Run("does not modify input", func(t *testing.T) {
+		in := []int{1, 2, 3, 4}
+		if _, err := RandomSample(in, 2); err != nil {
+			t.Fatalf("RandomSample() error = %v", err)
+		}
+		if !slices.Equal(in, []int{1, 2, 3, 4}) {
+			t.Errorf("RandomSample() modified input: %v", in)
+		}
+	})
+
+	t.Run("uniformity", func(t *testing.T) {
+		const draws = 20000
+		counts := make([]int, len(items))
+		for i := 0; i < draws; i++ {
+			got, err := RandomSample(items, 3)
+			if err != nil {
+				t.Fatalf("RandomSample() error = %v", err)
+			}
+			for _, v := range got {
+				counts[v]++
+			}
+		}
+		if chi := chiSquared(counts, draws*3); chi > 33.7 {
+			t.Errorf("RandomSample() chi-squared = %.1f, selection is not uniform: %v", chi, counts)
+		}
+	})
+}
+
+func TestRandomWeightedIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []int
+		wantErr bool
+	}{
+		{"single", []int{1}, false},
+		{"zero weight skipped", []int{0, 5, 0}, false},
+		{"empty", nil, true},
+		{"all zero", []int{0, 0}, true},
+		{"negative", []int{1, -1}, true},
+		{"overflow", []int{math.MaxInt, math.MaxInt, math.MaxInt}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RandomWeightedIndex(tt.weights)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RandomWeightedIndex(%v) error = %v, wantErr %v", tt.weights, err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.weights[got] == 0 {
+				t.Errorf("RandomWeightedIndex(%v) = %d, picked zero weight", tt.weights, got)
+			}
+		})
+	}
+
+	t.Run("proportional", func(t *testing.T) {
+		const samples = 100000
+		weights := []int{70, 20, 10}
+		counts := make([]int, len(weights))
+		for i := 0; i < samples; i++ {
+			idx, err := RandomWeightedIndex(weights)
+			if err != nil {
+				t.Fatalf("RandomWeightedIndex() error = %v", err)
+			}
+			counts[idx]++
+		}
+		var chi float64
+		for i, w := range weights {
+			expected := float64(samples) * float64(w) / 100
+			d := float64(counts[i]) - expected
+			chi += d * d / expected
+		}
+		// Critical value for 2 degrees of freedom at p = 0.0001 is about 18.4.
+		if chi > 18.4 {
+			t.Errorf("RandomWeightedIndex() chi-squared = %.1f, counts = %v", chi, counts)
+		}
+	})
+}
+
+func TestRandomWeightedChoice(t *testing.T) {
+	got, err := RandomWeightedChoice([]string{"control", "treatment"}, []int{0, 1})
+	if err != nil {
+		t.Fatalf("RandomWeightedChoice() error = %v", err)
+	}
+	if got != "treatment" {
+		t.Errorf("RandomWeightedChoice() = %v, want treatment", got)
+	}
+
+	if _, err := RandomWeightedChoice([]string{"a", "b"}, []int{1}); err == nil {
+		t.Error("RandomWeightedChoice() expected error for mismatched lengths")
+	}
+}
+
+func TestRandomIndex(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		if _, err := randomIndex(n); err == nil {
+			t.Errorf("randomIndex(%d) expected error", n)
+		}
+	}
+	for _, n := range []int{1, 2, 3, 7, 100, 7776} {
+		for i := 0; i < 100; i++ {
+			idx, err := randomIndex(n)
+			if err != nil {
+				t.Fatalf("randomIndex(%d) error = %v", n, err)
+			}
+			if idx < 0 || idx >= n {
+				t.Errorf("randomIndex(%d) = %d, out of range", n, idx)
+			}
+		}
+	}
+}
+
+func TestRandomUint64n(t *testing.T) {
+	if _, err := randomUint64n(0); err == nil {
+		t.Error("randomUint64n(0) expected error")
+	}
+	for _, n := range []uint64{1, 3, 1 << 63, math.MaxUint64} {
+		v, err := randomUint64n(n)
+		if err != nil {
+			t.Fatalf("randomUint64n(%d) error = %v", n, err)
+		}
+		if v >= n {
+			t.Errorf("randomUint64n(%d) = %d, out of range", n, v)
+		}
+	}
+}
+
 func TestEncodeBase32(t *testing.T) {
 	tests := []struct {
 		name string
@@ -448,6 +803,18 @@ func isValidHex(s string) bool {
 	return validChars.MatchString(s)
 }
 
+// chiSquared returns the chi-squared statistic of counts against a uniform
+// distribution of total samples.
+func chiSquared(counts []int, total int) float64 {
+	expected := float64(total) / float64(len(counts))
+	var chi float64
+	for _, c := range counts {
+		d := float64(c) - expected
+		chi += d * d / expected
+	}
+	return chi
+}
+
 // Benchmarks
 
 func BenchmarkGenerateUUID(b *testing.B) {
@@ -480,6 +847,25 @@ func BenchmarkRandomBase32String(b *testing.B) {
 	}
 }
 
+func BenchmarkRandomString(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		RandomString(AlphabetBase62, 20)
+	}
+}
+
+func BenchmarkRandomInt(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		RandomInt(1, 100)
+	}
+}
+
+func BenchmarkRandomShuffle(b *testing.B) {
+	items := make([]int, 52)
+	for i := 0; i < b.N; i++ {
+		RandomShuffle(items)
+	}
+}
+
 func BenchmarkEncodeBase32(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		encodeBase32(1000000)
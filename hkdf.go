@@ -0,0 +1,141 @@
+package xgen
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// KeyDerivationHash names the hash function of an HKDF key derivation.
+type KeyDerivationHash string
+
+// Supported key derivation hashes.
+const (
+	KeyDerivationSHA256 KeyDerivationHash = "hkdf-sha256"
+	KeyDerivationSHA512 KeyDerivationHash = "hkdf-sha512"
+)
+
+// KeyPurpose labels what a key derived from a MasterKey is used for. It is
+// the HKDF info, so keys for different purposes are independent.
+type KeyPurpose string
+
+// Common key purposes. Any other non-empty label may be used, e.g.
+// "signing/partner-1" for one key per partner.
+const (
+	KeyPurposeSigning         KeyPurpose = "signing"
+	KeyPurposePasswordPepper  KeyPurpose = "password-pepper"
+	KeyPurposeTokenEncryption KeyPurpose = "token-encryption"
+)
+
+const (
+	// minMasterKeyLength is the minimum master secret length in bytes.
+	minMasterKeyLength = 32
+	// masterKeyInfoPrefix namespaces the HKDF info of MasterKey purposes.
+	masterKeyInfoPrefix = "xgen/v1/"
+	// derivedSecretLength is the length in bytes of DeriveSecret keys,
+	// the same as GenerateSecretKey.
+	derivedSecretLength = 32
+)
+
+// hashFunc returns the hash constructor of h.
+func (h KeyDerivationHash) hashFunc() (func() hash.Hash, error) {
+	switch h {
+	case KeyDerivationSHA256:
+		return sha256.New, nil
+	case KeyDerivationSHA512:
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported key derivation hash %q", h)
+	}
+}
+
+// DeriveKey derives a key of length bytes from secret with HKDF (RFC 5869).
+// The salt is optional; info labels the key so one secret can produce
+// independent keys for different uses.
+//
+// Example:
+//
+//	key, _ := DeriveKey(KeyDerivationSHA256, secret, nil, "orders/encryption", 32)
+func DeriveKey(h KeyDerivationHash, secret, salt []byte, info string, length int) ([]byte, error) {
+	newHash, err := h.hashFunc()
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("key derivation secret must not be empty")
+	}
+	if length <= 0 {
+		return nil, errors.New("derived key length must be positive")
+	}
+	return hkdf.Key(newHash, secret, salt, info, length)
+}
+
+// MasterKeyOptions configures a MasterKey.
+type MasterKeyOptions struct {
+	// Hash is the HKDF hash function. Defaults to KeyDerivationSHA256.
+	Hash KeyDerivationHash
+	// Salt is an optional non-secret HKDF salt, e.g. a per-deployment value.
+	// Keys derived with different salts are independent.
+	Salt []byte
+}
+
+// MasterKey derives purpose-bound keys from one master secret, so the master
+// secret itself is never used directly to sign, pepper or encrypt anything.
+// It is safe for concurrent use.
+type MasterKey struct {
+	newHash func() hash.Hash
+	prk     []byte
+}
+
+// NewMasterKey returns a MasterKey for secret, e.g. a value from
+// GenerateSecretKey. The secret must be at least 32 bytes long.
+//
+// Example:
+//
+//	master, _ := NewMasterKey(os.Getenv("MASTER_SECRET"), MasterKeyOptions{})
+//	signingSecret, _ := master.DeriveSecret(KeyPurposeSigning)
+//	pepper, _ := master.DeriveSecret(KeyPurposePasswordPepper)
+func NewMasterKey(secret string, opts MasterKeyOptions) (*MasterKey, error) {
+	if opts.Hash == "" {
+		opts.Hash = KeyDerivationSHA256
+	}
+	newHash, err := opts.Hash.hashFunc()
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) < minMasterKeyLength {
+		return nil, fmt.Errorf("master secret must be at least %d bytes", minMasterKeyLength)
+	}
+	prk, err := hkdf.Extract(newHash, []byte(secret), opts.Salt)
+	if err != nil {
+		return nil, err
+	}
+	return &MasterKey{newHash: newHash, prk: prk}, nil
+}
+
+// DeriveKey derives a key of length bytes for purpose. The same master
+// secret, salt and purpose always derive the same key.
+func (m *MasterKey) DeriveKey(purpose KeyPurpose, length int) ([]byte, error) {
+	if purpose == "" {
+		return nil, errors.New("key purpose must not be empty")
+	}
+	if length <= 0 {
+		return nil, errors.New("derived key length must be positive")
+	}
+	return hkdf.Expand(m.newHash, m.prk, masterKeyInfoPrefix+string(purpose), length)
+}
+
+// DeriveSecret derives a 32-byte key for purpose and returns it hex encoded,
+// in the format of GenerateSecretKey. It can be passed wherever a secret
+// string is expected, e.g. to GenerateSignature or GeneratePasswordHash.
+func (m *MasterKey) DeriveSecret(purpose KeyPurpose) (string, error) {
+	key, err := m.DeriveKey(purpose, derivedSecretLength)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
@@ -0,0 +1,88 @@
+package xgen
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveKey_RFC5869(t *testing.T) {
+	// RFC 5869, appendix A.1.
+	secret := bytes.Repeat([]byte{0x0b}, 22)
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	key, err := DeriveKey(KeyDerivationSHA256, secret, salt, string(info), 42)
+	require.NoError(t, err)
+	assert.Equal(t, "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865", hex.EncodeToString(key))
+
+	// RFC 5869, appendix A.3: no salt and no info.
+	key, err = DeriveKey(KeyDerivationSHA256, secret, nil, "", 42)
+	require.NoError(t, err)
+	assert.Equal(t, "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8", hex.EncodeToString(key))
+
+	key512, err := DeriveKey(KeyDerivationSHA512, secret, salt, string(info), 42)
+	require.NoError(t, err)
+	assert.Len(t, key512, 42)
+	assert.NotEqual(t, key, key512)
+
+	_, err = DeriveKey("hkdf-md5", secret, nil, "", 32)
+	assert.Error(t, err)
+	_, err = DeriveKey(KeyDerivationSHA256, nil, nil, "", 32)
+	assert.Error(t, err)
+	_, err = DeriveKey(KeyDerivationSHA256, secret, nil, "", 0)
+	assert.Error(t, err)
+	_, err = DeriveKey(KeyDerivationSHA256, secret, nil, "", 255*32+1)
+	assert.Error(t, err)
+}
+
+func TestMasterKey(t *testing.T) {
+	secret, err := GenerateSecretKey()
+	require.NoError(t, err)
+	master, err := NewMasterKey(secret, MasterKeyOptions{})
+	require.NoError(t, err)
+
+	signing, err := master.DeriveSecret(KeyPurposeSigning)
+	require.NoError(t, err)
+	assert.Len(t, signing, 64)
+	assert.NotEqual(t, secret, signing)
+
+	// Each purpose derives an independent key; the same purpose is deterministic.
+	seen := map[string]KeyPurpose{}
+	for _, purpose := range []KeyPurpose{KeyPurposeSigning, KeyPurposePasswordPepper, KeyPurposeTokenEncryption, "signing/partner-1"} {
+		derived, err := master.DeriveSecret(purpose)
+		require.NoError(t, err)
+		again, err := master.DeriveSecret(purpose)
+		require.NoError(t, err)
+		assert.Equal(t, derived, again)
+		assert.NotContains(t, seen, derived, purpose)
+		seen[derived] = purpose
+	}
+
+	// MasterKey is HKDF with the purpose as namespaced info.
+	key, err := master.DeriveKey(KeyPurposeTokenEncryption, 32)
+	require.NoError(t, err)
+	want, err := DeriveKey(KeyDerivationSHA256, []byte(secret), nil, "xgen/v1/token-encryption", 32)
+	require.NoError(t, err)
+	assert.Equal(t, want, key)
+
+	// The salt and hash change every derived key.
+	for _, opts := range []MasterKeyOptions{{Salt: []byte("eu-west-1")}, {Hash: KeyDerivationSHA512}} {
+		other, err := NewMasterKey(secret, opts)
+		require.NoError(t, err)
+		otherSigning, err := other.DeriveSecret(KeyPurposeSigning)
+		require.NoError(t, err)
+		assert.NotEqual(t, signing, otherSigning)
+	}
+
+	_, err = master.DeriveKey("", 32)
+	assert.Error(t, err)
+	_, err = master.DeriveKey(KeyPurposeSigning, -1)
+	assert.Error(t, err)
+	_, err = NewMasterKey("too-short", MasterKeyOptions{})
+	assert.Error(t, err)
+	_, err = NewMasterKey(secret, MasterKeyOptions{Hash: "sha1"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,789 @@
+package xgen
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RFC 9421 header names.
+const (
+	HTTPSignatureInputHeader = "Signature-Input"
+	HTTPSignatureHeader      = "Signature"
+)
+
+// defaultHTTPSignatureLabel is the signature label used when HTTPSignatureOptions.Label is empty.
+const defaultHTTPSignatureLabel = "sig"
+
+// defaultHTTPSignatureComponents are signed when HTTPSignatureOptions.Components is empty.
+var defaultHTTPSignatureComponents = []string{"@method", "@target-uri"}
+
+// HTTPSignatureOptions configures SignHTTPMessage.
+type HTTPSignatureOptions struct {
+	// Label names the signature in the Signature-Input and Signature dictionaries. Defaults to "sig".
+	Label string
+	// Components lists the covered component identifiers in order: lower-case
+	// header names ("content-type") or derived components ("@method",
+	// "@target-uri", "@authority", "@scheme", "@request-target", "@path",
+	// "@query", `@query-param;name="id"`). Defaults to "@method" and "@target-uri".
+	Components []string
+	// KeyID is sent in the keyid parameter when set.
+	KeyID string
+	// Created is the creation time. Defaults to now.
+	Created time.Time
+	// Expires is sent in the expires parameter when set.
+	Expires time.Time
+	// Nonce is sent in the nonce parameter when set.
+	Nonce string
+	// Tag is sent in the tag parameter when set.
+	Tag string
+	// IncludeAlg sends the signer's algorithm in the alg parameter.
+	IncludeAlg bool
+}
+
+// HTTPSignatureKeyResolver returns the verifier for a keyid and, when the
+// signature carries one, its alg parameter (empty otherwise).
+type HTTPSignatureKeyResolver func(ctx context.Context, keyID string, alg SignatureAlgorithm) (Verifier, error)
+
+// HTTPSignatureVerifyOptions configures VerifyHTTPMessageSignature.
+type HTTPSignatureVerifyOptions struct {
+	// Label selects the signature to verify. When empty, every signature in
+	// the message is tried in order and the first valid one is returned.
+	Label string
+	// ResolveKey returns the verifier for a signature. Required.
+	ResolveKey HTTPSignatureKeyResolver
+	// RequiredComponents must all be covered by the signature, e.g. "@method",
+	// "@target-uri" and "content-digest".
+	RequiredComponents []string
+	// MaxAge is how old the required created parameter may be, so captured
+	// signatures cannot be replayed indefinitely. Defaults to 5 minutes.
+	MaxAge time.Duration
+	// MaxFuture is how far in the future a created parameter may be, for
+	// clock skew. Defaults to 5 minutes.
+	MaxFuture time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// HTTPSignature describes a verified RFC 9421 signature.
+type HTTPSignature struct {
+	Label      string
+	KeyID      string
+	Algorithm  SignatureAlgorithm
+	Components []string
+	Created    time.Time
+	Expires    time.Time
+	Nonce      string
+	Tag        string
+}
+
+// SignHTTPMessage signs r as an RFC 9421 HTTP message signature and adds the
+// Signature-Input and Signature headers. Existing signatures are kept, so a
+// request can carry several signatures with different labels.
+//
+// The Signer's algorithm names map to the RFC 9421 registry: hmac-sha256,
+// ed25519 and ecdsa-p256-sha256 (hmac-sha512 is not registered).
+//
+// Example:
+//
+//	signer, _ := NewEd25519Signer(privateKey)
+//	err := SignHTTPMessage(req, signer, HTTPSignatureOptions{
+//		KeyID:      "partner-1",
+//		Components: []string{"@method", "@target-uri", "content-digest"},
+//	})
+func SignHTTPMessage(r *http.Request, signer Signer, opts HTTPSignatureOptions) error {
+	if signer == nil {
+		return errors.New("HTTP message signature requires a signer")
+	}
+	if opts.Label == "" {
+		opts.Label = defaultHTTPSignatureLabel
+	}
+	if !isSFKey(opts.Label) {
+		return fmt.Errorf("invalid signature label %q", opts.Label)
+	}
+	if len(opts.Components) == 0 {
+		opts.Components = defaultHTTPSignatureComponents
+	}
+	if opts.Created.IsZero() {
+		opts.Created = time.Now()
+	}
+
+	params := sfInnerList{}
+	for _, c := range opts.Components {
+		item, err := parseComponentIdentifier(c)
+		if err != nil {
+			return err
+		}
+		params.items = append(params.items, item)
+	}
+	params.params = append(params.params, sfParam{"created", opts.Created.Unix()})
+	if !opts.Expires.IsZero() {
+		params.params = append(params.params, sfParam{"expires", opts.Expires.Unix()})
+	}
+	if opts.Nonce != "" {
+		params.params = append(params.params, sfParam{"nonce", opts.Nonce})
+	}
+	if opts.IncludeAlg {
+		params.params = append(params.params, sfParam{"alg", string(signer.Algorithm())})
+	}
+	if opts.KeyID != "" {
+		params.params = append(params.params, sfParam{"keyid", opts.KeyID})
+	}
+	if opts.Tag != "" {
+		params.params = append(params.params, sfParam{"tag", opts.Tag})
+	}
+
+	base, err := buildHTTPSignatureBase(r, params)
+	if err != nil {
+		return err
+	}
+	sig, err := signer.Sign([]byte(base))
+	if err != nil {
+		return err
+	}
+	appendHeader(r.Header, HTTPSignatureInputHeader, opts.Label+"="+params.String())
+	appendHeader(r.Header, HTTPSignatureHeader, opts.Label+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+// VerifyHTTPMessageSignature verifies an RFC 9421 signature of r and returns
+// its parameters.
+//
+// The verifier returned by ResolveKey decides the algorithm; when the
+// signature also carries an alg parameter it must match. Expired signatures,
+// signatures older than MaxAge and signatures created more than MaxFuture
+// ahead of the current time fail with ErrInvalidSignatureTimestamp,
+// uncovered required components with ErrUnsignedHeader, and bad signatures
+// with ErrInvalidSignature. Requests without signature headers fail with
+// ErrMissingSignature.
+func VerifyHTTPMessageSignature(r *http.Request, opts HTTPSignatureVerifyOptions) (*HTTPSignature, error) {
+	if opts.ResolveKey == nil {
+		return nil, errors.New("HTTP message signature verification requires ResolveKey")
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultSignatureDrift
+	}
+	if opts.MaxFuture <= 0 {
+		opts.MaxFuture = DefaultSignatureDrift
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	inputHeader := strings.Join(r.Header.Values(HTTPSignatureInputHeader), ", ")
+	sigHeader := strings.Join(r.Header.Values(HTTPSignatureHeader), ", ")
+	if inputHeader == "" || sigHeader == "" {
+		return nil, ErrMissingSignature
+	}
+	inputs, err := parseSFDictionary(inputHeader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: Signature-Input: %v", ErrInvalidSignature, err)
+	}
+	sigs, err := parseSFDictionary(sigHeader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: Signature: %v", ErrInvalidSignature, err)
+	}
+
+	var firstErr error
+	for _, member := range inputs {
+		if opts.Label != "" && member.key != opts.Label {
+			continue
+		}
+		result, err := verifyHTTPSignatureMember(r, member, sigs, opts)
+		if err == nil {
+			return result, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		return nil, ErrMissingSignature
+	}
+	return nil, firstErr
+}
+
+// verifyHTTPSignatureMember verifies the signature with the given Signature-Input member.
+func verifyHTTPSignatureMember(r *http.Request, input sfMember, sigs []sfMember, opts HTTPSignatureVerifyOptions) (*HTTPSignature, error) {
+	params, ok := input.value.(sfInnerList)
+	if !ok {
+		return nil, fmt.Errorf("%w: signature %q input is not an inner list", ErrInvalidSignature, input.key)
+	}
+	var sig []byte
+	for _, m := range sigs {
+		if item, isItem := m.value.(sfItem); isItem && m.key == input.key {
+			sig, _ = item.value.([]byte)
+		}
+	}
+	if len(sig) == 0 {
+		return nil, fmt.Errorf("%w: no signature value for %q", ErrInvalidSignature, input.key)
+	}
+
+	result := &HTTPSignature{Label: input.key}
+	for _, item := range params.items {
+		result.Components = append(result.Components, item.identifier())
+	}
+	for _, p := range params.params {
+		switch p.key {
+		case "created", "expires":
+			n, ok := p.value.(int64)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s must be an integer", ErrInvalidSignature, p.key)
+			}
+			if p.key == "created" {
+				result.Created = time.Unix(n, 0)
+			} else {
+				result.Expires = time.Unix(n, 0)
+			}
+		case "keyid", "nonce", "tag", "alg":
+			s, ok := p.value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s must be a string", ErrInvalidSignature, p.key)
+			}
+			switch p.key {
+			case "keyid":
+				result.KeyID = s
+			case "nonce":
+				result.Nonce = s
+			case "tag":
+				result.Tag = s
+			case "alg":
+				result.Algorithm = SignatureAlgorithm(s)
+			}
+		}
+	}
+
+	for _, required := range opts.RequiredComponents {
+		item, err := parseComponentIdentifier(required)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(result.Components, item.identifier()) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsignedHeader, required)
+		}
+	}
+	now := opts.Now()
+	if result.Created.IsZero() {
+		return nil, fmt.Errorf("%w: missing created parameter", ErrInvalidSignatureTimestamp)
+	}
+	if !result.Expires.IsZero() && !now.Before(result.Expires) {
+		return nil, fmt.Errorf("%w: signature expired", ErrInvalidSignatureTimestamp)
+	}
+	if now.Sub(result.Created) > opts.MaxAge {
+		return nil, fmt.Errorf("%w: signature too old", ErrInvalidSignatureTimestamp)
+	}
+	if result.Created.Sub(now) > opts.MaxFuture {
+		return nil, fmt.Errorf("%w: signature created in the future", ErrInvalidSignatureTimestamp)
+	}
+
+	verifier, err := opts.ResolveKey(r.Context(), result.KeyID, result.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if verifier == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, result.KeyID)
+	}
+	if result.Algorithm != "" && result.Algorithm != verifier.Algorithm() {
+		return nil, fmt.Errorf("%w: alg %q does not match key", ErrInvalidSignature, result.Algorithm)
+	}
+	result.Algorithm = verifier.Algorithm()
+
+	base, err := buildHTTPSignatureBase(r, params)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if !verifier.Verify([]byte(base), sig) {
+		return nil, ErrInvalidSignature
+	}
+	return result, nil
+}
+
+// buildHTTPSignatureBase creates the RFC 9421 signature base for the covered
+// components and parameters in params.
+func buildHTTPSignatureBase(r *http.Request, params sfInnerList) (string, error) {
+	var b strings.Builder
+	seen := make(map[string]bool)
+	for _, item := range params.items {
+		id := item.String()
+		if seen[id] {
+			return "", fmt.Errorf("duplicate component %s", id)
+		}
+		seen[id] = true
+		value, err := httpComponentValue(r, item)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(id + ": " + value + "\n")
+	}
+	b.WriteString(`"@signature-params": ` + params.String())
+	return b.String(), nil
+}
+
+// httpComponentValue returns the value of a covered component of r.
+func httpComponentValue(r *http.Request, item sfItem) (string, error) {
+	name, _ := item.value.(string)
+	for _, p := range item.params {
+		if p.key != "name" || name != "@query-param" {
+			return "", fmt.Errorf("unsupported parameter %q on component %q", p.key, name)
+		}
+	}
+
+	switch name {
+	case "@method":
+		return r.Method, nil
+	case "@target-uri":
+		return httpScheme(r) + "://" + httpAuthority(r) + r.URL.RequestURI(), nil
+	case "@authority":
+		return httpAuthority(r), nil
+	case "@scheme":
+		return httpScheme(r), nil
+	case "@request-target":
+		return r.URL.RequestURI(), nil
+	case "@path":
+		if p := r.URL.EscapedPath(); p != "" {
+			return p, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + r.URL.RawQuery, nil
+	case "@query-param":
+		return httpQueryParam(r, item)
+	}
+	if strings.HasPrefix(name, "@") {
+		return "", fmt.Errorf("unsupported derived component %q", name)
+	}
+
+	values := r.Header.Values(name)
+	if len(values) == 0 {
+		switch {
+		case name == "host" && httpAuthority(r) != "":
+			return httpAuthority(r), nil
+		case name == "content-length" && r.ContentLength > 0:
+			return strconv.FormatInt(r.ContentLength, 10), nil
+		}
+		return "", fmt.Errorf("missing header %q", name)
+	}
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+// httpQueryParam returns the re-encoded value of a single named query parameter.
+func httpQueryParam(r *http.Request, item sfItem) (string, error) {
+	var want string
+	for _, p := range item.params {
+		want, _ = p.value.(string)
+	}
+	if want == "" {
+		return "", errors.New(`@query-param requires a name parameter`)
+	}
+	var value string
+	found := 0
+	for _, pair := range strings.Split(r.URL.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(pair, "=")
+		key, err1 := url.QueryUnescape(k)
+		val, err2 := url.QueryUnescape(v)
+		if err1 != nil || err2 != nil {
+			return "", errors.New("invalid query escape")
+		}
+		if escapeFormComponent(key) == want {
+			value = escapeFormComponent(val)
+			found++
+		}
+	}
+	switch found {
+	case 0:
+		return "", fmt.Errorf("missing query parameter %q", want)
+	case 1:
+		return value, nil
+	default:
+		return "", fmt.Errorf("query parameter %q occurs more than once", want)
+	}
+}
+
+// httpScheme returns the lower-case scheme of r.
+func httpScheme(r *http.Request) string {
+	if r.URL.Scheme != "" {
+		return strings.ToLower(r.URL.Scheme)
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// httpAuthority returns the lower-case authority of r.
+func httpAuthority(r *http.Request) string {
+	if r.Host != "" {
+		return strings.ToLower(r.Host)
+	}
+	return strings.ToLower(r.URL.Host)
+}
+
+// escapeFormComponent percent-encodes s with the application/x-www-form-urlencoded
+// percent-encode set, writing spaces as %20 (RFC 9421 section 2.2.8).
+func escapeFormComponent(s string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '*' || c == '-' || c == '.' || c == '_' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&0x0F])
+	}
+	return b.String()
+}
+
+// parseComponentIdentifier parses a component identifier such as "content-type"
+// or `@query-param;name="id"`. Header names are lower-cased.
+func parseComponentIdentifier(s string) (sfItem, error) {
+	name, rest, _ := strings.Cut(s, ";")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return sfItem{}, errors.New("empty component identifier")
+	}
+	item := sfItem{value: name}
+	if rest != "" {
+		p := &sfParser{s: ";" + rest}
+		params, err := p.parseParams()
+		if err != nil || p.pos != len(p.s) {
+			return sfItem{}, fmt.Errorf("invalid component identifier %q", s)
+		}
+		item.params = params
+	}
+	return item, nil
+}
+
+// appendHeader adds a structured-field dictionary member to a header, keeping
+// existing members in the same field line.
+func appendHeader(h http.Header, name, member string) {
+	if existing := strings.Join(h.Values(name), ", "); existing != "" {
+		member = existing + ", " + member
+	}
+	h.Set(name, member)
+}
+
+// The types below implement the subset of RFC 8941 structured fields used by
+// RFC 9421: dictionaries of inner lists, items and parameters.
+
+// sfParam is a structured-field parameter. Its value is an int64, string,
+// sfToken, bool or []byte.
+type sfParam struct {
+	key   string
+	value any
+}
+
+// sfToken is a structured-field token, serialized without quotes.
+type sfToken string
+
+// sfItem is a structured-field item with parameters.
+type sfItem struct {
+	value  any
+	params []sfParam
+}
+
+// sfInnerList is a structured-field inner list with parameters.
+type sfInnerList struct {
+	items  []sfItem
+	params []sfParam
+}
+
+// sfMember is a dictionary member whose value is an sfItem or sfInnerList.
+type sfMember struct {
+	key   string
+	value any
+}
+
+// String serializes the item.
+func (i sfItem) String() string {
+	return serializeSFBareItem(i.value) + serializeSFParams(i.params)
+}
+
+// identifier returns the component identifier of the item in the form
+// accepted by parseComponentIdentifier.
+func (i sfItem) identifier() string {
+	name, _ := i.value.(string)
+	return name + serializeSFParams(i.params)
+}
+
+// String serializes the inner list.
+func (l sfInnerList) String() string {
+	items := make([]string, len(l.items))
+	for i, item := range l.items {
+		items[i] = item.String()
+	}
+	return "(" + strings.Join(items, " ") + ")" + serializeSFParams(l.params)
+}
+
+// serializeSFParams serializes parameters, omitting the value of true booleans.
+func serializeSFParams(params []sfParam) string {
+	var b strings.Builder
+	for _, p := range params {
+		b.WriteString(";" + p.key)
+		if v, ok := p.value.(bool); ok && v {
+			continue
+		}
+		b.WriteString("=" + serializeSFBareItem(p.value))
+	}
+	return b.String()
+}
+
+// serializeSFBareItem serializes an int64, string, sfToken, bool or []byte.
+func serializeSFBareItem(v any) string {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+	case sfToken:
+		return string(v)
+	case bool:
+		if v {
+			return "?1"
+		}
+		return "?0"
+	case []byte:
+		return ":" + base64.StdEncoding.EncodeToString(v) + ":"
+	default:
+		return ""
+	}
+}
+
+// isSFKey reports whether s is a valid structured-field key.
+func isSFKey(s string) bool {
+	if s == "" || !(s[0] == '*' || 'a' <= s[0] && s[0] <= 'z') {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '_' || c == '-' || c == '.' || c == '*') {
+			return false
+		}
+	}
+	return true
+}
+
+// sfParser parses structured-field values.
+type sfParser struct {
+	s   string
+	pos int
+}
+
+// parseSFDictionary parses a structured-field dictionary.
+func parseSFDictionary(s string) ([]sfMember, error) {
+	p := &sfParser{s: s}
+	p.skipSpaces()
+	var members []sfMember
+	for p.pos < len(p.s) {
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		var value any
+		if p.peek() == '=' {
+			p.pos++
+			if p.peek() == '(' {
+				value, err = p.parseInnerList()
+			} else {
+				value, err = p.parseItem()
+			}
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			params, err := p.parseParams()
+			if err != nil {
+				return nil, err
+			}
+			value = sfItem{value: true, params: params}
+		}
+		// Later members with the same key replace earlier ones.
+		members = slices.DeleteFunc(members, func(m sfMember) bool { return m.key == key })
+		members = append(members, sfMember{key: key, value: value})
+
+		p.skipOWS()
+		if p.pos == len(p.s) {
+			break
+		}
+		if p.peek() != ',' {
+			return nil, fmt.Errorf("expected ',' at offset %d", p.pos)
+		}
+		p.pos++
+		p.skipOWS()
+		if p.pos == len(p.s) {
+			return nil, errors.New("trailing ',' in dictionary")
+		}
+	}
+	return members, nil
+}
+
+// parseInnerList parses "(item item ...)" followed by parameters.
+func (p *sfParser) parseInnerList() (sfInnerList, error) {
+	var list sfInnerList
+	p.pos++ // '('
+	for {
+		p.skipSpaces()
+		if p.peek() == ')' {
+			p.pos++
+			params, err := p.parseParams()
+			list.params = params
+			return list, err
+		}
+		item, err := p.parseItem()
+		if err != nil {
+			return list, err
+		}
+		list.items = append(list.items, item)
+		if c := p.peek(); c != ' ' && c != ')' {
+			return list, fmt.Errorf("expected ' ' or ')' at offset %d", p.pos)
+		}
+	}
+}
+
+// parseItem parses a bare item followed by parameters.
+func (p *sfParser) parseItem() (sfItem, error) {
+	value, err := p.parseBareItem()
+	if err != nil {
+		return sfItem{}, err
+	}
+	params, err := p.parseParams()
+	return sfItem{value: value, params: params}, err
+}
+
+// parseParams parses zero or more ";key[=value]" parameters.
+func (p *sfParser) parseParams() ([]sfParam, error) {
+	var params []sfParam
+	for p.peek() == ';' {
+		p.pos++
+		p.skipSpaces()
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		var value any = true
+		if p.peek() == '=' {
+			p.pos++
+			if value, err = p.parseBareItem(); err != nil {
+				return nil, err
+			}
+		}
+		params = slices.DeleteFunc(params, func(q sfParam) bool { return q.key == key })
+		params = append(params, sfParam{key: key, value: value})
+	}
+	return params, nil
+}
+
+// parseKey parses a dictionary or parameter key.
+func (p *sfParser) parseKey() (string, error) {
+	start := p.pos
+	for p.pos < len(p.s) && isSFKey("a"+p.s[p.pos:p.pos+1]) {
+		p.pos++
+	}
+	if !isSFKey(p.s[start:p.pos]) {
+		return "", fmt.Errorf("invalid key at offset %d", start)
+	}
+	return p.s[start:p.pos], nil
+}
+
+// parseBareItem parses an integer, string, token, byte sequence or boolean.
+func (p *sfParser) parseBareItem() (any, error) {
+	c := p.peek()
+	switch {
+	case c == '-' || '0' <= c && c <= '9':
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.s) && '0' <= p.s[p.pos] && p.s[p.pos] <= '9' {
+			p.pos++
+		}
+		n, err := strconv.ParseInt(p.s[start:p.pos], 10, 64)
+		if err != nil || p.pos-start > 16 || p.peek() == '.' {
+			return nil, fmt.Errorf("invalid integer at offset %d", start)
+		}
+		return n, nil
+	case c == '"':
+		var b strings.Builder
+		for p.pos++; p.pos < len(p.s); p.pos++ {
+			switch ch := p.s[p.pos]; {
+			case ch == '\\':
+				p.pos++
+				if p.pos == len(p.s) || (p.s[p.pos] != '"' && p.s[p.pos] != '\\') {
+					return nil, errors.New("invalid string escape")
+				}
+				b.WriteByte(p.s[p.pos])
+			case ch == '"':
+				p.pos++
+				return b.String(), nil
+			case ch < 0x20 || ch > 0x7e:
+				return nil, errors.New("invalid string character")
+			default:
+				b.WriteByte(ch)
+			}
+		}
+		return nil, errors.New("unterminated string")
+	case c == ':':
+		end := strings.IndexByte(p.s[p.pos+1:], ':')
+		if end < 0 {
+			return nil, errors.New("unterminated byte sequence")
+		}
+		b, err := base64.StdEncoding.DecodeString(p.s[p.pos+1 : p.pos+1+end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid byte sequence: %w", err)
+		}
+		p.pos += end + 2
+		return b, nil
+	case c == '?':
+		if p.pos+1 < len(p.s) && (p.s[p.pos+1] == '0' || p.s[p.pos+1] == '1') {
+			p.pos += 2
+			return p.s[p.pos-1] == '1', nil
+		}
+		return nil, errors.New("invalid boolean")
+	case c == '*' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z':
+		start := p.pos
+		for p.pos++; p.pos < len(p.s); p.pos++ {
+			ch := rune(p.s[p.pos])
+			if !isHeaderTokenChar(ch) && !('A' <= ch && ch <= 'Z') && ch != ':' && ch != '/' {
+				break
+			}
+		}
+		return sfToken(p.s[start:p.pos]), nil
+	default:
+		return nil, fmt.Errorf("unexpected character at offset %d", p.pos)
+	}
+}
+
+// peek returns the current byte, or 0 at the end of input.
+func (p *sfParser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+// skipSpaces skips SP characters.
+func (p *sfParser) skipSpaces() {
+	for p.peek() == ' ' {
+		p.pos++
+	}
+}
+
+// skipOWS skips spaces and tabs.
+func (p *sfParser) skipOWS() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.pos++
+	}
+}
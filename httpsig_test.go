@@ -0,0 +1,327 @@
+package xgen
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRFC9421TestRequest returns the test request of RFC 9421 appendix B.2.
+func newRFC9421TestRequest() *http.Request {
+	r := httptest.NewRequest("POST", "/foo?param=Value&Pet=dog", strings.NewReader(`{"hello": "world"}`))
+	r.Host = "example.com"
+	r.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Content-Digest", "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:")
+	r.Header.Set("Content-Length", "18")
+	return r
+}
+
+// rfc9421Now is the created time of the RFC 9421 appendix B.2 signatures.
+func rfc9421Now() time.Time { return time.Unix(1618884473, 0) }
+
+// staticHTTPSignatureKey resolves every key ID to verifier.
+func staticHTTPSignatureKey(verifier Verifier) HTTPSignatureKeyResolver {
+	return func(context.Context, string, SignatureAlgorithm) (Verifier, error) { return verifier, nil }
+}
+
+func TestSignHTTPMessage_RFC9421HMAC(t *testing.T) {
+	// RFC 9421 appendix B.2.5
+	key, _ := base64.StdEncoding.DecodeString("uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ==")
+	signer, err := NewHMACSigner(SignatureAlgorithmHMACSHA256, string(key))
+	require.NoError(t, err)
+
+	r := newRFC9421TestRequest()
+	err = SignHTTPMessage(r, signer, HTTPSignatureOptions{
+		Label:      "sig-b25",
+		Components: []string{"date", "@authority", "content-type"},
+		KeyID:      "test-shared-secret",
+		Created:    time.Unix(1618884473, 0),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `sig-b25=("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`, r.Header.Get(HTTPSignatureInputHeader))
+	assert.Equal(t, "sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:", r.Header.Get(HTTPSignatureHeader))
+
+	result, err := VerifyHTTPMessageSignature(r, HTTPSignatureVerifyOptions{ResolveKey: staticHTTPSignatureKey(signer), Now: rfc9421Now})
+	require.NoError(t, err)
+	assert.Equal(t, &HTTPSignature{
+		Label:      "sig-b25",
+		KeyID:      "test-shared-secret",
+		Algorithm:  SignatureAlgorithmHMACSHA256,
+		Components: []string{"date", "@authority", "content-type"},
+		Created:    time.Unix(1618884473, 0),
+	}, result)
+}
+
+func TestSignHTTPMessage_RFC9421Ed25519(t *testing.T) {
+	// RFC 9421 appendix B.2.6, key test-key-ed25519 from appendix B.1.4
+	seed, _ := base64.StdEncoding.DecodeString("n4Ni+HpISpVObnQMW0wOhCKROaIKqKtW/2ZYb2p9KcU=")
+	pub, _ := base64.StdEncoding.DecodeString("JrQLj5P/89iXES9+vFgrIy29clF9CC/oPPsw3c5D0bs=")
+	signer, err := NewEd25519Signer(ed25519.NewKeyFromSeed(seed))
+	require.NoError(t, err)
+	verifier, err := NewEd25519Verifier(pub)
+	require.NoError(t, err)
+
+	r := newRFC9421TestRequest()
+	params := sfInnerList{params: []sfParam{{"created", int64(1618884473)}, {"keyid", "test-key-ed25519"}}}
+	for _, c := range []string{"date", "@method", "@path", "@authority", "content-type", "content-length"} {
+		params.items = append(params.items, sfItem{value: c})
+	}
+	base, err := buildHTTPSignatureBase(r, params)
+	require.NoError(t, err)
+	assert.Equal(t, `"date": Tue, 20 Apr 2021 02:07:55 GMT`+"\n"+
+		`"@method": POST`+"\n"+
+		`"@path": /foo`+"\n"+
+		`"@authority": example.com`+"\n"+
+		`"content-type": application/json`+"\n"+
+		`"content-length": 18`+"\n"+
+		`"@signature-params": ("date" "@method" "@path" "@authority" "content-type" "content-length");created=1618884473;keyid="test-key-ed25519"`, base)
+
+	err = SignHTTPMessage(r, signer, HTTPSignatureOptions{
+		Label:      "sig-b26",
+		Components: []string{"date", "@method", "@path", "@authority", "content-type", "content-length"},
+		KeyID:      "test-key-ed25519",
+		Created:    time.Unix(1618884473, 0),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "sig-b26=:wqcAqbmYJ2ji2glfAMaRy4gruYYnx2nEFN2HN6jrnDnQCK1u02Gb04v9EDgwUPiu4A0w6vuQv5lIp5WPpBKRCw==:", r.Header.Get(HTTPSignatureHeader))
+
+	// The published headers verify with the public key alone.
+	published := newRFC9421TestRequest()
+	published.Header.Set(HTTPSignatureInputHeader, `sig-b26=("date" "@method" "@path" "@authority" "content-type" "content-length");created=1618884473;keyid="test-key-ed25519"`)
+	published.Header.Set(HTTPSignatureHeader, "sig-b26=:wqcAqbmYJ2ji2glfAMaRy4gruYYnx2nEFN2HN6jrnDnQCK1u02Gb04v9EDgwUPiu4A0w6vuQv5lIp5WPpBKRCw==:")
+	result, err := VerifyHTTPMessageSignature(published, HTTPSignatureVerifyOptions{
+		ResolveKey:         staticHTTPSignatureKey(verifier),
+		RequiredComponents: []string{"@method", "@path", "content-length"},
+		Now:                rfc9421Now,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "test-key-ed25519", result.KeyID)
+	assert.Equal(t, SignatureAlgorithmEd25519, result.Algorithm)
+
+	published.Header.Set("Content-Length", "19")
+	_, err = VerifyHTTPMessageSignature(published, HTTPSignatureVerifyOptions{ResolveKey: staticHTTPSignatureKey(verifier), Now: rfc9421Now})
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestHTTPComponentValue(t *testing.T) {
+	// RFC 9421 sections 2.2.1 to 2.2.8
+	r := httptest.NewRequest("POST", "https://www.example.com/path?param=value&foo=bar&baz=batman&qux=", nil)
+	r.Header.Add("X-Multi", " a ")
+	r.Header.Add("X-Multi", "b")
+
+	tests := []struct {
+		component, want string
+	}{
+		{"@method", "POST"},
+		{"@target-uri", "https://www.example.com/path?param=value&foo=bar&baz=batman&qux="},
+		{"@authority", "www.example.com"},
+		{"@scheme", "https"},
+		{"@request-target", "/path?param=value&foo=bar&baz=batman&qux="},
+		{"@path", "/path"},
+		{"@query", "?param=value&foo=bar&baz=batman&qux="},
+		{`@query-param;name="baz"`, "batman"},
+		{`@query-param;name="qux"`, ""},
+		{"X-Multi", "a, b"},
+		{"host", "www.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.component, func(t *testing.T) {
+			item, err := parseComponentIdentifier(tt.component)
+			require.NoError(t, err)
+			got, err := httpComponentValue(r, item)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// Query parameter names and values are re-encoded.
+	r = httptest.NewRequest("GET", "/?var=this%20is%20a%20big%0Avalue&bar=with+plus+whitespace&fa%C3%A7ade%22%3A%20=something", nil)
+	for name, want := range map[string]string{
+		"var":                  "this%20is%20a%20big%0Avalue",
+		"bar":                  "with%20plus%20whitespace",
+		"fa%C3%A7ade%22%3A%20": "something",
+	} {
+		got, err := httpComponentValue(r, sfItem{value: "@query-param", params: []sfParam{{"name", name}}})
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	r = httptest.NewRequest("GET", "/?a=1&a=2", nil)
+	for _, c := range []string{`@query-param;name="a"`, `@query-param;name="b"`, "@status", "x-absent", "content-type;sf"} {
+		item, err := parseComponentIdentifier(c)
+		require.NoError(t, err)
+		_, err = httpComponentValue(r, item)
+		assert.Error(t, err, c)
+	}
+}
+
+func TestVerifyHTTPMessageSignature(t *testing.T) {
+	for _, signer := range newTestSigners(t) {
+		t.Run(string(signer.Algorithm()), func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/orders?id=7", nil)
+			r.Header.Set("Content-Type", "application/json")
+			require.NoError(t, SignHTTPMessage(r, signer, HTTPSignatureOptions{
+				KeyID:      "k1",
+				Components: []string{"@method", "@target-uri", "Content-Type"},
+				Nonce:      "n-1",
+				Tag:        "app",
+				IncludeAlg: true,
+				Expires:    time.Now().Add(time.Minute),
+			}))
+			result, err := VerifyHTTPMessageSignature(r, HTTPSignatureVerifyOptions{
+				ResolveKey:         staticHTTPSignatureKey(signer),
+				RequiredComponents: []string{"@target-uri"},
+				MaxAge:             time.Minute,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "sig", result.Label)
+			assert.Equal(t, "n-1", result.Nonce)
+			assert.Equal(t, "app", result.Tag)
+			assert.Equal(t, []string{"@method", "@target-uri", "content-type"}, result.Components)
+
+			tampered := r.Clone(r.Context())
+			tampered.URL.RawQuery = "id=8"
+			_, err = VerifyHTTPMessageSignature(tampered, HTTPSignatureVerifyOptions{ResolveKey: staticHTTPSignatureKey(signer)})
+			assert.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestVerifyHTTPMessageSignature_Errors(t *testing.T) {
+	signer, err := NewHMACSigner(SignatureAlgorithmHMACSHA256, "secret")
+	require.NoError(t, err)
+	other, err := NewHMACSigner(SignatureAlgorithmHMACSHA512, "secret")
+	require.NoError(t, err)
+	now := time.Unix(1704067200, 0)
+	verify := func(r *http.Request, opts HTTPSignatureVerifyOptions) error {
+		if opts.ResolveKey == nil {
+			opts.ResolveKey = staticHTTPSignatureKey(signer)
+		}
+		opts.Now = func() time.Time { return now }
+		_, err := VerifyHTTPMessageSignature(r, opts)
+		return err
+	}
+	sign := func(opts HTTPSignatureOptions) *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+		if opts.Created.IsZero() {
+			opts.Created = now
+		}
+		require.NoError(t, SignHTTPMessage(r, signer, opts))
+		return r
+	}
+
+	assert.ErrorIs(t, verify(httptest.NewRequest("GET", "/", nil), HTTPSignatureVerifyOptions{}), ErrMissingSignature)
+	assert.ErrorIs(t, verify(sign(HTTPSignatureOptions{}), HTTPSignatureVerifyOptions{Label: "other"}), ErrMissingSignature)
+	assert.ErrorIs(t, verify(sign(HTTPSignatureOptions{}), HTTPSignatureVerifyOptions{RequiredComponents: []string{"content-digest"}}), ErrUnsignedHeader)
+	assert.ErrorIs(t, verify(sign(HTTPSignatureOptions{Expires: now.Add(-time.Second)}), HTTPSignatureVerifyOptions{}), ErrInvalidSignatureTimestamp)
+	assert.ErrorIs(t, verify(sign(HTTPSignatureOptions{Created: now.Add(-time.Hour)}), HTTPSignatureVerifyOptions{MaxAge: time.Minute}), ErrInvalidSignatureTimestamp)
+
+	// Without a MaxAge, signatures older than 5 minutes are rejected, so a
+	// captured signature cannot be replayed indefinitely.
+	assert.NoError(t, verify(sign(HTTPSignatureOptions{Created: now.Add(-4 * time.Minute)}), HTTPSignatureVerifyOptions{}))
+	assert.ErrorIs(t, verify(sign(HTTPSignatureOptions{Created: now.Add(-6 * time.Minute)}), HTTPSignatureVerifyOptions{}), ErrInvalidSignatureTimestamp)
+	assert.NoError(t, verify(sign(HTTPSignatureOptions{Created: now.Add(-time.Hour)}), HTTPSignatureVerifyOptions{MaxAge: 2 * time.Hour}))
+
+	// A validly signed signature without a created parameter is rejected.
+	r := httptest.NewRequest("GET", "/", nil)
+	params := sfInnerList{items: []sfItem{{value: "@method"}}, params: []sfParam{{"expires", now.Add(time.Minute).Unix()}}}
+	base, err := buildHTTPSignatureBase(r, params)
+	require.NoError(t, err)
+	sig, err := signer.Sign([]byte(base))
+	require.NoError(t, err)
+	r.Header.Set(HTTPSignatureInputHeader, "sig="+params.String())
+	r.Header.Set(HTTPSignatureHeader, "sig=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	assert.ErrorIs(t, verify(r, HTTPSignatureVerifyOptions{}), ErrInvalidSignatureTimestamp)
+
+	// Signatures created in the future are rejected beyond the allowed skew,
+	// even with a MaxAge.
+	future := sign(HTTPSignatureOptions{Created: now.Add(time.Hour)})
+	assert.ErrorIs(t, verify(future, HTTPSignatureVerifyOptions{}), ErrInvalidSignatureTimestamp)
+	assert.ErrorIs(t, verify(future, HTTPSignatureVerifyOptions{MaxAge: time.Minute}), ErrInvalidSignatureTimestamp)
+	assert.NoError(t, verify(sign(HTTPSignatureOptions{Created: now.Add(30 * time.Second)}), HTTPSignatureVerifyOptions{MaxAge: time.Minute}))
+	assert.ErrorIs(t, verify(sign(HTTPSignatureOptions{Created: now.Add(30 * time.Second)}), HTTPSignatureVerifyOptions{MaxFuture: 10 * time.Second}), ErrInvalidSignatureTimestamp)
+	// Expiry applies even when the signature is otherwise fresh.
+	assert.ErrorIs(t, verify(sign(HTTPSignatureOptions{Expires: now.Add(-time.Second)}), HTTPSignatureVerifyOptions{MaxAge: time.Minute}), ErrInvalidSignatureTimestamp)
+
+	// An alg parameter must match the resolved key.
+	r = sign(HTTPSignatureOptions{IncludeAlg: true})
+	assert.ErrorIs(t, verify(r, HTTPSignatureVerifyOptions{ResolveKey: staticHTTPSignatureKey(other)}), ErrInvalidSignature)
+
+	// Resolver errors are returned as is.
+	keys, err := NewKeyring(SigningKey{ID: "k1", Secret: "secret"})
+	require.NoError(t, err)
+	r = sign(HTTPSignatureOptions{KeyID: "k2"})
+	assert.ErrorIs(t, verify(r, HTTPSignatureVerifyOptions{ResolveKey: keys.ResolveHTTPSignatureKey}), ErrUnknownKey)
+	r = sign(HTTPSignatureOptions{KeyID: "k1"})
+	assert.NoError(t, verify(r, HTTPSignatureVerifyOptions{ResolveKey: keys.ResolveHTTPSignatureKey}))
+
+	for _, headers := range [][2]string{
+		{`sig=("@method"`, "sig=:AA==:"},
+		{`sig=("@method")`, "sig=:not base64:"},
+		{`sig=("@method")`, "other=:AA==:"},
+		{`sig=("@method");created="x"`, "sig=:AA==:"},
+		{`sig=("@method" "@method");created=1704067200`, "sig=:AA==:"},
+		{`sig="@method"`, "sig=:AA==:"},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set(HTTPSignatureInputHeader, headers[0])
+		r.Header.Set(HTTPSignatureHeader, headers[1])
+		assert.ErrorIs(t, verify(r, HTTPSignatureVerifyOptions{}), ErrInvalidSignature, headers[0])
+	}
+
+	_, err = VerifyHTTPMessageSignature(r, HTTPSignatureVerifyOptions{})
+	assert.Error(t, err)
+	assert.Error(t, SignHTTPMessage(r, nil, HTTPSignatureOptions{}))
+	assert.Error(t, SignHTTPMessage(r, signer, HTTPSignatureOptions{Label: "Bad"}))
+	assert.Error(t, SignHTTPMessage(r, signer, HTTPSignatureOptions{Components: []string{"x-absent"}}))
+}
+
+func TestSignHTTPMessage_MultipleSignatures(t *testing.T) {
+	hmacSigner, err := NewHMACSigner(SignatureAlgorithmHMACSHA256, "secret")
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecSigner, err := NewECDSAP256Signer(ecKey)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	require.NoError(t, SignHTTPMessage(r, hmacSigner, HTTPSignatureOptions{Label: "a"}))
+	require.NoError(t, SignHTTPMessage(r, ecSigner, HTTPSignatureOptions{Label: "b", KeyID: "proxy"}))
+
+	// Each label verifies with its own key.
+	result, err := VerifyHTTPMessageSignature(r, HTTPSignatureVerifyOptions{ResolveKey: staticHTTPSignatureKey(ecSigner)})
+	require.NoError(t, err)
+	assert.Equal(t, "b", result.Label)
+	_, err = VerifyHTTPMessageSignature(r, HTTPSignatureVerifyOptions{Label: "b", ResolveKey: staticHTTPSignatureKey(hmacSigner)})
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	result, err = VerifyHTTPMessageSignature(r, HTTPSignatureVerifyOptions{Label: "a", ResolveKey: staticHTTPSignatureKey(hmacSigner)})
+	require.NoError(t, err)
+	assert.Equal(t, "a", result.Label)
+}
+
+func TestParseSFDictionary(t *testing.T) {
+	members, err := parseSFDictionary(`a=("x" "y";name="q\"\\");created=1;alg=tok/en, b=:AQI=:, c, d=?0;p`)
+	require.NoError(t, err)
+	require.Len(t, members, 4)
+	assert.Equal(t, `("x" "y";name="q\"\\");created=1;alg=tok/en`, members[0].value.(sfInnerList).String())
+	assert.Equal(t, []byte{1, 2}, members[1].value.(sfItem).value)
+	assert.Equal(t, true, members[2].value.(sfItem).value)
+	assert.Equal(t, "?0;p", members[3].value.(sfItem).String())
+
+	for _, bad := range []string{"A=1", "a=1,", "a=1 b=2", `a="unterminated`, "a=(1 2", "a=1.5", "a=:AQI", "a=?2", "a=@"} {
+		_, err := parseSFDictionary(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,437 @@
+package xgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxCanonicalJSONDepth bounds the nesting of documents passed to CanonicalizeJSON.
+const maxCanonicalJSONDepth = 1000
+
+// ErrInvalidJSON is returned when a document cannot be canonicalized: it is
+// not valid JSON, or it is outside I-JSON (RFC 7493) because it has
+// duplicate object keys, invalid Unicode or numbers that overflow a double.
+var ErrInvalidJSON = errors.New("invalid JSON for canonicalization")
+
+// CanonicalizeJSON returns the RFC 8785 JSON Canonicalization Scheme (JCS)
+// form of a JSON document: no whitespace, object members sorted by the UTF-16
+// code units of their names, numbers in ECMAScript shortest form and strings
+// with minimal escaping. Documents that differ only in formatting, key order
+// or number and string encoding have the same canonical form.
+//
+// Example:
+//
+//	canonical, err := CanonicalizeJSON([]byte(`{ "b": 1.50, "a": "A" }`))
+//	// {"a":"A","b":1.5}
+func CanonicalizeJSON(data []byte) ([]byte, error) {
+	p := &jcsParser{data: data}
+	p.skipSpace()
+	var out bytes.Buffer
+	if err := p.value(&out, 0); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.data) {
+		return nil, p.errorf("unexpected data after document")
+	}
+	return out.Bytes(), nil
+}
+
+// GenerateSignatureJSON canonicalizes a JSON body with CanonicalizeJSON and
+// signs it with GenerateSignature, so the signature survives proxies that
+// re-serialize the body. An empty body is signed as is.
+func GenerateSignatureJSON(secret, method, path, timestamp, rawBody string) (string, error) {
+	body, err := canonicalJSONBody(rawBody)
+	if err != nil {
+		return "", err
+	}
+	return GenerateSignature(secret, method, path, timestamp, body)
+}
+
+// VerifySignatureJSON checks a signature created by GenerateSignatureJSON.
+// Uses constant-time comparison to prevent timing attacks.
+func VerifySignatureJSON(secret, method, path, timestamp, rawBody, receivedSig string) bool {
+	body, err := canonicalJSONBody(rawBody)
+	if err != nil {
+		return false
+	}
+	return VerifySignature(secret, method, path, timestamp, body, receivedSig)
+}
+
+// canonicalJSONBody returns the canonical form of a non-empty JSON body.
+func canonicalJSONBody(rawBody string) (string, error) {
+	if rawBody == "" {
+		return "", nil
+	}
+	canonical, err := CanonicalizeJSON([]byte(rawBody))
+	if err != nil {
+		return "", err
+	}
+	return string(canonical), nil
+}
+
+// isJSONContentType reports whether a Content-Type header names JSON:
+// application/json or a "+json" structured syntax suffix.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// jcsParser parses a JSON document and writes its canonical form.
+type jcsParser struct {
+	data []byte
+	pos  int
+}
+
+// jcsMember is a parsed object member.
+type jcsMember struct {
+	name  string
+	key   []uint16
+	value []byte
+}
+
+// errorf returns an ErrInvalidJSON error at the current offset.
+func (p *jcsParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at offset %d", ErrInvalidJSON, fmt.Sprintf(format, args...), p.pos)
+}
+
+// skipSpace skips JSON whitespace.
+func (p *jcsParser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// value parses one JSON value and writes its canonical form to out.
+func (p *jcsParser) value(out *bytes.Buffer, depth int) error {
+	if p.pos >= len(p.data) {
+		return p.errorf("unexpected end of input")
+	}
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		return p.object(out, depth+1)
+	case c == '[':
+		return p.array(out, depth+1)
+	case c == '"':
+		s, err := p.string()
+		if err != nil {
+			return err
+		}
+		writeCanonicalJSONString(out, s)
+		return nil
+	case c == '-' || '0' <= c && c <= '9':
+		return p.number(out)
+	default:
+		for _, literal := range []string{"true", "false", "null"} {
+			if bytes.HasPrefix(p.data[p.pos:], []byte(literal)) {
+				p.pos += len(literal)
+				out.WriteString(literal)
+				return nil
+			}
+		}
+		return p.errorf("unexpected character %q", c)
+	}
+}
+
+// object parses an object and writes its members sorted by UTF-16 code units.
+func (p *jcsParser) object(out *bytes.Buffer, depth int) error {
+	if depth > maxCanonicalJSONDepth {
+		return p.errorf("document nested too deeply")
+	}
+	p.pos++ // '{'
+	var members []jcsMember
+	p.skipSpace()
+	if p.pos < len(p.data) && p.data[p.pos] == '}' {
+		p.pos++
+		out.WriteString("{}")
+		return nil
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) || p.data[p.pos] != '"' {
+			return p.errorf("expected object key")
+		}
+		name, err := p.string()
+		if err != nil {
+			return err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+			return p.errorf("expected ':'")
+		}
+		p.pos++
+		p.skipSpace()
+		var value bytes.Buffer
+		if err := p.value(&value, depth); err != nil {
+			return err
+		}
+		members = append(members, jcsMember{name: name, key: utf16.Encode([]rune(name)), value: value.Bytes()})
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return p.errorf("unexpected end of input")
+		}
+		if p.data[p.pos] == '}' {
+			p.pos++
+			break
+		}
+		if p.data[p.pos] != ',' {
+			return p.errorf("expected ',' or '}'")
+		}
+		p.pos++
+	}
+
+	slices.SortFunc(members, func(a, b jcsMember) int { return slices.Compare(a.key, b.key) })
+	out.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			if m.name == members[i-1].name {
+				return fmt.Errorf("%w: duplicate object key %q", ErrInvalidJSON, m.name)
+			}
+			out.WriteByte(',')
+		}
+		writeCanonicalJSONString(out, m.name)
+		out.WriteByte(':')
+		out.Write(m.value)
+	}
+	out.WriteByte('}')
+	return nil
+}
+
+// array parses an array and writes its canonical form.
+func (p *jcsParser) array(out *bytes.Buffer, depth int) error {
+	if depth > maxCanonicalJSONDepth {
+		return p.errorf("document nested too deeply")
+	}
+	p.pos++ // '['
+	out.WriteByte('[')
+	p.skipSpace()
+	if p.pos < len(p.data) && p.data[p.pos] == ']' {
+		p.pos++
+		out.WriteByte(']')
+		return nil
+	}
+	for first := true; ; first = false {
+		if !first {
+			out.WriteByte(',')
+		}
+		p.skipSpace()
+		if err := p.value(out, depth); err != nil {
+			return err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return p.errorf("unexpected end of input")
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			out.WriteByte(']')
+			return nil
+		}
+		if p.data[p.pos] != ',' {
+			return p.errorf("expected ',' or ']'")
+		}
+		p.pos++
+	}
+}
+
+// string parses a string literal and returns its value. Invalid UTF-8 and
+// unpaired surrogate escapes are rejected.
+func (p *jcsParser) string() (string, error) {
+	p.pos++ // '"'
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.data) {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.data[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		case c == '\\':
+			r, err := p.escape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		case c < 0x20:
+			return "", p.errorf("control character in string")
+		case c < utf8.RuneSelf:
+			b.WriteByte(c)
+			p.pos++
+		default:
+			r, size := utf8.DecodeRune(p.data[p.pos:])
+			if r == utf8.RuneError && size <= 1 {
+				return "", p.errorf("invalid UTF-8")
+			}
+			b.WriteRune(r)
+			p.pos += size
+		}
+	}
+}
+
+// escape parses an escape sequence, combining surrogate pairs.
+func (p *jcsParser) escape() (rune, error) {
+	if p.pos+1 >= len(p.data) {
+		return 0, p.errorf("unterminated escape")
+	}
+	c := p.data[p.pos+1]
+	p.pos += 2
+	switch c {
+	case '"', '\\', '/':
+		return rune(c), nil
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'u':
+		r, err := p.hex4()
+		if err != nil {
+			return 0, err
+		}
+		if !utf16.IsSurrogate(r) {
+			return r, nil
+		}
+		if r < 0xDC00 && p.pos+1 < len(p.data) && p.data[p.pos] == '\\' && p.data[p.pos+1] == 'u' {
+			p.pos += 2
+			low, err := p.hex4()
+			if err != nil {
+				return 0, err
+			}
+			if combined := utf16.DecodeRune(r, low); combined != utf8.RuneError {
+				return combined, nil
+			}
+		}
+		return 0, p.errorf("unpaired surrogate")
+	}
+	return 0, p.errorf("invalid escape %q", c)
+}
+
+// hex4 parses the four hex digits of a \u escape.
+func (p *jcsParser) hex4() (rune, error) {
+	if p.pos+4 > len(p.data) {
+		return 0, p.errorf("invalid \\u escape")
+	}
+	n, err := strconv.ParseUint(string(p.data[p.pos:p.pos+4]), 16, 16)
+	if err != nil {
+		return 0, p.errorf("invalid \\u escape")
+	}
+	p.pos += 4
+	return rune(n), nil
+}
+
+// number parses a number and writes it in ECMAScript form.
+func (p *jcsParser) number(out *bytes.Buffer) error {
+	start := p.pos
+	if p.data[p.pos] == '-' {
+		p.pos++
+	}
+	switch {
+	case p.pos < len(p.data) && p.data[p.pos] == '0':
+		p.pos++
+	case p.digits() == 0:
+		return p.errorf("invalid number")
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == '.' {
+		p.pos++
+		if p.digits() == 0 {
+			return p.errorf("invalid number")
+		}
+	}
+	if p.pos < len(p.data) && (p.data[p.pos] == 'e' || p.data[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.data) && (p.data[p.pos] == '+' || p.data[p.pos] == '-') {
+			p.pos++
+		}
+		if p.digits() == 0 {
+			return p.errorf("invalid number")
+		}
+	}
+	f, err := strconv.ParseFloat(string(p.data[start:p.pos]), 64)
+	if err != nil && math.IsInf(f, 0) {
+		return p.errorf("number out of range")
+	}
+	out.WriteString(formatCanonicalJSONNumber(f))
+	return nil
+}
+
+// digits skips decimal digits and returns how many there were.
+func (p *jcsParser) digits() int {
+	start := p.pos
+	for p.pos < len(p.data) && '0' <= p.data[p.pos] && p.data[p.pos] <= '9' {
+		p.pos++
+	}
+	return p.pos - start
+}
+
+// formatCanonicalJSONNumber formats a finite double like ECMAScript's
+// Number.prototype.toString: the shortest round-tripping digits, in fixed
+// notation between 1e-6 and 1e21 and exponent notation otherwise.
+func formatCanonicalJSONNumber(f float64) string {
+	if f == 0 {
+		return "0" // also -0
+	}
+	abs := math.Abs(f)
+	if abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	// Go writes at least two exponent digits ("1e-07"); ECMAScript does not.
+	mantissa, exp, _ := strings.Cut(s, "e")
+	sign, digits := exp[:1], strings.TrimLeft(exp[1:], "0")
+	return mantissa + "e" + sign + digits
+}
+
+// writeCanonicalJSONString writes s as a JSON string, escaping only '"', '\\'
+// and control characters, with the short escapes where JSON has one.
+func writeCanonicalJSONString(out *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	out.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\b':
+			out.WriteString(`\b`)
+		case '\f':
+			out.WriteString(`\f`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			if c < 0x20 {
+				out.WriteString(`\u00`)
+				out.WriteByte(hex[c>>4])
+				out.WriteByte(hex[c&0xF])
+			} else {
+				out.WriteByte(c)
+			}
+		}
+	}
+	out.WriteByte('"')
+}
@@ -0,0 +1,229 @@
+package xgen
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalizeJSON_RFC8785(t *testing.T) {
+	// RFC 8785, section 3.2.2.
+	input := `{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`
+	got, err := CanonicalizeJSON([]byte(input))
+	require.NoError(t, err)
+	assert.Equal(t, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`, string(got))
+
+	// RFC 8785, section 3.2.3: members sorted by UTF-16 code units.
+	input = `{
+  "\u20ac": "Euro Sign",
+  "\r": "Carriage Return",
+  "\ufb33": "Hebrew Letter Dalet With Dagesh",
+  "1": "One",
+  "\ud83d\ude00": "Emoji: Grinning Face",
+  "\u0080": "Control",
+  "\u00f6": "Latin Small Letter O With Diaeresis"
+}`
+	got, err = CanonicalizeJSON([]byte(input))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"ö\":\"Latin Small Letter O With Diaeresis\","+
+		"\"€\":\"Euro Sign\",\"😀\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}", string(got))
+}
+
+func TestFormatCanonicalJSONNumber(t *testing.T) {
+	// RFC 8785, appendix B.
+	tests := []struct {
+		bits uint64
+		want string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	}
+	for _, tt := range tests {
+		f := math.Float64frombits(tt.bits)
+		assert.Equal(t, tt.want, formatCanonicalJSONNumber(f), "%016x", tt.bits)
+
+		// The same value parsed from JSON canonicalizes identically.
+		got, err := CanonicalizeJSON([]byte(strconv.FormatFloat(f, 'g', -1, 64)))
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, string(got))
+	}
+}
+
+func TestCanonicalizeJSON(t *testing.T) {
+	for input, want := range map[string]string{
+		` { "b" : [ 1 , 2.0 , -0 ] , "a" : { } } `: `{"a":{},"b":[1,2,0]}`,
+		`"\u00e9\/\u001f\u007f"`:                   "\"é/\\u001f\u007f\"",
+		`[]`:                                       `[]`,
+		`-1.5E+2`:                                  `-150`,
+		`{"a":{"c":1,"b":2}}`:                      `{"a":{"b":2,"c":1}}`,
+		"\"\U0001F600\"":                           "\"\U0001F600\"",
+		`1e-400`:                                   `0`,
+		`0.10000000000000001`:                      `0.1`,
+		`9007199254740993`:                         `9007199254740992`,
+	} {
+		got, err := CanonicalizeJSON([]byte(input))
+		require.NoError(t, err, input)
+		assert.Equal(t, want, string(got), input)
+	}
+
+	for _, input := range []string{
+		``,
+		`{`,
+		`{"a":1,}`,
+		`[1,]`,
+		`{"a":1,"a":2}`,
+		`{"a" 1}`,
+		`01`,
+		`1.`,
+		`-`,
+		`1e`,
+		`+1`,
+		`1e400`,
+		`NaN`,
+		`tru`,
+		`"\ud800"`,
+		`"\udc00\ud800"`,
+		`"\ud800\u0041"`,
+		"\"\xff\"",
+		"\"\x01\"",
+		`"\x"`,
+		`"\u12"`,
+		`"abc`,
+		`{} {}`,
+		strings.Repeat("[", maxCanonicalJSONDepth+1) + strings.Repeat("]", maxCanonicalJSONDepth+1),
+	} {
+		_, err := CanonicalizeJSON([]byte(input))
+		assert.ErrorIs(t, err, ErrInvalidJSON, input)
+	}
+}
+
+func TestGenerateSignatureJSON(t *testing.T) {
+	sig, err := GenerateSignatureJSON("secret", "POST", "/api", "1700000000", `{"b":2,"a":1}`)
+	require.NoError(t, err)
+	plain, err := GenerateSignature("secret", "POST", "/api", "1700000000", `{"a":1,"b":2}`)
+	require.NoError(t, err)
+	assert.Equal(t, plain, sig)
+
+	assert.True(t, VerifySignatureJSON("secret", "POST", "/api", "1700000000", "{\n  \"a\": 1.0,\n  \"b\": 2\n}", sig))
+	assert.False(t, VerifySignatureJSON("secret", "POST", "/api", "1700000000", `{"a":1,"b":3}`, sig))
+	assert.False(t, VerifySignatureJSON("secret", "POST", "/api", "1700000000", `{"a":1,"b":2`, sig))
+
+	empty, err := GenerateSignatureJSON("secret", "GET", "/api", "1700000000", "")
+	require.NoError(t, err)
+	assert.True(t, VerifySignature("secret", "GET", "/api", "1700000000", "", empty))
+
+	_, err = GenerateSignatureJSON("secret", "POST", "/api", "1700000000", "not json")
+	assert.ErrorIs(t, err, ErrInvalidJSON)
+}
+
+func TestCanonicalJSON_MiddlewareTransport(t *testing.T) {
+	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret:  MapSecretLookup(map[string]string{"client-1": "secret-1"}),
+		CanonicalJSON: true,
+	})
+	require.NoError(t, err)
+
+	// A proxy that re-serializes JSON bodies between client and server.
+	server := httptest.NewServer(mw(echoHandler))
+	defer server.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out, _ := http.NewRequest(r.Method, server.URL+r.URL.Path, strings.NewReader(`{ "amount": 10.0, "id": "A-1" }`))
+		out.Header = r.Header.Clone()
+		resp, err := http.DefaultClient.Do(out)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+	}))
+	defer proxy.Close()
+
+	transport, err := NewSignatureTransport(SignatureTransportOptions{KeyID: "client-1", Secret: "secret-1", CanonicalJSON: true})
+	require.NoError(t, err)
+	client := &http.Client{Transport: transport}
+	resp, err := client.Post(proxy.URL+"/pay", "application/json; charset=utf-8", strings.NewReader(`{"id":"A-1","amount":10}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Non-JSON content types are signed as raw bytes.
+	resp, err = client.Post(proxy.URL+"/pay", "text/plain", strings.NewReader(`{"id":"A-1","amount":10}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Invalid JSON bodies are rejected with 400.
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	sig, err := GenerateSignature("secret-1", "POST", "/pay", ts, "{")
+	require.NoError(t, err)
+	req := httptest.NewRequest("POST", "/pay", strings.NewReader("{"))
+	req.Header.Set("Content-Type", "application/problem+json")
+	req.Header.Set(DefaultSignatureHeader, sig)
+	req.Header.Set(DefaultSignatureTimestampHeader, ts)
+	req.Header.Set(DefaultSignatureKeyIDHeader, "client-1")
+	rec := httptest.NewRecorder()
+	mw(echoHandler).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Handlers receive the canonical body that was verified.
+	send := func(signed, sent string) *httptest.ResponseRecorder {
+		sig, err := GenerateSignatureJSON("secret-1", "POST", "/pay", ts, signed)
+		require.NoError(t, err)
+		req := httptest.NewRequest("POST", "/pay", strings.NewReader(sent))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(DefaultSignatureHeader, sig)
+		req.Header.Set(DefaultSignatureTimestampHeader, ts)
+		req.Header.Set(DefaultSignatureKeyIDHeader, "client-1")
+		rec := httptest.NewRecorder()
+		mw(echoHandler).ServeHTTP(rec, req)
+		return rec
+	}
+	rec = send(`{"id":"A-1","amount":10}`, `{ "amount": 10.0, "id": "A-1" }`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `client-1:{"amount":10,"id":"A-1"}`, rec.Body.String())
+
+	// Numbers that round to the same double share a signature, but the
+	// handler sees the value that was signed.
+	rec = send(`{"amount":9007199254740992}`, `{"amount":9007199254740993}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `client-1:{"amount":9007199254740992}`, rec.Body.String())
+
+	_, err = NewSignatureMiddleware(SignatureMiddlewareOptions{LookupSecret: StaticSecretLookup("s"), CanonicalJSON: true, ContentDigest: true})
+	assert.Error(t, err)
+	_, err = NewSignatureTransport(SignatureTransportOptions{Secret: "s", CanonicalJSON: true, ContentDigest: DigestAlgorithmSHA256})
+	assert.Error(t, err)
+}
@@ -0,0 +1,375 @@
+package xgen
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JWTAlgorithm names a JWS "alg" header value.
+type JWTAlgorithm string
+
+// Supported JWT algorithms. "none" is never accepted.
+const (
+	JWTAlgorithmHS256 JWTAlgorithm = "HS256"
+	JWTAlgorithmHS512 JWTAlgorithm = "HS512"
+	JWTAlgorithmEdDSA JWTAlgorithm = "EdDSA"
+)
+
+// jwtAlgorithms maps signature algorithms to their JWS names.
+var jwtAlgorithms = map[SignatureAlgorithm]JWTAlgorithm{
+	SignatureAlgorithmHMACSHA256: JWTAlgorithmHS256,
+	SignatureAlgorithmHMACSHA512: JWTAlgorithmHS512,
+	SignatureAlgorithmEd25519:    JWTAlgorithmEdDSA,
+}
+
+// jwtRegisteredClaims are the claim names held in JWTClaims fields.
+var jwtRegisteredClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti"}
+
+// Errors returned by VerifyJWT in addition to ErrTokenMalformed,
+// ErrTokenInvalid and ErrTokenExpired.
+var (
+	// ErrJWTAlgorithmNotAllowed is returned when the "alg" header is not in
+	// the allow-list, including "none".
+	ErrJWTAlgorithmNotAllowed = errors.New("JWT algorithm not allowed")
+	// ErrTokenNotYetValid is returned when the "nbf" or "iat" claim is in the future.
+	ErrTokenNotYetValid = errors.New("token not yet valid")
+	// ErrTokenIssuerMismatch is returned when the "iss" claim is not the expected issuer.
+	ErrTokenIssuerMismatch = errors.New("token issuer mismatch")
+	// ErrTokenAudienceMismatch is returned when the "aud" claim does not
+	// contain the expected audience.
+	ErrTokenAudienceMismatch = errors.New("token audience mismatch")
+)
+
+// JWTClaims are the claims of a JWT. Zero fields are omitted.
+type JWTClaims struct {
+	// Issuer is the "iss" claim.
+	Issuer string
+	// Subject is the "sub" claim.
+	Subject string
+	// Audience is the "aud" claim. One value is encoded as a string.
+	Audience []string
+	// ExpiresAt is the "exp" claim.
+	ExpiresAt time.Time
+	// NotBefore is the "nbf" claim.
+	NotBefore time.Time
+	// IssuedAt is the "iat" claim.
+	IssuedAt time.Time
+	// ID is the "jti" claim.
+	ID string
+	// Extra holds private claims. Registered claim names are ignored when signing.
+	Extra map[string]any
+}
+
+// jwtHeader is the JOSE header of a JWT.
+type jwtHeader struct {
+	Algorithm JWTAlgorithm `json:"alg"`
+	KeyID     string       `json:"kid,omitempty"`
+	Type      string       `json:"typ,omitempty"`
+	Critical  []string     `json:"crit,omitempty"`
+}
+
+// SignJWTOptions configures SignJWT.
+type SignJWTOptions struct {
+	// Signer signs the token. Its algorithm must be HMAC-SHA256, HMAC-SHA512
+	// or Ed25519. Exactly one of Signer or Keyring is required.
+	Signer Signer
+	// KeyID is sent in the "kid" header with Signer.
+	KeyID string
+	// Keyring signs with its primary key and sends that key's ID. Keys with a
+	// Secret sign with HS256.
+	Keyring *Keyring
+}
+
+// VerifyJWTOptions configures VerifyJWT.
+type VerifyJWTOptions struct {
+	// Algorithms is the allow-list of accepted "alg" values. Required.
+	Algorithms []JWTAlgorithm
+	// Verifier checks the signature. Exactly one of Verifier or Keyring is required.
+	Verifier Verifier
+	// Keyring verifies against the active key named by the "kid" header, or
+	// every active key when there is none.
+	Keyring *Keyring
+	// Issuer, when set, must equal the "iss" claim.
+	Issuer string
+	// Audience, when set, must be one of the "aud" values. Tokens with an
+	// "aud" claim are rejected when it is empty.
+	Audience string
+	// Leeway is the clock skew tolerated for "exp", "nbf" and "iat".
+	Leeway time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// SignJWT returns claims as a JWS compact serialization signed with the
+// signer or the keyring's primary key.
+//
+// Example:
+//
+//	token, err := SignJWT(JWTClaims{
+//		Issuer:    "https://auth.example.com",
+//		Subject:   userID,
+//		Audience:  []string{"web"},
+//		IssuedAt:  time.Now(),
+//		ExpiresAt: time.Now().Add(15 * time.Minute),
+//	}, SignJWTOptions{Keyring: keyring})
+func SignJWT(claims JWTClaims, opts SignJWTOptions) (string, error) {
+	if (opts.Signer == nil) == (opts.Keyring == nil) {
+		return "", errors.New("JWT signing requires exactly one of Signer or Keyring")
+	}
+	signer, keyID := opts.Signer, opts.KeyID
+	if opts.Keyring != nil {
+		key, err := opts.Keyring.Primary()
+		if err != nil {
+			return "", err
+		}
+		if signer, err = KeySigner(key); err != nil {
+			return "", err
+		}
+		keyID = key.ID
+	}
+	alg, ok := jwtAlgorithms[signer.Algorithm()]
+	if !ok {
+		return "", fmt.Errorf("unsupported JWT signing algorithm %q", signer.Algorithm())
+	}
+
+	header, err := json.Marshal(jwtHeader{Algorithm: alg, KeyID: keyID, Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims.toMap())
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := signer.Sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyJWT checks the signature and claims of a JWS compact serialization
+// and returns its claims. The "alg" header must be in the allow-list and
+// match the algorithm of the key, so a token cannot choose how it is
+// verified. The "exp" claim is required. Errors match:
+//
+//   - ErrTokenMalformed when the token or its claims cannot be parsed
+//   - ErrJWTAlgorithmNotAllowed when "alg" is not allowed
+//   - ErrUnknownKey when the keyring has no active key named by "kid"
+//   - ErrTokenInvalid when the signature does not match
+//   - ErrTokenExpired, ErrTokenNotYetValid, ErrTokenIssuerMismatch or
+//     ErrTokenAudienceMismatch when a claim is rejected
+//
+// Example:
+//
+//	claims, err := VerifyJWT(token, VerifyJWTOptions{
+//		Algorithms: []JWTAlgorithm{JWTAlgorithmEdDSA},
+//		Keyring:    keyring,
+//		Issuer:     "https://auth.example.com",
+//		Audience:   "web",
+//		Leeway:     30 * time.Second,
+//	})
+func VerifyJWT(token string, opts VerifyJWTOptions) (*JWTClaims, error) {
+	if (opts.Verifier == nil) == (opts.Keyring == nil) {
+		return nil, errors.New("JWT verification requires exactly one of Verifier or Keyring")
+	}
+	if len(opts.Algorithms) == 0 {
+		return nil, errors.New("JWT verification requires Algorithms")
+	}
+	for _, alg := range opts.Algorithms {
+		if !slices.Contains([]JWTAlgorithm{JWTAlgorithmHS256, JWTAlgorithmHS512, JWTAlgorithmEdDSA}, alg) {
+			return nil, fmt.Errorf("unsupported JWT algorithm %q", alg)
+		}
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+	var header jwtHeader
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	// Extensions listed in "crit" must be understood; none are supported.
+	if len(header.Critical) > 0 {
+		return nil, fmt.Errorf("%w: unsupported crit header", ErrTokenMalformed)
+	}
+	if !slices.Contains(opts.Algorithms, header.Algorithm) {
+		return nil, fmt.Errorf("%w: %q", ErrJWTAlgorithmNotAllowed, header.Algorithm)
+	}
+	signature, err := base64.RawURLEncoding.Strict().DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	signingInput := []byte(parts[0] + "." + parts[1])
+	verify := func(verifier Verifier) bool {
+		return verifier != nil && jwtAlgorithms[verifier.Algorithm()] == header.Algorithm && verifier.Verify(signingInput, signature)
+	}
+	if opts.Keyring != nil {
+		_, err := opts.Keyring.Match(header.KeyID, func(key SigningKey) bool { return verify(KeyVerifier(key)) })
+		if errors.Is(err, ErrSignatureMismatch) {
+			return nil, ErrTokenInvalid
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else if !verify(opts.Verifier) {
+		return nil, ErrTokenInvalid
+	}
+
+	var raw map[string]json.RawMessage
+	if err := decodeJWTSegment(parts[1], &raw); err != nil {
+		return nil, err
+	}
+	claims, err := parseJWTClaims(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := claims.validate(opts); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validate checks the time, issuer and audience claims.
+func (c *JWTClaims) validate(opts VerifyJWTOptions) error {
+	now := opts.Now()
+	if c.ExpiresAt.IsZero() {
+		return fmt.Errorf("%w: missing exp claim", ErrTokenMalformed)
+	}
+	if !now.Before(c.ExpiresAt.Add(opts.Leeway)) {
+		return ErrTokenExpired
+	}
+	if !c.NotBefore.IsZero() && now.Add(opts.Leeway).Before(c.NotBefore) {
+		return ErrTokenNotYetValid
+	}
+	if !c.IssuedAt.IsZero() && now.Add(opts.Leeway).Before(c.IssuedAt) {
+		return ErrTokenNotYetValid
+	}
+	if opts.Issuer != "" && c.Issuer != opts.Issuer {
+		return ErrTokenIssuerMismatch
+	}
+	if len(c.Audience) > 0 || opts.Audience != "" {
+		if !slices.Contains(c.Audience, opts.Audience) {
+			return ErrTokenAudienceMismatch
+		}
+	}
+	return nil
+}
+
+// toMap returns the claims as a JSON object.
+func (c JWTClaims) toMap() map[string]any {
+	m := make(map[string]any, len(c.Extra)+len(jwtRegisteredClaims))
+	for name, value := range c.Extra {
+		if !slices.Contains(jwtRegisteredClaims, name) {
+			m[name] = value
+		}
+	}
+	for name, value := range map[string]string{"iss": c.Issuer, "sub": c.Subject, "jti": c.ID} {
+		if value != "" {
+			m[name] = value
+		}
+	}
+	switch len(c.Audience) {
+	case 0:
+	case 1:
+		m["aud"] = c.Audience[0]
+	default:
+		m["aud"] = c.Audience
+	}
+	for name, t := range map[string]time.Time{"exp": c.ExpiresAt, "nbf": c.NotBefore, "iat": c.IssuedAt} {
+		if !t.IsZero() {
+			m[name] = t.Unix()
+		}
+	}
+	return m
+}
+
+// parseJWTClaims decodes the registered claims of a JWT payload and keeps
+// the others in Extra.
+func parseJWTClaims(raw map[string]json.RawMessage) (*JWTClaims, error) {
+	claims := &JWTClaims{}
+	for name, value := range raw {
+		var err error
+		switch name {
+		case "iss":
+			err = json.Unmarshal(value, &claims.Issuer)
+		case "sub":
+			err = json.Unmarshal(value, &claims.Subject)
+		case "jti":
+			err = json.Unmarshal(value, &claims.ID)
+		case "aud":
+			claims.Audience, err = parseJWTAudience(value)
+		case "exp":
+			claims.ExpiresAt, err = parseJWTNumericDate(value)
+		case "nbf":
+			claims.NotBefore, err = parseJWTNumericDate(value)
+		case "iat":
+			claims.IssuedAt, err = parseJWTNumericDate(value)
+		default:
+			var v any
+			err = json.Unmarshal(value, &v)
+			if claims.Extra == nil {
+				claims.Extra = make(map[string]any)
+			}
+			claims.Extra[name] = v
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid %s claim", ErrTokenMalformed, name)
+		}
+	}
+	return claims, nil
+}
+
+// parseJWTAudience decodes an "aud" claim, a string or an array of strings.
+func parseJWTAudience(value json.RawMessage) ([]string, error) {
+	var single string
+	if err := json.Unmarshal(value, &single); err == nil {
+		return []string{single}, nil
+	}
+	var list []string
+	if err := json.Unmarshal(value, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// parseJWTNumericDate decodes a NumericDate: seconds since the epoch,
+// possibly with a fractional part.
+func parseJWTNumericDate(value json.RawMessage) (time.Time, error) {
+	var seconds float64
+	if err := json.Unmarshal(value, &seconds); err != nil {
+		return time.Time{}, err
+	}
+	if seconds < 0 || seconds > float64(math.MaxInt64/int64(time.Second)) {
+		return time.Time{}, errors.New("numeric date out of range")
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*float64(time.Second))), nil
+}
+
+// decodeJWTSegment decodes a base64url JSON object into v.
+func decodeJWTSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.Strict().DecodeString(segment)
+	if err != nil {
+		return ErrTokenMalformed
+	}
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("{")) {
+		return ErrTokenMalformed
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrTokenMalformed
+	}
+	return nil
+}
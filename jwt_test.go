@@ -0,0 +1,238 @@
+package xgen
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyJWT_RFC7515(t *testing.T) {
+	// RFC 7515, appendix A.1.
+	key, err := base64.RawURLEncoding.DecodeString("AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow")
+	require.NoError(t, err)
+	signer, err := NewHMACSigner(SignatureAlgorithmHMACSHA256, string(key))
+	require.NoError(t, err)
+	token := "eyJ0eXAiOiJKV1QiLA0KICJhbGciOiJIUzI1NiJ9" +
+		".eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ" +
+		".dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+	opts := VerifyJWTOptions{
+		Algorithms: []JWTAlgorithm{JWTAlgorithmHS256},
+		Verifier:   signer,
+		Issuer:     "joe",
+		Now:        func() time.Time { return time.Unix(1300819000, 0) },
+	}
+	claims, err := VerifyJWT(token, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "joe", claims.Issuer)
+	assert.Equal(t, time.Unix(1300819380, 0), claims.ExpiresAt)
+	assert.Equal(t, map[string]any{"http://example.com/is_root": true}, claims.Extra)
+
+	opts.Now = func() time.Time { return time.Unix(1300819380, 0) }
+	_, err = VerifyJWT(token, opts)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+	opts.Leeway = time.Minute
+	_, err = VerifyJWT(token, opts)
+	assert.NoError(t, err)
+}
+
+func TestSignVerifyJWT(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signer, err := NewHMACSigner(SignatureAlgorithmHMACSHA512, "secret")
+	require.NoError(t, err)
+	claims := JWTClaims{
+		Issuer:    "https://auth.example.com",
+		Subject:   "user-42",
+		Audience:  []string{"web"},
+		IssuedAt:  now,
+		NotBefore: now,
+		ExpiresAt: now.Add(15 * time.Minute),
+		ID:        "t-1",
+		Extra:     map[string]any{"role": "admin", "exp": "ignored"},
+	}
+	token, err := SignJWT(claims, SignJWTOptions{Signer: signer, KeyID: "k1"})
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	header, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	assert.JSONEq(t, `{"alg":"HS512","kid":"k1","typ":"JWT"}`, string(header))
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.JSONEq(t, `{"iss":"https://auth.example.com","sub":"user-42","aud":"web","iat":1700000000,"nbf":1700000000,"exp":1700000900,"jti":"t-1","role":"admin"}`, string(payload))
+
+	opts := VerifyJWTOptions{
+		Algorithms: []JWTAlgorithm{JWTAlgorithmHS512},
+		Verifier:   signer,
+		Issuer:     "https://auth.example.com",
+		Audience:   "web",
+		Now:        func() time.Time { return now },
+	}
+	got, err := VerifyJWT(token, opts)
+	require.NoError(t, err)
+	claims.Extra = map[string]any{"role": "admin"}
+	assert.Equal(t, claims, *got)
+
+	for _, tt := range []struct {
+		mutate func(*VerifyJWTOptions)
+		err    error
+	}{
+		{func(o *VerifyJWTOptions) { o.Issuer = "https://evil.example.com" }, ErrTokenIssuerMismatch},
+		{func(o *VerifyJWTOptions) { o.Audience = "mobile" }, ErrTokenAudienceMismatch},
+		{func(o *VerifyJWTOptions) { o.Audience = "" }, ErrTokenAudienceMismatch},
+		{func(o *VerifyJWTOptions) { o.Now = func() time.Time { return now.Add(-time.Second) } }, ErrTokenNotYetValid},
+		{func(o *VerifyJWTOptions) { o.Now = func() time.Time { return now.Add(15 * time.Minute) } }, ErrTokenExpired},
+		{func(o *VerifyJWTOptions) { o.Algorithms = []JWTAlgorithm{JWTAlgorithmHS256} }, ErrJWTAlgorithmNotAllowed},
+	} {
+		o := opts
+		tt.mutate(&o)
+		_, err := VerifyJWT(token, o)
+		assert.ErrorIs(t, err, tt.err)
+	}
+
+	// Leeway tolerates small clock differences.
+	leeway := opts
+	leeway.Leeway = 5 * time.Second
+	leeway.Now = func() time.Time { return now.Add(-time.Second) }
+	_, err = VerifyJWT(token, leeway)
+	assert.NoError(t, err)
+
+	// The same secret with a different hash does not verify.
+	hs256, err := NewHMACSigner(SignatureAlgorithmHMACSHA256, "secret")
+	require.NoError(t, err)
+	wrongKey := opts
+	wrongKey.Algorithms = []JWTAlgorithm{JWTAlgorithmHS256, JWTAlgorithmHS512}
+	wrongKey.Verifier = hs256
+	_, err = VerifyJWT(token, wrongKey)
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+
+	_, err = VerifyJWT(parts[0]+"."+base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin","exp":1700000900}`))+"."+parts[2], opts)
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+}
+
+// unsignedJWT encodes header and payload and appends signature as is.
+func unsignedJWT(header, payload, signature string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + signature
+}
+
+func TestVerifyJWT_Rejects(t *testing.T) {
+	signer, err := NewHMACSigner(SignatureAlgorithmHMACSHA256, "secret")
+	require.NoError(t, err)
+	opts := VerifyJWTOptions{Algorithms: []JWTAlgorithm{JWTAlgorithmHS256}, Verifier: signer}
+	exp := `{"exp":` + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + `}`
+
+	// sign returns a validly signed token for header and payload.
+	sign := func(header, payload string) string {
+		input := unsignedJWT(header, payload, "")
+		sig, _ := signer.Sign([]byte(strings.TrimSuffix(input, ".")))
+		return input + base64.RawURLEncoding.EncodeToString(sig)
+	}
+	_, err = VerifyJWT(sign(`{"alg":"HS256"}`, exp), opts)
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		token string
+		err   error
+	}{
+		{unsignedJWT(`{"alg":"none"}`, exp, ""), ErrJWTAlgorithmNotAllowed},
+		{unsignedJWT(`{"alg":"None"}`, exp, ""), ErrJWTAlgorithmNotAllowed},
+		{sign(`{"alg":"HS512"}`, exp), ErrJWTAlgorithmNotAllowed},
+		{sign(`{}`, exp), ErrJWTAlgorithmNotAllowed},
+		{sign(`{"alg":"HS256","crit":["exp"]}`, exp), ErrTokenMalformed},
+		{sign(`{"alg":"HS256"}`, `{"sub":"user-42"}`), ErrTokenMalformed},
+		{sign(`{"alg":"HS256"}`, `{"exp":"tomorrow"}`), ErrTokenMalformed},
+		{sign(`{"alg":"HS256"}`, `{"exp":-1}`), ErrTokenMalformed},
+		{sign(`{"alg":"HS256"}`, `{"exp":1e300}`), ErrTokenMalformed},
+		{sign(`{"alg":"HS256"}`, `{"exp":4102444800,"aud":7}`), ErrTokenMalformed},
+		{sign(`{"alg":"HS256"}`, `[1]`), ErrTokenMalformed},
+		{sign(`{"alg":"HS256"}`, `{"exp":4102444800,"iat":4102444000}`), ErrTokenNotYetValid},
+		{sign(`{"alg":"HS256"}`, exp) + "=", ErrTokenMalformed},
+		{"", ErrTokenMalformed},
+		{"a.b", ErrTokenMalformed},
+		{"a.b.c.d", ErrTokenMalformed},
+		{"!." + strings.SplitN(sign(`{"alg":"HS256"}`, exp), ".", 2)[1], ErrTokenMalformed},
+	} {
+		_, err := VerifyJWT(tt.token, opts)
+		assert.ErrorIs(t, err, tt.err, tt.token)
+	}
+
+	for _, bad := range []VerifyJWTOptions{
+		{Verifier: signer},
+		{Algorithms: []JWTAlgorithm{"none"}, Verifier: signer},
+		{Algorithms: []JWTAlgorithm{JWTAlgorithmHS256}},
+	} {
+		_, err := VerifyJWT(sign(`{"alg":"HS256"}`, exp), bad)
+		assert.Error(t, err)
+	}
+}
+
+func TestSignVerifyJWT_Keyring(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	edSigner, err := NewEd25519Signer(ed25519.NewKeyFromSeed(seed))
+	require.NoError(t, err)
+	edVerifier, err := NewEd25519Verifier(ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey))
+	require.NoError(t, err)
+
+	// The issuer holds the private key; the API keyring shares the public key
+	// and an HMAC secret that also signs requests.
+	issuer, err := NewKeyring(SigningKey{ID: "ed-1", Signer: edSigner, State: KeyStatePrimary})
+	require.NoError(t, err)
+	api, err := NewKeyring(
+		SigningKey{ID: "ed-1", Verifier: edVerifier},
+		SigningKey{ID: "client-1", Secret: "shared-secret", State: KeyStatePrimary},
+	)
+	require.NoError(t, err)
+	claims := JWTClaims{Subject: "user-42", ExpiresAt: time.Now().Add(time.Hour)}
+
+	token, err := SignJWT(claims, SignJWTOptions{Keyring: issuer})
+	require.NoError(t, err)
+	header, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	assert.JSONEq(t, `{"alg":"EdDSA","kid":"ed-1","typ":"JWT"}`, string(header))
+
+	eddsaOnly := VerifyJWTOptions{Algorithms: []JWTAlgorithm{JWTAlgorithmEdDSA}, Keyring: api}
+	got, err := VerifyJWT(token, eddsaOnly)
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", got.Subject)
+
+	// HMAC tokens from the request-signing secret verify only when HS256 is allowed.
+	hsToken, err := SignJWT(claims, SignJWTOptions{Keyring: api})
+	require.NoError(t, err)
+	_, err = VerifyJWT(hsToken, eddsaOnly)
+	assert.ErrorIs(t, err, ErrJWTAlgorithmNotAllowed)
+	_, err = VerifyJWT(hsToken, VerifyJWTOptions{Algorithms: []JWTAlgorithm{JWTAlgorithmHS256, JWTAlgorithmEdDSA}, Keyring: api})
+	assert.NoError(t, err)
+
+	// An HS256 token claiming the Ed25519 key ID is rejected (algorithm confusion).
+	forged := unsignedJWT(`{"alg":"HS256","kid":"ed-1"}`, `{"exp":4102444800}`, "")
+	mac, err := NewHMACSigner(SignatureAlgorithmHMACSHA256, string(edVerifier.(*ed25519Verifier).public))
+	require.NoError(t, err)
+	sig, _ := mac.Sign([]byte(strings.TrimSuffix(forged, ".")))
+	_, err = VerifyJWT(forged+base64.RawURLEncoding.EncodeToString(sig), VerifyJWTOptions{Algorithms: []JWTAlgorithm{JWTAlgorithmHS256, JWTAlgorithmEdDSA}, Keyring: api})
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+
+	unknown := unsignedJWT(`{"alg":"EdDSA","kid":"ed-9"}`, `{"exp":4102444800}`, "AA")
+	_, err = VerifyJWT(unknown, eddsaOnly)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecSigner, err := NewECDSAP256Signer(ecKey)
+	require.NoError(t, err)
+	_, err = SignJWT(claims, SignJWTOptions{Signer: ecSigner})
+	assert.Error(t, err)
+	_, err = SignJWT(claims, SignJWTOptions{})
+	assert.Error(t, err)
+	empty, err := NewKeyring()
+	require.NoError(t, err)
+	_, err = SignJWT(claims, SignJWTOptions{Keyring: empty})
+	assert.ErrorIs(t, err, ErrNoPrimaryKey)
+}
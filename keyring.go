@@ -0,0 +1,399 @@
+package xgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// KeyState controls how a key in a Keyring may be used.
+type KeyState int
+
+const (
+	// KeyStateAccepted keys verify signatures but are never used for signing.
+	KeyStateAccepted KeyState = iota
+	// KeyStatePrimary keys sign new requests and verify signatures.
+	KeyStatePrimary
+	// KeyStateDisabled keys are kept in the keyring but neither sign nor verify.
+	KeyStateDisabled
+)
+
+// String returns the name of the key state.
+func (s KeyState) String() string {
+	switch s {
+	case KeyStateAccepted:
+		return "accepted"
+	case KeyStatePrimary:
+		return "primary"
+	case KeyStateDisabled:
+		return "disabled"
+	default:
+		return fmt.Sprintf("KeyState(%d)", int(s))
+	}
+}
+
+// ErrNoPrimaryKey is returned when a Keyring has no primary key that is currently active.
+var ErrNoPrimaryKey = errors.New("keyring has no active primary key")
+
+// SigningKey is a secret held by a Keyring.
+type SigningKey struct {
+	// ID identifies the key in the key ID header. Required and unique within a keyring.
+	ID string
+	// Secret is an HMAC-SHA256 secret used with GenerateSignature.
+	// Exactly one of Secret, Signer or Verifier is required.
+	Secret string
+	// Signer signs and verifies with GenerateSignatureWithSigner, e.g. an
+	// Ed25519 private key from NewEd25519Signer.
+	Signer Signer
+	// Verifier only verifies signatures from GenerateSignatureWithSigner,
+	// e.g. a partner's Ed25519 public key. Such keys cannot be primary.
+	Verifier Verifier
+	// State selects whether the key signs, only verifies, or is disabled.
+	State KeyState
+	// NotBefore is when the key becomes active. Zero means immediately.
+	NotBefore time.Time
+	// NotAfter is when the key stops being active. Zero means never.
+	NotAfter time.Time
+}
+
+// ActiveAt reports whether the key is enabled and within its validity period at t.
+func (k SigningKey) ActiveAt(t time.Time) bool {
+	if k.State == KeyStateDisabled {
+		return false
+	}
+	if !k.NotBefore.IsZero() && t.Before(k.NotBefore) {
+		return false
+	}
+	if !k.NotAfter.IsZero() && !t.Before(k.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// Keyring holds the signing keys of a client or server so secrets can be
+// rotated without a flag day.
+//
+// One primary key signs new requests while older (or newer, not yet
+// distributed) keys stay accepted for verification. A rotation typically
+// adds the new key as accepted on servers, promotes it to primary on
+// clients, and removes or expires the old key once traffic has moved over.
+// A Keyring is safe for concurrent use.
+type Keyring struct {
+	mu   sync.RWMutex
+	keys []SigningKey
+	now  func() time.Time
+}
+
+// NewKeyring creates a keyring holding keys.
+//
+// Example:
+//
+//	keyring, err := NewKeyring(
+//		SigningKey{ID: "2024-01", Secret: oldSecret, NotAfter: cutover.Add(24 * time.Hour)},
+//		SigningKey{ID: "2024-06", Secret: newSecret, State: KeyStatePrimary},
+//	)
+func NewKeyring(keys ...SigningKey) (*Keyring, error) {
+	k := &Keyring{now: time.Now}
+	for _, key := range keys {
+		if err := k.Add(key); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Add adds a key to the keyring. The key ID must not already be present.
+func (k *Keyring) Add(key SigningKey) error {
+	if key.ID == "" {
+		return errors.New("signing key requires ID")
+	}
+	if n := countSet(key.Secret != "", key.Signer != nil, key.Verifier != nil); n != 1 {
+		return fmt.Errorf("signing key %q requires exactly one of Secret, Signer or Verifier", key.ID)
+	}
+	if err := key.checkState(key.State); err != nil {
+		return err
+	}
+	if !key.NotBefore.IsZero() && !key.NotAfter.IsZero() && !key.NotBefore.Before(key.NotAfter) {
+		return fmt.Errorf("signing key %q: NotBefore must be before NotAfter", key.ID)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.indexOf(key.ID) >= 0 {
+		return fmt.Errorf("signing key %q already exists", key.ID)
+	}
+	k.keys = append(k.keys, key)
+	return nil
+}
+
+// Remove deletes the key with the given ID and reports whether it was present.
+func (k *Keyring) Remove(id string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	i := k.indexOf(id)
+	if i < 0 {
+		return false
+	}
+	k.keys = append(k.keys[:i], k.keys[i+1:]...)
+	return true
+}
+
+// SetState changes the state of the key with the given ID.
+func (k *Keyring) SetState(id string, state KeyState) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	i := k.indexOf(id)
+	if i < 0 {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	if err := k.keys[i].checkState(state); err != nil {
+		return err
+	}
+	k.keys[i].State = state
+	return nil
+}
+
+// Rotate adds key as the primary key and demotes the current primary keys to
+// accepted. When overlap is positive the demoted keys expire after overlap,
+// giving in-flight requests time to arrive; otherwise their expiry is unchanged.
+func (k *Keyring) Rotate(key SigningKey, overlap time.Duration) error {
+	key.State = KeyStatePrimary
+	if err := k.Add(key); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	expires := k.now().Add(overlap)
+	for i := range k.keys {
+		old := &k.keys[i]
+		if old.ID == key.ID || old.State != KeyStatePrimary {
+			continue
+		}
+		old.State = KeyStateAccepted
+		if overlap > 0 && (old.NotAfter.IsZero() || expires.Before(old.NotAfter)) {
+			old.NotAfter = expires
+		}
+	}
+	return nil
+}
+
+// Key returns the key with the given ID, whether or not it is active.
+func (k *Keyring) Key(id string) (SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	i := k.indexOf(id)
+	if i < 0 {
+		return SigningKey{}, false
+	}
+	return k.keys[i], true
+}
+
+// Keys returns a copy of all keys in the order they were added.
+func (k *Keyring) Keys() []SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return append([]SigningKey(nil), k.keys...)
+}
+
+// Primary returns the active primary key used for signing.
+// When several primary keys are active, the one with the latest NotBefore
+// wins, so a new primary can be scheduled in advance.
+func (k *Keyring) Primary() (SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	now := k.now()
+	var primary SigningKey
+	found := false
+	for _, key := range k.keys {
+		if key.State != KeyStatePrimary || !key.ActiveAt(now) {
+			continue
+		}
+		if !found || key.NotBefore.After(primary.NotBefore) {
+			primary, found = key, true
+		}
+	}
+	if !found {
+		return SigningKey{}, ErrNoPrimaryKey
+	}
+	return primary, nil
+}
+
+// Sign signs the request components with the primary key and returns the key
+// ID to send alongside the signature. Keys with a Secret use GenerateSignature;
+// keys with a Signer use GenerateSignatureWithSigner.
+func (k *Keyring) Sign(method, path, timestamp, rawBody string) (keyID, signature string, err error) {
+	key, err := k.Primary()
+	if err != nil {
+		return "", "", err
+	}
+	signature, err = signWithKey(key, method, path, timestamp, "", rawBody)
+	if err != nil {
+		return "", "", err
+	}
+	return key.ID, signature, nil
+}
+
+// Verify checks a signature created by Sign against the keyring and returns
+// the ID of the key that matched.
+//
+// When keyID is set only that key is tried, and it must be active. When keyID
+// is empty every active key is tried, which lets clients that do not send a
+// key ID keep working while their secret is rotated.
+func (k *Keyring) Verify(keyID, method, path, timestamp, rawBody, receivedSig string) (string, bool) {
+	key, err := k.Match(keyID, func(key SigningKey) bool {
+		return verifyWithKey(key, method, path, timestamp, "", rawBody, receivedSig)
+	})
+	if err != nil {
+		return "", false
+	}
+	return key.ID, true
+}
+
+// ResolveHTTPSignatureKey returns the verifier of the active key named keyID.
+// It has the HTTPSignatureKeyResolver signature, so a keyring can be passed
+// directly to VerifyHTTPMessageSignature.
+func (k *Keyring) ResolveHTTPSignatureKey(_ context.Context, keyID string, _ SignatureAlgorithm) (Verifier, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	i := k.indexOf(keyID)
+	if i < 0 || !k.keys[i].ActiveAt(k.now()) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	if v := KeyVerifier(k.keys[i]); v != nil {
+		return v, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+}
+
+// Match returns the first key accepted by verify, following the key selection
+// rules of Verify. It returns ErrUnknownKey when keyID names no active key and
+// ErrSignatureMismatch when no key matches. It lets other protocols check
+// their own signatures against the keyring. verify is called with copies of
+// the keys and without holding the keyring lock.
+//
+// Example:
+//
+//	key, err := keyring.Match(keyID, func(key SigningKey) bool {
+//		return verifyMessage(key, message, signature)
+//	})
+func (k *Keyring) Match(keyID string, verify func(key SigningKey) bool) (SigningKey, error) {
+	candidates, err := k.candidates(keyID)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	// verify runs without the lock, so slow signature checks do not block
+	// rotation and callbacks may use the keyring.
+	for _, key := range candidates {
+		if verify(key) {
+			return key, nil
+		}
+	}
+	return SigningKey{}, ErrSignatureMismatch
+}
+
+// candidates returns a copy of the keys Match tries for keyID: the named key,
+// which must be active, or every active key when keyID is empty.
+func (k *Keyring) candidates(keyID string) ([]SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	now := k.now()
+	if keyID != "" {
+		i := k.indexOf(keyID)
+		if i < 0 || !k.keys[i].ActiveAt(now) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+		}
+		return []SigningKey{k.keys[i]}, nil
+	}
+	var keys []SigningKey
+	for _, key := range k.keys {
+		if key.ActiveAt(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// indexOf returns the position of the key with the given ID, or -1.
+// The caller must hold the lock.
+func (k *Keyring) indexOf(id string) int {
+	for i, key := range k.keys {
+		if key.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkState reports whether key may be put in state.
+func (key SigningKey) checkState(state KeyState) error {
+	if state < KeyStateAccepted || state > KeyStateDisabled {
+		return fmt.Errorf("invalid key state %d", int(state))
+	}
+	if state == KeyStatePrimary && key.Verifier != nil {
+		return fmt.Errorf("signing key %q has only a Verifier and cannot be primary", key.ID)
+	}
+	return nil
+}
+
+// signWithKey signs the request components with key, using GenerateSignatureWithSigner
+// for keys with a Signer and the HMAC-SHA256 secret functions otherwise.
+func signWithKey(key SigningKey, method, path, timestamp, nonce, rawBody string) (string, error) {
+	switch {
+	case key.Signer != nil:
+		return GenerateSignatureWithSigner(key.Signer, method, path, timestamp, nonce, rawBody)
+	case nonce != "":
+		return GenerateSignatureWithNonce(key.Secret, method, path, timestamp, nonce, rawBody)
+	default:
+		return GenerateSignature(key.Secret, method, path, timestamp, rawBody)
+	}
+}
+
+// verifyWithKey checks a signature created by signWithKey with the same key.
+func verifyWithKey(key SigningKey, method, path, timestamp, nonce, rawBody, receivedSig string) bool {
+	switch {
+	case key.Signer != nil:
+		return VerifySignatureWithVerifier(key.Signer, method, path, timestamp, nonce, rawBody, receivedSig)
+	case key.Verifier != nil:
+		return VerifySignatureWithVerifier(key.Verifier, method, path, timestamp, nonce, rawBody, receivedSig)
+	case nonce != "":
+		return VerifySignatureWithNonce(key.Secret, method, path, timestamp, nonce, rawBody, receivedSig)
+	default:
+		return VerifySignature(key.Secret, method, path, timestamp, rawBody, receivedSig)
+	}
+}
+
+// KeySigner returns the Signer of key, using HMAC-SHA256 for a Secret, so
+// code outside this package signs with keys the way the keyring does.
+func KeySigner(key SigningKey) (Signer, error) {
+	if key.Signer != nil {
+		return key.Signer, nil
+	}
+	return NewHMACSigner(SignatureAlgorithmHMACSHA256, key.Secret)
+}
+
+// KeyVerifier returns the Verifier of key, using HMAC-SHA256 for a Secret,
+// or nil when the key cannot verify.
+func KeyVerifier(key SigningKey) Verifier {
+	if key.Verifier != nil {
+		return key.Verifier
+	}
+	signer, err := KeySigner(key)
+	if err != nil {
+		return nil
+	}
+	return signer
+}
+
+// countSet returns how many of the conditions are true.
+func countSet(conds ...bool) int {
+	n := 0
+	for _, c := range conds {
+		if c {
+			n++
+		}
+	}
+	return n
+}
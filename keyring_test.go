@@ -0,0 +1,263 @@
+package xgen
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigningKey_ActiveAt(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name string
+		key  SigningKey
+		want bool
+	}{
+		{"no bounds", SigningKey{}, true},
+		{"not yet active", SigningKey{NotBefore: now.Add(time.Second)}, false},
+		{"activates now", SigningKey{NotBefore: now}, true},
+		{"expired", SigningKey{NotAfter: now}, false},
+		{"before expiry", SigningKey{NotAfter: now.Add(time.Second)}, true},
+		{"disabled", SigningKey{State: KeyStateDisabled}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.key.ActiveAt(now))
+		})
+	}
+}
+
+func TestNewKeyring_Validation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name string
+		keys []SigningKey
+	}{
+		{"missing id", []SigningKey{{Secret: "s"}}},
+		{"missing secret", []SigningKey{{ID: "a"}}},
+		{"duplicate id", []SigningKey{{ID: "a", Secret: "s"}, {ID: "a", Secret: "t"}}},
+		{"invalid state", []SigningKey{{ID: "a", Secret: "s", State: KeyState(9)}}},
+		{"empty validity", []SigningKey{{ID: "a", Secret: "s", NotBefore: now, NotAfter: now}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.keys...)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestKeyring_Primary(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	keyring, err := NewKeyring(
+		SigningKey{ID: "old", Secret: "s1", State: KeyStatePrimary},
+		SigningKey{ID: "next", Secret: "s2", State: KeyStatePrimary, NotBefore: now.Add(time.Hour)},
+		SigningKey{ID: "accepted", Secret: "s3"},
+	)
+	require.NoError(t, err)
+	keyring.now = func() time.Time { return now }
+
+	key, err := keyring.Primary()
+	require.NoError(t, err)
+	assert.Equal(t, "old", key.ID)
+
+	// The scheduled primary takes over once it activates.
+	now = now.Add(time.Hour)
+	key, err = keyring.Primary()
+	require.NoError(t, err)
+	assert.Equal(t, "next", key.ID)
+
+	require.NoError(t, keyring.SetState("old", KeyStateDisabled))
+	require.NoError(t, keyring.SetState("next", KeyStateAccepted))
+	_, err = keyring.Primary()
+	assert.ErrorIs(t, err, ErrNoPrimaryKey)
+
+	assert.ErrorIs(t, keyring.SetState("missing", KeyStatePrimary), ErrUnknownKey)
+}
+
+func TestKeyring_SignVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	keyring, err := NewKeyring(
+		SigningKey{ID: "k1", Secret: "secret-1", State: KeyStatePrimary},
+		SigningKey{ID: "k2", Secret: "secret-2", NotAfter: now.Add(time.Hour)},
+	)
+	require.NoError(t, err)
+	keyring.now = func() time.Time { return now }
+
+	keyID, sig, err := keyring.Sign("POST", "/api", "1700000000", "body")
+	require.NoError(t, err)
+	assert.Equal(t, "k1", keyID)
+	assert.True(t, VerifySignature("secret-1", "POST", "/api", "1700000000", "body", sig))
+
+	matched, ok := keyring.Verify("k1", "POST", "/api", "1700000000", "body", sig)
+	assert.True(t, ok)
+	assert.Equal(t, "k1", matched)
+
+	// Without a key ID every active key is tried.
+	sig2, err := GenerateSignature("secret-2", "POST", "/api", "1700000000", "body")
+	require.NoError(t, err)
+	matched, ok = keyring.Verify("", "POST", "/api", "1700000000", "body", sig2)
+	assert.True(t, ok)
+	assert.Equal(t, "k2", matched)
+
+	// A named key must match by itself.
+	_, ok = keyring.Verify("k1", "POST", "/api", "1700000000", "body", sig2)
+	assert.False(t, ok)
+	_, ok = keyring.Verify("unknown", "POST", "/api", "1700000000", "body", sig)
+	assert.False(t, ok)
+
+	// Expired keys no longer verify.
+	now = now.Add(time.Hour)
+	_, ok = keyring.Verify("k2", "POST", "/api", "1700000000", "body", sig2)
+	assert.False(t, ok)
+	_, ok = keyring.Verify("", "POST", "/api", "1700000000", "body", sig2)
+	assert.False(t, ok)
+}
+
+func TestKeyring_Rotate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	keyring, err := NewKeyring(SigningKey{ID: "k1", Secret: "secret-1", State: KeyStatePrimary})
+	require.NoError(t, err)
+	keyring.now = func() time.Time { return now }
+
+	oldSig, err := GenerateSignature("secret-1", "GET", "/", "1700000000", "")
+	require.NoError(t, err)
+
+	require.NoError(t, keyring.Rotate(SigningKey{ID: "k2", Secret: "secret-2"}, time.Hour))
+	key, err := keyring.Primary()
+	require.NoError(t, err)
+	assert.Equal(t, "k2", key.ID)
+
+	old, ok := keyring.Key("k1")
+	require.True(t, ok)
+	assert.Equal(t, KeyStateAccepted, old.State)
+	assert.Equal(t, now.Add(time.Hour), old.NotAfter)
+
+	// Old signatures verify during the overlap only.
+	_, ok = keyring.Verify("k1", "GET", "/", "1700000000", "", oldSig)
+	assert.True(t, ok)
+	now = now.Add(time.Hour)
+	_, ok = keyring.Verify("k1", "GET", "/", "1700000000", "", oldSig)
+	assert.False(t, ok)
+
+	assert.Error(t, keyring.Rotate(SigningKey{ID: "k2", Secret: "again"}, 0))
+	assert.True(t, keyring.Remove("k1"))
+	assert.False(t, keyring.Remove("k1"))
+	assert.Len(t, keyring.Keys(), 1)
+}
+
+func TestKeyring_MatchUnlocked(t *testing.T) {
+	keyring, err := NewKeyring(
+		SigningKey{ID: "k1", Secret: "secret-1", State: KeyStatePrimary},
+		SigningKey{ID: "k2", Secret: "secret-2"},
+	)
+	require.NoError(t, err)
+
+	// Callbacks run without the lock, so they may change the keyring.
+	done := make(chan error, 1)
+	go func() {
+		_, err := keyring.Match("", func(key SigningKey) bool {
+			return key.ID == "k2" && keyring.Rotate(SigningKey{ID: "k3", Secret: "secret-3"}, time.Hour) == nil
+		})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Match blocked a keyring update from its callback")
+	}
+	primary, err := keyring.Primary()
+	require.NoError(t, err)
+	assert.Equal(t, "k3", primary.ID)
+
+	_, err = keyring.Match("k9", func(SigningKey) bool { return true })
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	_, err = keyring.Match("k1", func(SigningKey) bool { return false })
+	assert.ErrorIs(t, err, ErrSignatureMismatch)
+}
+
+func TestKeyring_MiddlewareTransport(t *testing.T) {
+	serverKeys, err := NewKeyring(
+		SigningKey{ID: "k1", Secret: "secret-1"},
+		SigningKey{ID: "k2", Secret: "secret-2"},
+	)
+	require.NoError(t, err)
+	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{Keyring: serverKeys})
+	require.NoError(t, err)
+	server := httptest.NewServer(mw(echoHandler))
+	defer server.Close()
+
+	clientKeys, err := NewKeyring(SigningKey{ID: "k1", Secret: "secret-1", State: KeyStatePrimary})
+	require.NoError(t, err)
+	transport, err := NewSignatureTransport(SignatureTransportOptions{Keyring: clientKeys})
+	require.NoError(t, err)
+	client := &http.Client{Transport: transport}
+
+	post := func() (int, string) {
+		resp, err := client.Post(server.URL+"/api", "text/plain", strings.NewReader("data"))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	status, body := post()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "k1:data", body)
+
+	// The client rotates; the server already accepts the new key.
+	require.NoError(t, clientKeys.Rotate(SigningKey{ID: "k2", Secret: "secret-2"}, 0))
+	status, body = post()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "k2:data", body)
+
+	// Requests without a key ID are matched against every active key.
+	req := httptest.NewRequest("POST", "/api", strings.NewReader("data"))
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	sig, err := GenerateSignature("secret-2", "POST", "/api", timestamp, "data")
+	require.NoError(t, err)
+	req.Header.Set(DefaultSignatureHeader, sig)
+	req.Header.Set(DefaultSignatureTimestampHeader, timestamp)
+	rec := httptest.NewRecorder()
+	mw(echoHandler).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "k2:data", rec.Body.String())
+
+	// Disabled keys are rejected.
+	require.NoError(t, serverKeys.SetState("k2", KeyStateDisabled))
+	status, _ = post()
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestKeyring_OptionsValidation(t *testing.T) {
+	keyring, err := NewKeyring()
+	require.NoError(t, err)
+
+	_, err = NewSignatureMiddleware(SignatureMiddlewareOptions{LookupSecret: StaticSecretLookup("s"), Keyring: keyring})
+	assert.Error(t, err)
+	_, err = NewSignatureTransport(SignatureTransportOptions{Secret: "s", Keyring: keyring})
+	assert.Error(t, err)
+
+	// A keyring without a primary key fails the request, not the constructor.
+	transport, err := NewSignatureTransport(SignatureTransportOptions{Keyring: keyring})
+	require.NoError(t, err)
+	req, err := http.NewRequest("GET", "http://example.com/", nil)
+	require.NoError(t, err)
+	_, err = transport.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrNoPrimaryKey)
+}
+
+func TestKeyState_String(t *testing.T) {
+	assert.Equal(t, "accepted", KeyStateAccepted.String())
+	assert.Equal(t, "primary", KeyStatePrimary.String())
+	assert.Equal(t, "disabled", KeyStateDisabled.String())
+	assert.Equal(t, "KeyState(7)", KeyState(7).String())
+}
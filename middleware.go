@@ -0,0 +1,408 @@
+package xgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default header names used for signed HTTP requests.
+const (
+	DefaultSignatureHeader          = "X-Signature"
+	DefaultSignatureTimestampHeader = "X-Signature-Timestamp"
+	DefaultSignatureKeyIDHeader     = "X-Signature-Key-Id"
+	DefaultSignatureNonceHeader     = "X-Signature-Nonce"
+	// DefaultSignatureSignedHeadersHeader lists the headers covered by a v2 signature.
+	DefaultSignatureSignedHeadersHeader = "X-Signature-Headers"
+)
+
+// defaultMaxSignedBodySize is the default limit for request bodies read by the middleware.
+const defaultMaxSignedBodySize = 10 << 20 // 10 MiB
+
+// Errors passed to the middleware error handler.
+var (
+	// ErrMissingSignature is returned when the signature or timestamp header is absent.
+	ErrMissingSignature = errors.New("missing request signature")
+	// ErrInvalidSignatureTimestamp is returned when the timestamp is malformed or outside the allowed drift.
+	ErrInvalidSignatureTimestamp = errors.New("invalid signature timestamp")
+	// ErrInvalidSignature is returned when the signature does not match the request.
+	ErrInvalidSignature = errors.New("invalid request signature")
+	// ErrUnknownKey is returned by a SecretLookupFunc when the key ID is not recognized.
+	ErrUnknownKey = errors.New("unknown signature key")
+	// ErrBodyTooLarge is returned when the request body exceeds the configured limit.
+	ErrBodyTooLarge = errors.New("request body too large")
+	// ErrMissingNonce is returned when a NonceStore is configured but the request has no nonce.
+	ErrMissingNonce = errors.New("missing request nonce")
+	// ErrReplayedNonce is returned when the request nonce has already been used.
+	ErrReplayedNonce = errors.New("request nonce already used")
+	// ErrUnsignedHeader is returned when a v2 request does not sign a required header.
+	ErrUnsignedHeader = errors.New("required header not signed")
+)
+
+// SecretLookupFunc returns the signing secret for a key ID.
+// It should return ErrUnknownKey (optionally wrapped) for unrecognized key IDs;
+// the key ID is empty when the request has no key ID header.
+type SecretLookupFunc func(ctx context.Context, keyID string) (string, error)
+
+// StaticSecretLookup returns a SecretLookupFunc that uses the same secret for every key ID.
+func StaticSecretLookup(secret string) SecretLookupFunc {
+	return func(context.Context, string) (string, error) {
+		return secret, nil
+	}
+}
+
+// MapSecretLookup returns a SecretLookupFunc that looks key IDs up in secrets.
+// The map must not be modified after the lookup is created.
+func MapSecretLookup(secrets map[string]string) SecretLookupFunc {
+	return func(_ context.Context, keyID string) (string, error) {
+		secret, ok := secrets[keyID]
+		if !ok {
+			return "", ErrUnknownKey
+		}
+		return secret, nil
+	}
+}
+
+// SignatureMiddlewareOptions configures NewSignatureMiddleware.
+type SignatureMiddlewareOptions struct {
+	// LookupSecret resolves the secret for the request's key ID.
+	// Either LookupSecret or Keyring is required.
+	LookupSecret SecretLookupFunc
+	// Keyring verifies requests against its active keys instead of LookupSecret.
+	// Requests without a key ID are tried against every active key. Use it for
+	// asymmetric keys, which are verified with VerifySignatureWithVerifier.
+	Keyring *Keyring
+	// SignatureHeader defaults to DefaultSignatureHeader.
+	SignatureHeader string
+	// TimestampHeader defaults to DefaultSignatureTimestampHeader.
+	TimestampHeader string
+	// KeyIDHeader defaults to DefaultSignatureKeyIDHeader.
+	KeyIDHeader string
+	// NonceHeader defaults to DefaultSignatureNonceHeader.
+	NonceHeader string
+	// Version selects the canonical string format. Defaults to SignatureVersion1.
+	// SignatureVersion2 requests are verified with VerifySignatureV2; a Secret
+	// is used as an HMAC-SHA256 key.
+	Version SignatureVersion
+	// SignedHeadersHeader carries the v2 signed header list.
+	// Defaults to DefaultSignatureSignedHeadersHeader.
+	SignedHeadersHeader string
+	// RequiredSignedHeaders lists headers every v2 request must sign, e.g.
+	// "host" and "content-type". Requests that omit one fail with ErrUnsignedHeader.
+	RequiredSignedHeaders []string
+	// CredentialRegion and CredentialService, when set, require v1 requests
+	// signed with a ScopedKey for that region and service. The key ID is read
+	// from the credential header, and the signing key is derived from the
+	// secret with DeriveSigningKey. Credentials for another region, service or
+	// day fail with ErrInvalidCredentialScope.
+	CredentialRegion  string
+	CredentialService string
+	// CredentialHeader defaults to DefaultSignatureCredentialHeader.
+	CredentialHeader string
+	// NonceStore, when set, requires a nonce on every request and rejects
+	// nonces already used by the same key ID within the drift window.
+	// Requests carrying a nonce header are always verified with
+	// VerifySignatureWithNonce, even without a store.
+	NonceStore NonceStore
+	// ContentDigest verifies signatures over the RFC 9530 Content-Digest header
+	// instead of the body, for uploads too large to buffer. Requests without a
+	// sha-256 or sha-512 digest fail with ErrMissingContentDigest. The body is
+	// not read by the middleware: the handler reads it through a reader that
+	// returns ErrContentDigestMismatch at EOF if it does not match the digest.
+	ContentDigest bool
+	// CanonicalJSON verifies signatures over the RFC 8785 canonical form of
+	// JSON bodies (see GenerateSignatureJSON), so they survive proxies that
+	// re-serialize JSON. It applies to non-empty bodies with an application/json
+	// or "+json" Content-Type; bodies that are not valid JSON fail with
+	// ErrInvalidJSON. Handlers receive the canonical bytes that were verified,
+	// not the original body.
+	CanonicalJSON bool
+	// MaxBodySize limits the bytes read from the request body. Defaults to 10 MiB.
+	// With ContentDigest, reads past the limit fail with *http.MaxBytesError.
+	MaxBodySize int64
+	// AllowedDrift is the accepted timestamp drift. Defaults to ±5 minutes.
+	AllowedDrift time.Duration
+	// Timestamp sets the timestamp format (Unix seconds by default; set
+	// TimestampFormatAuto to also accept milliseconds and RFC 3339), separate
+	// past and future windows (defaulting to AllowedDrift) and the clock.
+	// Nonces are remembered for the past window.
+	Timestamp TimestampPolicy
+	// ErrorHandler writes the response for rejected requests.
+	// Defaults to DefaultSignatureErrorHandler.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// signatureKeyIDContextKey is the context key for the verified key ID.
+type signatureKeyIDContextKey struct{}
+
+// SignatureKeyIDFromContext returns the key ID of a request verified by the signature middleware.
+func SignatureKeyIDFromContext(ctx context.Context) (string, bool) {
+	keyID, ok := ctx.Value(signatureKeyIDContextKey{}).(string)
+	return keyID, ok
+}
+
+// ContextWithSignatureKeyID returns ctx carrying a verified key ID, for
+// verifiers of other protocols that report it through SignatureKeyIDFromContext.
+func ContextWithSignatureKeyID(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, signatureKeyIDContextKey{}, keyID)
+}
+
+// NewSignatureMiddleware returns middleware that verifies request signatures
+// created by GenerateSignature before calling the next handler.
+//
+// The middleware reads the signature, timestamp and key ID headers, looks up
+// the secret (or tries the keys of the Keyring), checks the timestamp drift and verifies the signature over the
+// method, URL path, timestamp, optional nonce and body. The body is restored
+// so downstream handlers can read it, and the verified key ID is stored in the
+// request context (see SignatureKeyIDFromContext). Rejected requests are
+// passed to the error handler.
+//
+// Example:
+//
+//	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+//		LookupSecret: MapSecretLookup(map[string]string{"client-1": secret}),
+//	})
+//	http.ListenAndServe(":8080", mw(mux))
+func NewSignatureMiddleware(opts SignatureMiddlewareOptions) (func(http.Handler) http.Handler, error) {
+	if (opts.LookupSecret == nil) == (opts.Keyring == nil) {
+		return nil, errors.New("signature middleware requires exactly one of LookupSecret or Keyring")
+	}
+	if opts.SignatureHeader == "" {
+		opts.SignatureHeader = DefaultSignatureHeader
+	}
+	if opts.TimestampHeader == "" {
+		opts.TimestampHeader = DefaultSignatureTimestampHeader
+	}
+	if opts.KeyIDHeader == "" {
+		opts.KeyIDHeader = DefaultSignatureKeyIDHeader
+	}
+	if opts.NonceHeader == "" {
+		opts.NonceHeader = DefaultSignatureNonceHeader
+	}
+	if opts.SignedHeadersHeader == "" {
+		opts.SignedHeadersHeader = DefaultSignatureSignedHeadersHeader
+	}
+	if opts.Version != SignatureVersion1 && opts.Version != SignatureVersion2 {
+		return nil, fmt.Errorf("unsupported signature version %d", int(opts.Version))
+	}
+	if opts.CredentialHeader == "" {
+		opts.CredentialHeader = DefaultSignatureCredentialHeader
+	}
+	if (opts.CredentialRegion == "") != (opts.CredentialService == "") {
+		return nil, errors.New("signature middleware requires both CredentialRegion and CredentialService")
+	}
+	if opts.CredentialService != "" && opts.Version != SignatureVersion1 {
+		return nil, errors.New("scoped signatures require SignatureVersion1")
+	}
+	if opts.CanonicalJSON && opts.ContentDigest {
+		return nil, errors.New("signature middleware cannot combine CanonicalJSON and ContentDigest")
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = defaultMaxSignedBodySize
+	}
+	if opts.AllowedDrift <= 0 {
+		opts.AllowedDrift = DefaultSignatureDrift
+	}
+	if err := ValidateTimestampFormat(opts.Timestamp.Format); err != nil {
+		return nil, err
+	}
+	opts.Timestamp = opts.Timestamp.withDrift(opts.AllowedDrift)
+	if opts.ErrorHandler == nil {
+		opts.ErrorHandler = DefaultSignatureErrorHandler
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keyID, err := verifySignedRequest(r, opts)
+			if err != nil {
+				opts.ErrorHandler(w, r, err)
+				return
+			}
+			ctx := ContextWithSignatureKeyID(r.Context(), keyID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}, nil
+}
+
+// DefaultSignatureErrorHandler responds with 413 for ErrBodyTooLarge, 400 for
+// ErrInvalidJSON, 503 for ErrNonceStoreFull, 500 for secret lookup and nonce
+// store failures and 401 for every other verification error.
+func DefaultSignatureErrorHandler(w http.ResponseWriter, _ *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrBodyTooLarge):
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, ErrInvalidJSON):
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	case errors.Is(err, ErrNonceStoreFull):
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	case errors.Is(err, ErrMissingSignature),
+		errors.Is(err, ErrInvalidSignatureTimestamp),
+		errors.Is(err, ErrInvalidSignature),
+		errors.Is(err, ErrUnknownKey),
+		errors.Is(err, ErrMissingNonce),
+		errors.Is(err, ErrReplayedNonce),
+		errors.Is(err, ErrUnsignedHeader),
+		errors.Is(err, ErrMissingContentDigest):
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// verifySignedRequest checks the signature headers of r and returns the verified key ID.
+// On return the request body has been replaced with an in-memory copy, or with
+// a digest-checking reader when opts.ContentDigest is set.
+func verifySignedRequest(r *http.Request, opts SignatureMiddlewareOptions) (string, error) {
+	signature := r.Header.Get(opts.SignatureHeader)
+	timestamp := r.Header.Get(opts.TimestampHeader)
+	if signature == "" || timestamp == "" {
+		return "", ErrMissingSignature
+	}
+	signedAt, _, err := opts.Timestamp.check(timestamp)
+	if err != nil {
+		return "", err
+	}
+	if _, err := hex.DecodeString(signature); err != nil {
+		return "", ErrMalformedSignature
+	}
+	nonce := r.Header.Get(opts.NonceHeader)
+	if opts.NonceStore != nil && nonce == "" {
+		return "", ErrMissingNonce
+	}
+	var signedHeaders []string
+	if opts.Version == SignatureVersion2 {
+		var err error
+		if signedHeaders, err = RequireSignedHeaders(r.Header.Get(opts.SignedHeadersHeader), opts.RequiredSignedHeaders); err != nil {
+			return "", err
+		}
+	}
+
+	keyID := r.Header.Get(opts.KeyIDHeader)
+	var scope CredentialScope
+	if opts.CredentialService != "" {
+		credential := r.Header.Get(opts.CredentialHeader)
+		if credential == "" {
+			return "", ErrMissingSignature
+		}
+		if keyID, scope, err = ParseCredential(credential); err != nil {
+			return "", err
+		}
+		if scope.Region != opts.CredentialRegion || scope.Service != opts.CredentialService || !scope.Covers(signedAt) {
+			return "", ErrInvalidCredentialScope
+		}
+	}
+	var secret string
+	if opts.LookupSecret != nil {
+		var err error
+		if secret, err = opts.LookupSecret(r.Context(), keyID); err != nil {
+			return "", err
+		}
+	}
+
+	var signedBody string
+	if opts.ContentDigest {
+		digest := r.Header.Get(ContentDigestHeader)
+		if digest == "" {
+			return "", ErrMissingContentDigest
+		}
+		if r.Body == nil {
+			r.Body = http.NoBody
+		}
+		body, err := NewContentDigestVerifier(http.MaxBytesReader(nil, r.Body, opts.MaxBodySize), digest)
+		if err != nil {
+			return "", err
+		}
+		r.Body, signedBody = body, digest
+	} else {
+		body, err := readRequestBody(r, opts.MaxBodySize)
+		if err != nil {
+			return "", err
+		}
+		signedBody = string(body)
+		if opts.CanonicalJSON && isJSONContentType(r.Header.Get("Content-Type")) {
+			if signedBody, err = canonicalJSONBody(signedBody); err != nil {
+				return "", err
+			}
+			// Hand the handler exactly the bytes that were verified.
+			r.Body = io.NopCloser(strings.NewReader(signedBody))
+			r.ContentLength = int64(len(signedBody))
+			r.Header.Set("Content-Length", strconv.Itoa(len(signedBody)))
+		}
+	}
+	verify := func(key SigningKey) bool {
+		if opts.CredentialService != "" {
+			signingKey, err := DeriveSigningKey(key.Secret, scope)
+			if err != nil {
+				return false
+			}
+			scoped := &ScopedKey{KeyID: keyID, Scope: scope, Key: signingKey, TimestampFormat: opts.Timestamp.Format}
+			return scoped.Verify(r.Method, r.URL.Path, timestamp, nonce, signedBody, signature)
+		}
+		if opts.Version == SignatureVersion2 {
+			req := NewSignatureRequest(r, signedHeaders, timestamp, nonce, signedBody)
+			return VerifySignatureV2(KeyVerifier(key), req, signature)
+		}
+		return verifyWithKey(key, r.Method, r.URL.Path, timestamp, nonce, signedBody, signature)
+	}
+	if opts.Keyring != nil {
+		key, err := opts.Keyring.Match(keyID, verify)
+		if err != nil {
+			return "", err
+		}
+		keyID = key.ID
+	} else if !verify(SigningKey{Secret: secret}) {
+		return "", ErrSignatureMismatch
+	}
+
+	// Only remember nonces of authentic requests, so forged ones cannot fill the store.
+	if opts.NonceStore != nil {
+		if err := CheckNonce(r.Context(), opts.NonceStore, keyID, nonce, signedAt.Add(opts.Timestamp.MaxAge)); err != nil {
+			return "", err
+		}
+	}
+	return keyID, nil
+}
+
+// RequireSignedHeaders parses a ";"-separated signed header list, as sent in
+// the signed headers header, and checks that it includes every required
+// header. It returns ErrInvalidSignature for malformed lists and
+// ErrUnsignedHeader when a required header is missing.
+func RequireSignedHeaders(list string, required []string) ([]string, error) {
+	signed, err := CanonicalHeaderNames(strings.Split(list, ";"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	for _, name := range required {
+		if !slices.Contains(signed, strings.ToLower(name)) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsignedHeader, name)
+		}
+	}
+	return signed, nil
+}
+
+// readRequestBody reads up to limit bytes of the request body and replaces
+// r.Body with an in-memory copy so it can be read again.
+func readRequestBody(r *http.Request, limit int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	defer r.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, ErrBodyTooLarge
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	return body, nil
+}
@@ -0,0 +1,277 @@
+package xgen
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSignedTestRequest builds a request signed with GenerateSignature using the default headers.
+func newSignedTestRequest(t *testing.T, secret, keyID, method, path, body string) *http.Request {
+	t.Helper()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	sig, err := GenerateSignature(secret, method, path, timestamp, body)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(DefaultSignatureHeader, sig)
+	req.Header.Set(DefaultSignatureTimestampHeader, timestamp)
+	if keyID != "" {
+		req.Header.Set(DefaultSignatureKeyIDHeader, keyID)
+	}
+	return req
+}
+
+// echoHandler writes the verified key ID and request body.
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	keyID, _ := SignatureKeyIDFromContext(r.Context())
+	body, _ := io.ReadAll(r.Body)
+	w.Write([]byte(keyID + ":" + string(body)))
+})
+
+func TestNewSignatureMiddleware(t *testing.T) {
+	secrets := map[string]string{"client-1": "secret-1", "client-2": "secret-2"}
+	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret: MapSecretLookup(secrets),
+	})
+	require.NoError(t, err)
+	handler := mw(echoHandler)
+
+	t.Run("valid request", func(t *testing.T) {
+		req := newSignedTestRequest(t, "secret-1", "client-1", "POST", "/api/users", `{"name":"John"}`)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `client-1:{"name":"John"}`, rec.Body.String())
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		req := newSignedTestRequest(t, "secret-2", "client-2", "GET", "/api/orders", "")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "client-2:", rec.Body.String())
+	})
+
+	t.Run("missing headers", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/orders", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		req := newSignedTestRequest(t, "secret-1", "client-1", "POST", "/api/users", `{"name":"John"}`)
+		req.Body = io.NopCloser(strings.NewReader(`{"name":"Jane"}`))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		req := newSignedTestRequest(t, "secret-1", "client-2", "POST", "/api/users", "x")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		req := newSignedTestRequest(t, "secret-1", "client-9", "POST", "/api/users", "x")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("expired timestamp", func(t *testing.T) {
+		timestamp := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+		sig, err := GenerateSignature("secret-1", "GET", "/api/orders", timestamp, "")
+		require.NoError(t, err)
+		req := httptest.NewRequest("GET", "/api/orders", nil)
+		req.Header.Set(DefaultSignatureHeader, sig)
+		req.Header.Set(DefaultSignatureTimestampHeader, timestamp)
+		req.Header.Set(DefaultSignatureKeyIDHeader, "client-1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+func TestNewSignatureMiddleware_Options(t *testing.T) {
+	t.Run("requires lookup", func(t *testing.T) {
+		_, err := NewSignatureMiddleware(SignatureMiddlewareOptions{})
+		assert.Error(t, err)
+	})
+
+	t.Run("custom headers", func(t *testing.T) {
+		mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+			LookupSecret:    StaticSecretLookup("secret"),
+			SignatureHeader: "X-Sig",
+			TimestampHeader: "X-Ts",
+			KeyIDHeader:     "X-Kid",
+		})
+		require.NoError(t, err)
+
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		sig, err := GenerateSignature("secret", "PUT", "/items/1", timestamp, "data")
+		require.NoError(t, err)
+		req := httptest.NewRequest("PUT", "/items/1", strings.NewReader("data"))
+		req.Header.Set("X-Sig", sig)
+		req.Header.Set("X-Ts", timestamp)
+		req.Header.Set("X-Kid", "any")
+
+		rec := httptest.NewRecorder()
+		mw(echoHandler).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "any:data", rec.Body.String())
+	})
+
+	t.Run("body size limit", func(t *testing.T) {
+		mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+			LookupSecret: StaticSecretLookup("secret"),
+			MaxBodySize:  4,
+		})
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		mw(echoHandler).ServeHTTP(rec, newSignedTestRequest(t, "secret", "", "POST", "/", "12345"))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+		rec = httptest.NewRecorder()
+		mw(echoHandler).ServeHTTP(rec, newSignedTestRequest(t, "secret", "", "POST", "/", "1234"))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("custom error handler", func(t *testing.T) {
+		var got error
+		mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+			LookupSecret: StaticSecretLookup("secret"),
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				got = err
+				w.WriteHeader(http.StatusForbidden)
+			},
+		})
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		mw(echoHandler).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.ErrorIs(t, got, ErrMissingSignature)
+	})
+
+	t.Run("lookup failure", func(t *testing.T) {
+		mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+			LookupSecret: func(context.Context, string) (string, error) {
+				return "", errors.New("database unavailable")
+			},
+		})
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		mw(echoHandler).ServeHTTP(rec, newSignedTestRequest(t, "secret", "k", "GET", "/", ""))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestNewSignatureMiddleware_Nonce(t *testing.T) {
+	store := NewMemoryNonceStore(MemoryNonceStoreOptions{})
+	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret: MapSecretLookup(map[string]string{"client-1": "secret-1", "client-2": "secret-2"}),
+		NonceStore:   store,
+	})
+	require.NoError(t, err)
+	handler := mw(echoHandler)
+
+	newNonceRequest := func(secret, keyID, nonce string) *http.Request {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		sig, err := GenerateSignatureWithNonce(secret, "POST", "/pay", timestamp, nonce, "amount=10")
+		require.NoError(t, err)
+		req := httptest.NewRequest("POST", "/pay", strings.NewReader("amount=10"))
+		req.Header.Set(DefaultSignatureHeader, sig)
+		req.Header.Set(DefaultSignatureTimestampHeader, timestamp)
+		req.Header.Set(DefaultSignatureKeyIDHeader, keyID)
+		req.Header.Set(DefaultSignatureNonceHeader, nonce)
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newNonceRequest("secret-1", "client-1", "n-1"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Replaying the same nonce is rejected.
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newNonceRequest("secret-1", "client-1", "n-1"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Nonces are scoped per key ID.
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newNonceRequest("secret-2", "client-2", "n-1"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Requests without a nonce are rejected when a store is configured.
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newSignedTestRequest(t, "secret-1", "client-1", "POST", "/pay", "amount=10"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Forged requests do not consume nonces.
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newNonceRequest("wrong", "client-1", "n-2"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newNonceRequest("secret-1", "client-1", "n-2"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// A request signed without a nonce cannot be replayed by moving the first
+	// body line into the nonce header.
+	req := newSignedTestRequest(t, "secret-1", "client-1", "POST", "/pay", "n-3\namount=10")
+	req.Body = io.NopCloser(strings.NewReader("amount=10"))
+	req.Header.Set(DefaultSignatureNonceHeader, "n-3")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestSignatureKeyIDFromContext(t *testing.T) {
+	_, ok := SignatureKeyIDFromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := ContextWithSignatureKeyID(context.Background(), "client-1")
+	keyID, ok := SignatureKeyIDFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "client-1", keyID)
+}
+
+func TestDefaultSignatureErrorHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"missing", ErrMissingSignature, http.StatusUnauthorized},
+		{"timestamp", ErrInvalidSignatureTimestamp, http.StatusUnauthorized},
+		{"signature", ErrInvalidSignature, http.StatusUnauthorized},
+		{"unknown key wrapped", errors.Join(ErrUnknownKey, errors.New("client-9")), http.StatusUnauthorized},
+		{"too large", ErrBodyTooLarge, http.StatusRequestEntityTooLarge},
+		{"missing nonce", ErrMissingNonce, http.StatusUnauthorized},
+		{"replayed nonce", ErrReplayedNonce, http.StatusUnauthorized},
+		{"nonce store full", ErrNonceStoreFull, http.StatusServiceUnavailable},
+		{"unsigned header", ErrUnsignedHeader, http.StatusUnauthorized},
+		{"other", errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			DefaultSignatureErrorHandler(rec, httptest.NewRequest("GET", "/", nil), tt.err)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package xgen
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultNonceStoreShards is the shard count used when MemoryNonceStoreOptions.Shards is zero.
+	defaultNonceStoreShards = 64
+	// defaultNonceStoreMaxEntries is the capacity used when MemoryNonceStoreOptions.MaxEntries is zero.
+	defaultNonceStoreMaxEntries = 1_000_000
+	// nonceSweepInterval is the minimum time between expiry sweeps of a shard.
+	nonceSweepInterval = 10 * time.Second
+)
+
+// ErrNonceStoreFull is returned when a MemoryNonceStore has no room for a new nonce.
+// Requests are rejected rather than accepted unchecked.
+var ErrNonceStoreFull = errors.New("nonce store is full")
+
+// NonceStore remembers used nonces to reject replayed requests.
+type NonceStore interface {
+	// CheckAndStore records nonce as used until expiresAt and reports whether
+	// it was unused. It must be atomic: when called concurrently with the same
+	// nonce, at most one call returns true.
+	CheckAndStore(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+}
+
+// CheckNonce records the nonce of a verified request in store, scoped to its
+// key ID, until expiresAt, when its timestamp leaves the accepted window. It
+// returns ErrReplayedNonce when the key ID has already used the nonce. Call it
+// only after the signature is verified, so forged requests cannot fill the
+// store.
+func CheckNonce(ctx context.Context, store NonceStore, keyID, nonce string, expiresAt time.Time) error {
+	// Length-prefix the key ID so "a" + "b:c" and "a:b" + "c" stay distinct.
+	scoped := strconv.Itoa(len(keyID)) + ":" + keyID + ":" + nonce
+	fresh, err := store.CheckAndStore(ctx, scoped, expiresAt)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrReplayedNonce
+	}
+	return nil
+}
+
+// MemoryNonceStoreOptions configures NewMemoryNonceStore.
+type MemoryNonceStoreOptions struct {
+	// MaxEntries bounds the number of remembered nonces. Defaults to 1,000,000.
+	MaxEntries int
+	// Shards is the number of independently locked shards. Defaults to 64.
+	Shards int
+}
+
+// MemoryNonceStore is an in-memory NonceStore for a single process.
+//
+// Nonces are spread over independently locked shards to reduce contention.
+// Expired nonces are removed lazily, and each shard holds at most its share
+// of MaxEntries: when a shard is full of unexpired nonces, CheckAndStore
+// returns ErrNonceStoreFull.
+type MemoryNonceStore struct {
+	shards []*nonceShard
+	now    func() time.Time
+}
+
+// nonceShard is one locked partition of a MemoryNonceStore.
+type nonceShard struct {
+	mu        sync.Mutex
+	entries   map[string]time.Time
+	max       int
+	nextSweep time.Time
+}
+
+// NewMemoryNonceStore creates an in-memory NonceStore.
+//
+// Example:
+//
+//	store := NewMemoryNonceStore(MemoryNonceStoreOptions{})
+//	fresh, err := store.CheckAndStore(ctx, nonce, time.Now().Add(5*time.Minute))
+func NewMemoryNonceStore(opts MemoryNonceStoreOptions) *MemoryNonceStore {
+	if opts.Shards <= 0 {
+		opts.Shards = defaultNonceStoreShards
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = defaultNonceStoreMaxEntries
+	}
+	perShard := max(opts.MaxEntries/opts.Shards, 1)
+	shards := make([]*nonceShard, opts.Shards)
+	for i := range shards {
+		shards[i] = &nonceShard{entries: make(map[string]time.Time), max: perShard}
+	}
+	return &MemoryNonceStore{shards: shards, now: time.Now}
+}
+
+// CheckAndStore implements NonceStore.
+func (s *MemoryNonceStore) CheckAndStore(_ context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	now := s.now()
+	shard := s.shard(nonce)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if exp, ok := shard.entries[nonce]; ok {
+		if now.Before(exp) {
+			return false, nil
+		}
+		delete(shard.entries, nonce)
+	}
+	if !now.Before(expiresAt) {
+		// Already expired; the timestamp check should have rejected it.
+		return false, nil
+	}
+	if len(shard.entries) >= shard.max || now.After(shard.nextSweep) {
+		shard.sweep(now)
+	}
+	if len(shard.entries) >= shard.max {
+		return false, ErrNonceStoreFull
+	}
+	shard.entries[nonce] = expiresAt
+	return true, nil
+}
+
+// Len returns the number of remembered nonces, including expired ones not yet swept.
+func (s *MemoryNonceStore) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		n += len(shard.entries)
+		shard.mu.Unlock()
+	}
+	return n
+}
+
+// shard returns the shard responsible for nonce.
+func (s *MemoryNonceStore) shard(nonce string) *nonceShard {
+	h := fnv.New32a()
+	h.Write([]byte(nonce))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// sweep removes expired nonces. The caller must hold the shard lock.
+func (sh *nonceShard) sweep(now time.Time) {
+	for nonce, exp := range sh.entries {
+		if !now.Before(exp) {
+			delete(sh.entries, nonce)
+		}
+	}
+	sh.nextSweep = now.Add(nonceSweepInterval)
+}
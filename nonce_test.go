@@ -0,0 +1,146 @@
+package xgen
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryNonceStore_CheckAndStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryNonceStore(MemoryNonceStoreOptions{})
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+
+	fresh, err := store.CheckAndStore(ctx, "nonce-1", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, fresh)
+
+	// Duplicate within the window
+	fresh, err = store.CheckAndStore(ctx, "nonce-1", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, fresh)
+
+	// Different nonce
+	fresh, err = store.CheckAndStore(ctx, "nonce-2", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, fresh)
+
+	// Already expired nonce is rejected without being stored
+	fresh, err = store.CheckAndStore(ctx, "nonce-3", now)
+	require.NoError(t, err)
+	assert.False(t, fresh)
+	assert.Equal(t, 2, store.Len())
+
+	// After expiry the nonce is forgotten
+	now = now.Add(2 * time.Minute)
+	fresh, err = store.CheckAndStore(ctx, "nonce-1", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, fresh)
+}
+
+func TestCheckNonce(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryNonceStore(MemoryNonceStoreOptions{})
+	expiresAt := time.Now().Add(time.Minute)
+
+	require.NoError(t, CheckNonce(ctx, store, "a", "b:c", expiresAt))
+	assert.ErrorIs(t, CheckNonce(ctx, store, "a", "b:c", expiresAt), ErrReplayedNonce)
+	// Nonces are scoped to the key ID without ambiguity.
+	assert.NoError(t, CheckNonce(ctx, store, "a:b", "c", expiresAt))
+	assert.NoError(t, CheckNonce(ctx, store, "b", "b:c", expiresAt))
+}
+
+func TestMemoryNonceStore_Bounded(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryNonceStore(MemoryNonceStoreOptions{MaxEntries: 10, Shards: 1})
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		fresh, err := store.CheckAndStore(ctx, strconv.Itoa(i), now.Add(time.Minute))
+		require.NoError(t, err)
+		require.True(t, fresh)
+	}
+
+	// Full of unexpired nonces: fail closed.
+	_, err := store.CheckAndStore(ctx, "overflow", now.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrNonceStoreFull)
+	assert.Equal(t, 10, store.Len())
+
+	// Once entries expire, the sweep makes room again.
+	now = now.Add(2 * time.Minute)
+	fresh, err := store.CheckAndStore(ctx, "overflow", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, fresh)
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestMemoryNonceStore_PeriodicSweep(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryNonceStore(MemoryNonceStoreOptions{Shards: 1})
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		_, err := store.CheckAndStore(ctx, strconv.Itoa(i), now.Add(time.Second))
+		require.NoError(t, err)
+	}
+	now = now.Add(nonceSweepInterval + time.Second)
+	_, err := store.CheckAndStore(ctx, "new", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestMemoryNonceStore_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryNonceStore(MemoryNonceStoreOptions{})
+	expires := time.Now().Add(time.Minute)
+
+	// Many goroutines race to use the same nonces; each must be accepted exactly once.
+	const nonces, workers = 100, 8
+	var accepted atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < nonces; i++ {
+				fresh, err := store.CheckAndStore(ctx, strconv.Itoa(i), expires)
+				if err == nil && fresh {
+					accepted.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(nonces), accepted.Load())
+}
+
+func TestNewMemoryNonceStore_Defaults(t *testing.T) {
+	store := NewMemoryNonceStore(MemoryNonceStoreOptions{})
+	assert.Len(t, store.shards, defaultNonceStoreShards)
+	assert.Equal(t, defaultNonceStoreMaxEntries/defaultNonceStoreShards, store.shards[0].max)
+
+	// More shards than entries still allows one entry per shard.
+	store = NewMemoryNonceStore(MemoryNonceStoreOptions{MaxEntries: 2, Shards: 4})
+	assert.Equal(t, 1, store.shards[0].max)
+}
+
+func BenchmarkMemoryNonceStore(b *testing.B) {
+	ctx := context.Background()
+	store := NewMemoryNonceStore(MemoryNonceStoreOptions{})
+	expires := time.Now().Add(time.Minute)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			nonce, _ := GenerateSignatureNonce()
+			store.CheckAndStore(ctx, nonce, expires)
+		}
+	})
+}
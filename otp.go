@@ -0,0 +1,326 @@
+package xgen
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OTPAlgorithm is the HMAC hash function used for one-time passwords.
+type OTPAlgorithm string
+
+// Supported OTP algorithms, named as in otpauth:// URIs.
+const (
+	OTPAlgorithmSHA1   OTPAlgorithm = "SHA1"
+	OTPAlgorithmSHA256 OTPAlgorithm = "SHA256"
+	OTPAlgorithmSHA512 OTPAlgorithm = "SHA512"
+)
+
+const (
+	// defaultOTPDigits is the code length used when OTPOptions.Digits is zero.
+	defaultOTPDigits = 6
+	// defaultOTPPeriod is the TOTP time step used when OTPOptions.Period is zero.
+	defaultOTPPeriod = 30 * time.Second
+	// defaultOTPSecretSize is the secret size (160 bits) recommended by RFC 4226.
+	defaultOTPSecretSize = 20
+)
+
+// otpBase32 is the unpadded RFC 4648 Base32 encoding used by authenticator apps.
+var otpBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// OTPOptions configures HOTP and TOTP generation and verification.
+// Zero Digits, Algorithm and Period fall back to the defaults used by most
+// authenticator apps; a zero Skew accepts no clock skew.
+type OTPOptions struct {
+	// Digits is the code length, between 6 and 10. Defaults to 6.
+	Digits int
+	// Algorithm is the HMAC hash function. Defaults to OTPAlgorithmSHA1.
+	Algorithm OTPAlgorithm
+	// Period is the TOTP time step. Defaults to 30 seconds. Ignored by HOTP.
+	Period time.Duration
+	// Skew is the verification window: for TOTP the number of time steps
+	// accepted before and after the current one, for HOTP the number of
+	// counters accepted after the expected one. Defaults to 0, which accepts
+	// only the current step or expected counter; DefaultOTPOptions uses 1.
+	Skew uint
+}
+
+// DefaultOTPOptions returns the options used by most authenticator apps:
+// 6 digits, SHA1, a 30 second period and one step of clock skew.
+func DefaultOTPOptions() OTPOptions {
+	return OTPOptions{
+		Digits:    defaultOTPDigits,
+		Algorithm: OTPAlgorithmSHA1,
+		Period:    defaultOTPPeriod,
+		Skew:      1,
+	}
+}
+
+// GenerateOTPSecret generates a random OTP secret of size bytes, encoded as
+// unpadded Base32 for use with authenticator apps.
+// A size <= 0 uses 20 bytes (160 bits) as recommended by RFC 4226.
+func GenerateOTPSecret(size int) (string, error) {
+	if size <= 0 {
+		size = defaultOTPSecretSize
+	}
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return otpBase32.EncodeToString(b), nil
+}
+
+// GenerateHOTP generates an RFC 4226 HMAC-based one-time password for the
+// given Base32 secret and counter.
+//
+// Example:
+//
+//	code, err := GenerateHOTP(secret, 0, DefaultOTPOptions())
+//	// code = "755224"
+func GenerateHOTP(secret string, counter uint64, opts OTPOptions) (string, error) {
+	key, err := decodeOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	opts, err = opts.normalize()
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, counter, opts), nil
+}
+
+// VerifyHOTP checks an HOTP code against the expected counter and up to
+// opts.Skew counters after it, using constant-time comparison.
+//
+// On success it returns the counter to store for the next verification
+// (matched counter + 1), which also prevents the same code being replayed.
+func VerifyHOTP(secret, code string, counter uint64, opts OTPOptions) (uint64, bool) {
+	key, err := decodeOTPSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+	opts, err = opts.normalize()
+	if err != nil || len(code) != opts.Digits {
+		return 0, false
+	}
+	for i := uint64(0); i <= uint64(opts.Skew); i++ {
+		c := counter + i
+		if c < counter {
+			break // counter overflow
+		}
+		if subtle.ConstantTimeCompare([]byte(hotp(key, c, opts)), []byte(code)) == 1 {
+			return c + 1, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateTOTP generates an RFC 6238 time-based one-time password for the
+// given Base32 secret at time t.
+//
+// Example:
+//
+//	code, err := GenerateTOTP(secret, time.Now(), DefaultOTPOptions())
+func GenerateTOTP(secret string, t time.Time, opts OTPOptions) (string, error) {
+	key, err := decodeOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	opts, err = opts.normalize()
+	if err != nil {
+		return "", err
+	}
+	step, err := totpStep(t, opts.Period)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, step, opts), nil
+}
+
+// VerifyTOTP checks a TOTP code at time t, accepting opts.Skew time steps on
+// either side to tolerate clock drift. On success it returns the matched time step.
+func VerifyTOTP(secret, code string, t time.Time, opts OTPOptions) (uint64, bool) {
+	return VerifyTOTPAfter(secret, code, t, 0, opts)
+}
+
+// VerifyTOTPAfter is like VerifyTOTP but only accepts time steps greater than
+// lastUsedStep, so each code can be used at most once. Persist the returned
+// step per account and pass it back on the next verification; use 0 when no
+// code has been accepted yet.
+func VerifyTOTPAfter(secret, code string, t time.Time, lastUsedStep uint64, opts OTPOptions) (uint64, bool) {
+	key, err := decodeOTPSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+	opts, err = opts.normalize()
+	if err != nil || len(code) != opts.Digits {
+		return 0, false
+	}
+	current, err := totpStep(t, opts.Period)
+	if err != nil {
+		return 0, false
+	}
+	skew := uint64(opts.Skew)
+	first := uint64(0)
+	if current > skew {
+		first = current - skew
+	}
+	for step := first; step <= current+skew; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step, opts)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// BuildTOTPURI builds an otpauth://totp provisioning URI, typically rendered
+// as a QR code for authenticator apps.
+//
+// Example:
+//
+//	uri := BuildTOTPURI(secret, "Acme", "alice@example.com", DefaultOTPOptions())
+//	// uri = "otpauth://totp/Acme:alice@example.com?algorithm=SHA1&digits=6&issuer=Acme&period=30&secret=..."
+func BuildTOTPURI(secret, issuer, account string, opts OTPOptions) string {
+	opts = opts.withDefaults()
+	params := otpURIParams(secret, issuer, opts)
+	params.Set("period", strconv.FormatInt(int64(opts.Period/time.Second), 10))
+	return buildOTPURI("totp", issuer, account, params)
+}
+
+// BuildHOTPURI builds an otpauth://hotp provisioning URI with the initial counter.
+func BuildHOTPURI(secret, issuer, account string, counter uint64, opts OTPOptions) string {
+	opts = opts.withDefaults()
+	params := otpURIParams(secret, issuer, opts)
+	params.Set("counter", strconv.FormatUint(counter, 10))
+	return buildOTPURI("hotp", issuer, account, params)
+}
+
+// otpURIParams returns the query parameters shared by HOTP and TOTP URIs.
+func otpURIParams(secret, issuer string, opts OTPOptions) url.Values {
+	params := url.Values{}
+	params.Set("secret", normalizeOTPSecret(secret))
+	if issuer != "" {
+		params.Set("issuer", issuer)
+	}
+	params.Set("algorithm", string(opts.Algorithm))
+	params.Set("digits", strconv.Itoa(opts.Digits))
+	return params
+}
+
+// buildOTPURI assembles an otpauth URI with an "issuer:account" label.
+func buildOTPURI(kind, issuer, account string, params url.Values) string {
+	label := account
+	if issuer != "" {
+		label = issuer + ":" + account
+	}
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     kind,
+		Path:     "/" + label,
+		RawQuery: strings.ReplaceAll(params.Encode(), "+", "%20"),
+	}
+	return u.String()
+}
+
+// hotp computes the RFC 4226 dynamic truncation of HMAC(key, counter).
+func hotp(key []byte, counter uint64, opts OTPOptions) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(opts.Algorithm.hash(), key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := uint64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+	mod := uint64(1)
+	for range opts.Digits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", opts.Digits, value%mod)
+}
+
+// totpStep returns the RFC 6238 time step counter for t.
+func totpStep(t time.Time, period time.Duration) (uint64, error) {
+	unix := t.Unix()
+	if unix < 0 {
+		return 0, errors.New("TOTP time must not be before the Unix epoch")
+	}
+	return uint64(unix) / uint64(period/time.Second), nil
+}
+
+// decodeOTPSecret decodes a Base32 secret, tolerating lowercase letters,
+// spaces and padding as commonly typed by users.
+func decodeOTPSecret(secret string) ([]byte, error) {
+	normalized := normalizeOTPSecret(secret)
+	if normalized == "" {
+		return nil, errors.New("OTP secret must not be empty")
+	}
+	key, err := otpBase32.DecodeString(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Base32 OTP secret: %w", err)
+	}
+	return key, nil
+}
+
+// normalizeOTPSecret upper-cases a Base32 secret and strips spaces, dashes and padding.
+func normalizeOTPSecret(secret string) string {
+	secret = strings.ToUpper(secret)
+	return strings.NewReplacer(" ", "", "-", "", "=", "").Replace(secret)
+}
+
+// withDefaults fills zero-valued options with their defaults.
+func (o OTPOptions) withDefaults() OTPOptions {
+	if o.Digits == 0 {
+		o.Digits = defaultOTPDigits
+	}
+	if o.Algorithm == "" {
+		o.Algorithm = OTPAlgorithmSHA1
+	}
+	if o.Period == 0 {
+		o.Period = defaultOTPPeriod
+	}
+	return o
+}
+
+// normalize applies defaults and validates the options.
+func (o OTPOptions) normalize() (OTPOptions, error) {
+	o = o.withDefaults()
+	if o.Digits < 6 || o.Digits > 10 {
+		return o, fmt.Errorf("OTP digits must be between 6 and 10, got %d", o.Digits)
+	}
+	if o.Algorithm.hash() == nil {
+		return o, fmt.Errorf("unsupported OTP algorithm %q", o.Algorithm)
+	}
+	if o.Period < time.Second || o.Period%time.Second != 0 {
+		return o, fmt.Errorf("OTP period must be a positive whole number of seconds, got %s", o.Period)
+	}
+	return o, nil
+}
+
+// hash returns the hash constructor for the algorithm, or nil if unsupported.
+func (a OTPAlgorithm) hash() func() hash.Hash {
+	switch a {
+	case OTPAlgorithmSHA1:
+		return sha1.New
+	case OTPAlgorithmSHA256:
+		return sha256.New
+	case OTPAlgorithmSHA512:
+		return sha512.New
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package xgen
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 4226 Appendix D and RFC 6238 Appendix B test secrets, Base32 encoded.
+var (
+	rfcSecretSHA1   = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	rfcSecretSHA256 = base32.StdEncoding.EncodeToString([]byte("12345678901234567890123456789012"))
+	rfcSecretSHA512 = base32.StdEncoding.EncodeToString([]byte("1234567890123456789012345678901234567890123456789012345678901234"))
+)
+
+func TestGenerateHOTP_RFC4226Vectors(t *testing.T) {
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range want {
+		got, err := GenerateHOTP(rfcSecretSHA1, uint64(counter), DefaultOTPOptions())
+		require.NoError(t, err)
+		assert.Equal(t, code, got, "counter %d", counter)
+	}
+}
+
+func TestGenerateTOTP_RFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix   int64
+		sha1   string
+		sha256 string
+		sha512 string
+	}{
+		{59, "94287082", "46119246", "90693936"},
+		{1111111109, "07081804", "68084774", "25091201"},
+		{1111111111, "14050471", "67062674", "99943326"},
+		{1234567890, "89005924", "91819424", "93441116"},
+		{2000000000, "69279037", "90698825", "38618901"},
+		{20000000000, "65353130", "77737706", "47863826"},
+	}
+
+	for _, tt := range tests {
+		ts := time.Unix(tt.unix, 0)
+		for _, c := range []struct {
+			secret string
+			alg    OTPAlgorithm
+			want   string
+		}{
+			{rfcSecretSHA1, OTPAlgorithmSHA1, tt.sha1},
+			{rfcSecretSHA256, OTPAlgorithmSHA256, tt.sha256},
+			{rfcSecretSHA512, OTPAlgorithmSHA512, tt.sha512},
+		} {
+			got, err := GenerateTOTP(c.secret, ts, OTPOptions{Digits: 8, Algorithm: c.alg})
+			require.NoError(t, err)
+			assert.Equal(t, c.want, got, "time %d, %s", tt.unix, c.alg)
+		}
+	}
+}
+
+func TestGenerateHOTP_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		opts   OTPOptions
+	}{
+		{"empty secret", "", DefaultOTPOptions()},
+		{"invalid base32", "not*base32", DefaultOTPOptions()},
+		{"too few digits", rfcSecretSHA1, OTPOptions{Digits: 4}},
+		{"too many digits", rfcSecretSHA1, OTPOptions{Digits: 11}},
+		{"unknown algorithm", rfcSecretSHA1, OTPOptions{Algorithm: "MD5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GenerateHOTP(tt.secret, 0, tt.opts)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGenerateTOTP_InvalidInput(t *testing.T) {
+	_, err := GenerateTOTP(rfcSecretSHA1, time.Unix(-1, 0), DefaultOTPOptions())
+	assert.Error(t, err)
+
+	_, err = GenerateTOTP(rfcSecretSHA1, time.Now(), OTPOptions{Period: 1500 * time.Millisecond})
+	assert.Error(t, err)
+}
+
+func TestDecodeOTPSecret_Normalization(t *testing.T) {
+	secret, err := GenerateOTPSecret(0)
+	require.NoError(t, err)
+
+	want, err := GenerateHOTP(secret, 1, DefaultOTPOptions())
+	require.NoError(t, err)
+
+	// Lowercase, grouped with spaces and padded secrets decode to the same key.
+	var groups []string
+	for i := 0; i < len(secret); i += 4 {
+		groups = append(groups, strings.ToLower(secret[i:min(i+4, len(secret))]))
+	}
+	got, err := GenerateHOTP(strings.Join(groups, " ")+"====", 1, DefaultOTPOptions())
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestVerifyHOTP(t *testing.T) {
+	opts := DefaultOTPOptions()
+	opts.Skew = 2
+
+	code, err := GenerateHOTP(rfcSecretSHA1, 5, opts)
+	require.NoError(t, err)
+
+	// Expected counter
+	next, ok := VerifyHOTP(rfcSecretSHA1, code, 5, opts)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(6), next)
+
+	// Within look-ahead window
+	next, ok = VerifyHOTP(rfcSecretSHA1, code, 3, opts)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(6), next)
+
+	// Replay: counter already advanced past the code
+	_, ok = VerifyHOTP(rfcSecretSHA1, code, 6, opts)
+	assert.False(t, ok)
+
+	// Outside look-ahead window
+	_, ok = VerifyHOTP(rfcSecretSHA1, code, 2, opts)
+	assert.False(t, ok)
+
+	// Wrong length, wrong secret
+	_, ok = VerifyHOTP(rfcSecretSHA1, code[:5], 5, opts)
+	assert.False(t, ok)
+	_, ok = VerifyHOTP(rfcSecretSHA256, code, 5, opts)
+	assert.False(t, ok)
+	_, ok = VerifyHOTP("", code, 5, opts)
+	assert.False(t, ok)
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	opts := DefaultOTPOptions()
+	now := time.Unix(1700000000, 0)
+
+	code, err := GenerateTOTP(rfcSecretSHA1, now, opts)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		wantOK bool
+	}{
+		{"same time", now, true},
+		{"one step later", now.Add(30 * time.Second), true},
+		{"one step earlier", now.Add(-30 * time.Second), true},
+		{"two steps later", now.Add(60 * time.Second), false},
+		{"two steps earlier", now.Add(-60 * time.Second), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := VerifyTOTP(rfcSecretSHA1, code, tt.at, opts)
+			assert.Equal(t, tt.wantOK, ok)
+			if ok {
+				assert.Equal(t, uint64(now.Unix()/30), step)
+			}
+		})
+	}
+
+	t.Run("no skew", func(t *testing.T) {
+		strict := opts
+		strict.Skew = 0
+		_, ok := VerifyTOTP(rfcSecretSHA1, code, now.Add(30*time.Second), strict)
+		assert.False(t, ok)
+	})
+
+	t.Run("zero options accept no skew", func(t *testing.T) {
+		_, ok := VerifyTOTP(rfcSecretSHA1, code, now, OTPOptions{})
+		assert.True(t, ok)
+		_, ok = VerifyTOTP(rfcSecretSHA1, code, now.Add(30*time.Second), OTPOptions{})
+		assert.False(t, ok)
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		_, ok := VerifyTOTP(rfcSecretSHA1, "000000", now.Add(10*time.Hour), opts)
+		assert.False(t, ok)
+	})
+}
+
+func TestVerifyTOTPAfter(t *testing.T) {
+	opts := DefaultOTPOptions()
+	now := time.Unix(1700000000, 0)
+
+	code, err := GenerateTOTP(rfcSecretSHA1, now, opts)
+	require.NoError(t, err)
+
+	step, ok := VerifyTOTPAfter(rfcSecretSHA1, code, now, 0, opts)
+	require.True(t, ok)
+
+	// The same code cannot be used twice.
+	_, ok = VerifyTOTPAfter(rfcSecretSHA1, code, now, step, opts)
+	assert.False(t, ok)
+
+	// The next code is still accepted.
+	nextCode, err := GenerateTOTP(rfcSecretSHA1, now.Add(30*time.Second), opts)
+	require.NoError(t, err)
+	nextStep, ok := VerifyTOTPAfter(rfcSecretSHA1, nextCode, now.Add(30*time.Second), step, opts)
+	assert.True(t, ok)
+	assert.Equal(t, step+1, nextStep)
+}
+
+func TestGenerateOTPSecret(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		wantSize int
+	}{
+		{"default", 0, 20},
+		{"negative uses default", -1, 20},
+		{"sha256 size", 32, 32},
+		{"sha512 size", 64, 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, err := GenerateOTPSecret(tt.size)
+			require.NoError(t, err)
+			key, err := decodeOTPSecret(secret)
+			require.NoError(t, err)
+			assert.Len(t, key, tt.wantSize)
+			assert.NotContains(t, secret, "=")
+		})
+	}
+}
+
+func TestBuildTOTPURI(t *testing.T) {
+	uri := BuildTOTPURI("jbsw y3dp", "Acme Co", "alice@example.com", OTPOptions{Digits: 8, Algorithm: OTPAlgorithmSHA256, Period: 60 * time.Second})
+	assert.Equal(t, "otpauth://totp/Acme%20Co:alice@example.com?algorithm=SHA256&digits=8&issuer=Acme%20Co&period=60&secret=JBSWY3DP", uri)
+
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Acme Co:alice@example.com", u.Path)
+	assert.Equal(t, "Acme Co", u.Query().Get("issuer"))
+}
+
+func TestBuildHOTPURI(t *testing.T) {
+	uri := BuildHOTPURI("JBSWY3DP", "", "bob", 7, OTPOptions{})
+	assert.Equal(t, "otpauth://hotp/bob?algorithm=SHA1&counter=7&digits=6&secret=JBSWY3DP", uri)
+}
+
+func BenchmarkGenerateTOTP(b *testing.B) {
+	opts := DefaultOTPOptions()
+	now := time.Now()
+	for i := 0; i < b.N; i++ {
+		GenerateTOTP(rfcSecretSHA1, now, opts)
+	}
+}
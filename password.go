@@ -0,0 +1,394 @@
+package xgen
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Character classes used by GeneratePassword.
+const (
+	PasswordLowercase = "abcdefghijklmnopqrstuvwxyz"
+	PasswordUppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	PasswordDigits    = "0123456789"
+	PasswordSymbols   = "!@#$%^&*()-_=+[]{}<>?"
+	// PasswordAmbiguous lists look-alike characters removed by ExcludeAmbiguous.
+	PasswordAmbiguous = "0Oo1Il|"
+)
+
+// defaultPasswordLength is the password length used by DefaultPasswordPolicy.
+const defaultPasswordLength = 16
+
+// defaultPassphraseWords is the number of words used by DefaultPassphraseOptions.
+// Six words from the EFF large wordlist give about 77.5 bits of entropy.
+const defaultPassphraseWords = 6
+
+// effLargeWordlistRaw is the EFF large diceware wordlist (7776 words, CC BY 3.0 US),
+// see https://www.eff.org/dice.
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlistRaw string
+
+// effLargeWordlist returns the parsed EFF large wordlist.
+var effLargeWordlist = sync.OnceValue(func() []string {
+	return strings.Fields(effLargeWordlistRaw)
+})
+
+// PasswordPolicy describes the characters and length of a generated password.
+//
+// Every enabled character class is also required: the generated password
+// contains at least one character from each of them.
+type PasswordPolicy struct {
+	// Length is the number of characters in the password.
+	Length int
+	// Lowercase enables the a-z class.
+	Lowercase bool
+	// Uppercase enables the A-Z class.
+	Uppercase bool
+	// Digits enables the 0-9 class.
+	Digits bool
+	// Symbols enables the symbol class (PasswordSymbols unless SymbolSet is set).
+	Symbols bool
+	// SymbolSet overrides the characters of the symbol class.
+	SymbolSet string
+	// ExcludeAmbiguous removes the characters in PasswordAmbiguous from every class.
+	ExcludeAmbiguous bool
+	// ExcludeChars removes additional characters from every class.
+	ExcludeChars string
+	// Alphabet, when set, replaces the built-in classes with a single custom class.
+	Alphabet string
+}
+
+// DefaultPasswordPolicy returns a 16 character policy that requires
+// lowercase, uppercase, digit and symbol characters.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		Length:    defaultPasswordLength,
+		Lowercase: true,
+		Uppercase: true,
+		Digits:    true,
+		Symbols:   true,
+	}
+}
+
+// GeneratePassword generates a random password that satisfies the given policy.
+//
+// Characters are picked with crypto/rand using rejection sampling, so every
+// character of the alphabet is equally likely. Returns an error if the policy
+// leaves no usable characters or is too short to hold every required class.
+//
+// Example:
+//
+//	pw, err := GeneratePassword(PasswordPolicy{Length: 12, Lowercase: true, Digits: true})
+//	// pw = "k3q9vx0m2tza"
+func GeneratePassword(policy PasswordPolicy) (string, error) {
+	classes, err := policy.classes()
+	if err != nil {
+		return "", err
+	}
+	if policy.Length < len(classes) {
+		return "", fmt.Errorf("password length %d is too short for %d required character classes", policy.Length, len(classes))
+	}
+
+	var all []rune
+	for _, class := range classes {
+		all = append(all, class...)
+	}
+
+	result := make([]rune, 0, policy.Length)
+	// One character from every required class first...
+	for _, class := range classes {
+		r, err := randomRune(class)
+		if err != nil {
+			return "", err
+		}
+		result = append(result, r)
+	}
+	// ...then fill the rest from the combined alphabet.
+	for len(result) < policy.Length {
+		r, err := randomRune(all)
+		if err != nil {
+			return "", err
+		}
+		result = append(result, r)
+	}
+	// Shuffle so required characters do not sit at predictable positions.
+	if err := RandomShuffle(result); err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+// GeneratePasswordDefault generates a password using DefaultPasswordPolicy.
+func GeneratePasswordDefault() (string, error) {
+	return GeneratePassword(DefaultPasswordPolicy())
+}
+
+// classes returns the de-duplicated character classes enabled by the policy.
+func (p PasswordPolicy) classes() ([][]rune, error) {
+	if p.Length <= 0 {
+		return nil, errors.New("password length must be positive")
+	}
+	var sets []string
+	if p.Alphabet != "" {
+		sets = []string{p.Alphabet}
+	} else {
+		if p.Lowercase {
+			sets = append(sets, PasswordLowercase)
+		}
+		if p.Uppercase {
+			sets = append(sets, PasswordUppercase)
+		}
+		if p.Digits {
+			sets = append(sets, PasswordDigits)
+		}
+		if p.Symbols {
+			symbols := p.SymbolSet
+			if symbols == "" {
+				symbols = PasswordSymbols
+			}
+			sets = append(sets, symbols)
+		}
+	}
+	if len(sets) == 0 {
+		return nil, errors.New("password policy enables no character classes")
+	}
+
+	excluded := p.ExcludeChars
+	if p.ExcludeAmbiguous {
+		excluded += PasswordAmbiguous
+	}
+	seen := make(map[rune]bool)
+	classes := make([][]rune, 0, len(sets))
+	for _, set := range sets {
+		if !utf8.ValidString(set) {
+			return nil, errors.New("password alphabet must be valid UTF-8")
+		}
+		var class []rune
+		for _, r := range set {
+			if seen[r] || strings.ContainsRune(excluded, r) {
+				continue
+			}
+			seen[r] = true
+			class = append(class, r)
+		}
+		if len(class) == 0 {
+			return nil, errors.New("password policy leaves an empty character class")
+		}
+		classes = append(classes, class)
+	}
+	return classes, nil
+}
+
+// PassphraseCase controls the capitalization of passphrase words.
+type PassphraseCase int
+
+const (
+	// PassphraseLower keeps every word in lowercase.
+	PassphraseLower PassphraseCase = iota
+	// PassphraseTitle capitalizes the first letter of every word.
+	PassphraseTitle
+	// PassphraseUpper converts every word to uppercase.
+	PassphraseUpper
+	// PassphraseRandomTitle capitalizes the first letter of each word with
+	// probability 1/2, adding up to one bit of entropy per word: words whose
+	// first character has no upper case add nothing.
+	PassphraseRandomTitle
+)
+
+// PassphraseOptions configures GeneratePassphrase.
+type PassphraseOptions struct {
+	// Words is the number of words in the passphrase.
+	Words int
+	// Separator is placed between words. Words containing it, such as the
+	// hyphenated EFF words with "-", are never drawn, so the passphrase splits
+	// back into its words.
+	Separator string
+	// Case controls word capitalization.
+	Case PassphraseCase
+	// Wordlist replaces the embedded EFF large wordlist. Words must be unique.
+	Wordlist []string
+}
+
+// Passphrase is a generated passphrase together with its strength.
+type Passphrase struct {
+	// Value is the passphrase text.
+	Value string
+	// EntropyBits is the entropy of the passphrase assuming the attacker
+	// knows the wordlist, word count, separator and case option.
+	EntropyBits float64
+}
+
+// String returns the passphrase text.
+func (p Passphrase) String() string {
+	return p.Value
+}
+
+// DefaultPassphraseOptions returns options for a six word, dash separated,
+// lowercase passphrase drawn from the EFF large wordlist.
+func DefaultPassphraseOptions() PassphraseOptions {
+	return PassphraseOptions{
+		Words:     defaultPassphraseWords,
+		Separator: "-",
+		Case:      PassphraseLower,
+	}
+}
+
+// GeneratePassphrase generates a diceware-style passphrase.
+//
+// Words are picked uniformly at random from the wordlist with crypto/rand.
+// The returned Passphrase reports the entropy in bits of the words as they
+// appear: Words × log2 of the number of usable words, plus up to one bit per
+// word for PassphraseRandomTitle, less when words look the same after the
+// case is applied.
+//
+// Example:
+//
+//	p, err := GeneratePassphrase(PassphraseOptions{Words: 4, Separator: " "})
+//	// p.Value = "mulled cider yodel ravine", p.EntropyBits ≈ 51.7
+func GeneratePassphrase(opts PassphraseOptions) (Passphrase, error) {
+	if opts.Words <= 0 {
+		return Passphrase{}, errors.New("passphrase word count must be positive")
+	}
+	wordlist := opts.Wordlist
+	if wordlist == nil {
+		wordlist = effLargeWordlist()
+	} else if err := validateWordlist(wordlist); err != nil {
+		return Passphrase{}, err
+	}
+	wordlist = wordsWithoutSeparator(wordlist, opts.Separator, opts.Case)
+	if len(wordlist) < 2 {
+		return Passphrase{}, fmt.Errorf("passphrase wordlist must contain at least two words without the separator %q", opts.Separator)
+	}
+
+	words := make([]string, opts.Words)
+	for i := range words {
+		idx, err := randomIndex(len(wordlist))
+		if err != nil {
+			return Passphrase{}, err
+		}
+		forms := passphraseForms(wordlist[idx], opts.Case)
+		words[i] = forms[0]
+		if len(forms) > 1 {
+			flip, err := randomIndex(len(forms))
+			if err != nil {
+				return Passphrase{}, err
+			}
+			words[i] = forms[flip]
+		}
+	}
+
+	return Passphrase{
+		Value:       strings.Join(words, opts.Separator),
+		EntropyBits: passphraseWordEntropy(wordlist, opts.Case) * float64(opts.Words),
+	}, nil
+}
+
+// passphraseForms returns the equally likely forms word takes in a passphrase
+// with case c.
+func passphraseForms(word string, c PassphraseCase) []string {
+	switch c {
+	case PassphraseTitle:
+		return []string{capitalizeWord(word)}
+	case PassphraseUpper:
+		return []string{strings.ToUpper(word)}
+	case PassphraseRandomTitle:
+		return []string{word, capitalizeWord(word)}
+	}
+	return []string{word}
+}
+
+// wordsWithoutSeparator returns the words of wordlist that have no form
+// containing separator. wordlist is returned as is when every word qualifies.
+func wordsWithoutSeparator(wordlist []string, separator string, c PassphraseCase) []string {
+	if separator == "" {
+		return wordlist
+	}
+	usable := func(word string) bool {
+		for _, form := range passphraseForms(word, c) {
+			if strings.Contains(form, separator) {
+				return false
+			}
+		}
+		return true
+	}
+	for i, word := range wordlist {
+		if usable(word) {
+			continue
+		}
+		kept := append([]string(nil), wordlist[:i]...)
+		for _, word := range wordlist[i+1:] {
+			if usable(word) {
+				kept = append(kept, word)
+			}
+		}
+		return kept
+	}
+	return wordlist
+}
+
+// passphraseWordEntropy returns the Shannon entropy in bits of one passphrase
+// word drawn uniformly from wordlist with case c. Forms that several draws
+// produce, such as a word capitalization leaves unchanged, count once.
+func passphraseWordEntropy(wordlist []string, c PassphraseCase) float64 {
+	weights := make(map[string]float64, 2*len(wordlist))
+	for _, word := range wordlist {
+		forms := passphraseForms(word, c)
+		for _, form := range forms {
+			weights[form] += 1 / float64(len(forms))
+		}
+	}
+	n := float64(len(wordlist))
+	bits := 0.0
+	for _, w := range weights {
+		p := w / n
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
+
+// GeneratePassphraseDefault generates a passphrase using DefaultPassphraseOptions.
+func GeneratePassphraseDefault() (Passphrase, error) {
+	return GeneratePassphrase(DefaultPassphraseOptions())
+}
+
+// validateWordlist checks that a custom wordlist has at least two unique, non-empty words.
+func validateWordlist(wordlist []string) error {
+	if len(wordlist) < 2 {
+		return errors.New("passphrase wordlist must contain at least two words")
+	}
+	seen := make(map[string]bool, len(wordlist))
+	for _, w := range wordlist {
+		if w == "" {
+			return errors.New("passphrase wordlist must not contain empty words")
+		}
+		if seen[w] {
+			return fmt.Errorf("passphrase wordlist contains duplicate word %q", w)
+		}
+		seen[w] = true
+	}
+	return nil
+}
+
+// capitalizeWord upper-cases the first letter of word.
+func capitalizeWord(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	if r == utf8.RuneError {
+		return word
+	}
+	return string(unicode.ToUpper(r)) + word[size:]
+}
+
+// randomRune returns a uniformly random rune from set.
+func randomRune(set []rune) (rune, error) {
+	idx, err := randomIndex(len(set))
+	if err != nil {
+		return 0, err
+	}
+	return set[idx], nil
+}
@@ -0,0 +1,181 @@
+package xgen
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  PasswordPolicy
+		wantLen int
+		wantErr bool
+	}{
+		{"default policy", DefaultPasswordPolicy(), 16, false},
+		{"lowercase only", PasswordPolicy{Length: 10, Lowercase: true}, 10, false},
+		{"all classes minimum length", PasswordPolicy{Length: 4, Lowercase: true, Uppercase: true, Digits: true, Symbols: true}, 4, false},
+		{"custom alphabet", PasswordPolicy{Length: 8, Alphabet: "ab"}, 8, false},
+		{"unicode alphabet", PasswordPolicy{Length: 5, Alphabet: "αβγ"}, 5, false},
+		{"zero length", PasswordPolicy{Length: 0, Lowercase: true}, 0, true},
+		{"no classes", PasswordPolicy{Length: 8}, 0, true},
+		{"too short for classes", PasswordPolicy{Length: 2, Lowercase: true, Uppercase: true, Digits: true}, 0, true},
+		{"class fully excluded", PasswordPolicy{Length: 8, Digits: true, ExcludeChars: PasswordDigits}, 0, true},
+		{"invalid utf-8 alphabet", PasswordPolicy{Length: 8, Alphabet: "\xff\xfe"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GeneratePassword(tt.policy)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLen, utf8.RuneCountInString(got))
+		})
+	}
+}
+
+func TestGeneratePassword_RequiredClasses(t *testing.T) {
+	policy := PasswordPolicy{Length: 4, Lowercase: true, Uppercase: true, Digits: true, Symbols: true, SymbolSet: "#"}
+	for i := 0; i < 200; i++ {
+		pw, err := GeneratePassword(policy)
+		require.NoError(t, err)
+		assert.True(t, strings.ContainsAny(pw, PasswordLowercase), "missing lowercase in %q", pw)
+		assert.True(t, strings.ContainsAny(pw, PasswordUppercase), "missing uppercase in %q", pw)
+		assert.True(t, strings.ContainsAny(pw, PasswordDigits), "missing digit in %q", pw)
+		assert.True(t, strings.Contains(pw, "#"), "missing symbol in %q", pw)
+	}
+}
+
+func TestGeneratePassword_Exclusions(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+	policy.Length = 64
+	policy.ExcludeAmbiguous = true
+	policy.ExcludeChars = "xyz"
+	for i := 0; i < 100; i++ {
+		pw, err := GeneratePassword(policy)
+		require.NoError(t, err)
+		assert.False(t, strings.ContainsAny(pw, PasswordAmbiguous), "ambiguous character in %q", pw)
+		assert.False(t, strings.ContainsAny(pw, "xyz"), "excluded character in %q", pw)
+	}
+}
+
+func TestGeneratePassword_Uniformity(t *testing.T) {
+	// With a three character alphabet, each character should appear about a third of the time.
+	pw, err := GeneratePassword(PasswordPolicy{Length: 30000, Alphabet: "abc"})
+	require.NoError(t, err)
+	for _, c := range "abc" {
+		count := strings.Count(pw, string(c))
+		assert.InDelta(t, 10000, count, 500, "character %c appeared %d times", c, count)
+	}
+}
+
+func TestGeneratePasswordDefault(t *testing.T) {
+	pw, err := GeneratePasswordDefault()
+	require.NoError(t, err)
+	assert.Len(t, pw, defaultPasswordLength)
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      PassphraseOptions
+		wantWords int
+		wantBits  float64
+		wantErr   bool
+	}{
+		// The four hyphenated EFF words are never drawn with the "-" separator.
+		{"default options", DefaultPassphraseOptions(), 6, 6 * math.Log2(7772), false},
+		{"four words", PassphraseOptions{Words: 4, Separator: " "}, 4, 4 * math.Log2(7776), false},
+		{"random title adds a bit per word", PassphraseOptions{Words: 5, Separator: ".", Case: PassphraseRandomTitle}, 5, 5 * (math.Log2(7776) + 1), false},
+		{"custom wordlist", PassphraseOptions{Words: 3, Separator: "_", Wordlist: []string{"a", "b", "c", "d"}}, 3, 6, false},
+		{"words containing the separator", PassphraseOptions{Words: 3, Separator: "-", Wordlist: []string{"a", "b", "c-d", "e-f"}}, 3, 3, false},
+		{"title case merging words", PassphraseOptions{Words: 2, Separator: " ", Case: PassphraseTitle, Wordlist: []string{"apple", "Apple", "pear", "plum"}}, 2, 2 * 1.5, false},
+		{"random title of digits", PassphraseOptions{Words: 2, Separator: " ", Case: PassphraseRandomTitle, Wordlist: []string{"1", "2", "3", "4"}}, 2, 2 * 2, false},
+		{"random title of mixed words", PassphraseOptions{Words: 2, Separator: " ", Case: PassphraseRandomTitle, Wordlist: []string{"1", "2", "a", "b"}}, 2, 2 * 2.5, false},
+		{"too few words without the separator", PassphraseOptions{Words: 3, Separator: "-", Wordlist: []string{"a", "b-c", "d-e"}}, 0, 0, true},
+		{"zero words", PassphraseOptions{Words: 0}, 0, 0, true},
+		{"wordlist too small", PassphraseOptions{Words: 3, Wordlist: []string{"only"}}, 0, 0, true},
+		{"duplicate words", PassphraseOptions{Words: 3, Wordlist: []string{"a", "b", "a"}}, 0, 0, true},
+		{"empty word", PassphraseOptions{Words: 3, Wordlist: []string{"a", ""}}, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GeneratePassphrase(tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			sep := tt.opts.Separator
+			assert.Len(t, strings.Split(got.Value, sep), tt.wantWords)
+			assert.InDelta(t, tt.wantBits, got.EntropyBits, 1e-9)
+			assert.Equal(t, got.Value, got.String())
+		})
+	}
+}
+
+func TestGeneratePassphrase_Case(t *testing.T) {
+	tests := []struct {
+		name  string
+		pcase PassphraseCase
+		check func(word string) bool
+	}{
+		{"lower", PassphraseLower, func(w string) bool { return w == strings.ToLower(w) }},
+		{"upper", PassphraseUpper, func(w string) bool { return w == strings.ToUpper(w) }},
+		{"title", PassphraseTitle, func(w string) bool { return w == capitalizeWord(strings.ToLower(w)) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := GeneratePassphrase(PassphraseOptions{Words: 8, Separator: " ", Case: tt.pcase})
+			require.NoError(t, err)
+			for _, w := range strings.Split(p.Value, " ") {
+				assert.True(t, tt.check(w), "unexpected case for word %q", w)
+			}
+		})
+	}
+}
+
+func TestGeneratePassphraseDefault(t *testing.T) {
+	p, err := GeneratePassphraseDefault()
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(p.Value, "-"), defaultPassphraseWords)
+	assert.Greater(t, p.EntropyBits, 77.0)
+}
+
+func TestEFFLargeWordlist(t *testing.T) {
+	words := effLargeWordlist()
+	assert.Len(t, words, 7776)
+	assert.NoError(t, validateWordlist(words))
+	assert.Equal(t, "abacus", words[0])
+	assert.Equal(t, "zoom", words[len(words)-1])
+}
+
+func TestCapitalizeWord(t *testing.T) {
+	assert.Equal(t, "Hello", capitalizeWord("hello"))
+	assert.Equal(t, "Élan", capitalizeWord("élan"))
+	assert.Equal(t, "", capitalizeWord(""))
+}
+
+func BenchmarkGeneratePassword(b *testing.B) {
+	policy := DefaultPasswordPolicy()
+	for i := 0; i < b.N; i++ {
+		GeneratePassword(policy)
+	}
+}
+
+func BenchmarkGeneratePassphrase(b *testing.B) {
+	opts := DefaultPassphraseOptions()
+	for i := 0; i < b.N; i++ {
+		GeneratePassphrase(opts)
+	}
+}
@@ -0,0 +1,186 @@
+package xgen
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultSignatureCredentialHeader carries the "<key ID>/<credential scope>" of
+// a scoped signature.
+const DefaultSignatureCredentialHeader = "X-Signature-Credential"
+
+const (
+	// scopedSignatureAlgorithm is the first line of the scoped canonical string.
+	scopedSignatureAlgorithm = "XGEN-HMAC-SHA256"
+	// credentialScopeTerminator ends every credential scope.
+	credentialScopeTerminator = "xgen_request"
+	// credentialScopeDateFormat is the date format of a credential scope.
+	credentialScopeDateFormat = "20060102"
+	// scopedSecretPrefix is prepended to the root secret before deriving kDate.
+	scopedSecretPrefix = "XGEN"
+)
+
+// ErrInvalidCredentialScope is returned when a credential is malformed or its
+// scope does not match the expected region, service or request date.
+var ErrInvalidCredentialScope = fmt.Errorf("%w: invalid credential scope", ErrInvalidSignature)
+
+// CredentialScope limits a derived signing key to one UTC day, region and
+// service, like an AWS Signature Version 4 credential scope.
+type CredentialScope struct {
+	// Date is the day the key is valid for. Only its UTC date is used.
+	Date time.Time
+	// Region is the deployment region, e.g. "eu-west-1".
+	Region string
+	// Service is the service the key may call, e.g. "orders".
+	Service string
+}
+
+// String returns the scope as "YYYYMMDD/region/service/xgen_request".
+func (s CredentialScope) String() string {
+	return s.Date.UTC().Format(credentialScopeDateFormat) + "/" + s.Region + "/" + s.Service + "/" + credentialScopeTerminator
+}
+
+// Covers reports whether t falls on the scope's UTC date.
+func (s CredentialScope) Covers(t time.Time) bool {
+	return t.UTC().Format(credentialScopeDateFormat) == s.Date.UTC().Format(credentialScopeDateFormat)
+}
+
+// validate checks that the scope is complete and serializes unambiguously.
+func (s CredentialScope) validate() error {
+	if s.Date.IsZero() || s.Region == "" || s.Service == "" {
+		return errors.New("credential scope requires Date, Region and Service")
+	}
+	if strings.Contains(s.Region, "/") || strings.Contains(s.Service, "/") {
+		return errors.New("credential scope region and service must not contain '/'")
+	}
+	return nil
+}
+
+// ParseCredential parses a "<key ID>/YYYYMMDD/region/service/xgen_request"
+// credential. It returns ErrInvalidCredentialScope when it is malformed.
+func ParseCredential(credential string) (keyID string, scope CredentialScope, err error) {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[0] == "" || parts[4] != credentialScopeTerminator {
+		return "", CredentialScope{}, ErrInvalidCredentialScope
+	}
+	date, err := time.Parse(credentialScopeDateFormat, parts[1])
+	if err != nil {
+		return "", CredentialScope{}, ErrInvalidCredentialScope
+	}
+	scope = CredentialScope{Date: date, Region: parts[2], Service: parts[3]}
+	if scope.validate() != nil {
+		return "", CredentialScope{}, ErrInvalidCredentialScope
+	}
+	return parts[0], scope, nil
+}
+
+// DeriveSigningKey derives the signing key of scope from a root secret by
+// HMAC-SHA256 chaining, as in AWS Signature Version 4:
+//
+//	kDate    = HMAC("XGEN" + secret, "YYYYMMDD")
+//	kRegion  = HMAC(kDate, region)
+//	kService = HMAC(kRegion, service)
+//	kSigning = HMAC(kService, "xgen_request")
+//
+// A leaked signing key is only valid for one day, region and service, and
+// the root secret is never needed to sign requests.
+func DeriveSigningKey(secret string, scope CredentialScope) ([]byte, error) {
+	if secret == "" {
+		return nil, errors.New("missing required fields for signature")
+	}
+	if err := scope.validate(); err != nil {
+		return nil, err
+	}
+	key := []byte(scopedSecretPrefix + secret)
+	for _, part := range []string{scope.Date.UTC().Format(credentialScopeDateFormat), scope.Region, scope.Service, credentialScopeTerminator} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	return key, nil
+}
+
+// ScopedKey is a signing key derived for one credential scope. Clients sign
+// with it in place of their root secret and send Credential() in the
+// credential header; servers derive the same key from the root secret.
+type ScopedKey struct {
+	// KeyID identifies the root secret the key was derived from.
+	KeyID string
+	// Scope is the day, region and service the key is valid for.
+	Scope CredentialScope
+	// Key is the derived signing key.
+	Key []byte
+	// TimestampFormat is the format of the timestamps passed to Sign and
+	// Verify. It must match the verifying middleware's Timestamp.Format.
+	// Defaults to TimestampFormatUnix.
+	TimestampFormat TimestampFormat
+}
+
+// NewScopedKey derives the ScopedKey of scope from the root secret of keyID.
+//
+// Example:
+//
+//	key, err := NewScopedKey("client-1", rootSecret, CredentialScope{
+//		Date: time.Now(), Region: "eu-west-1", Service: "orders",
+//	})
+//	signature, err := key.Sign("POST", "/orders", timestamp, "", body)
+//	req.Header.Set(DefaultSignatureCredentialHeader, key.Credential())
+func NewScopedKey(keyID, secret string, scope CredentialScope) (*ScopedKey, error) {
+	if keyID == "" || strings.Contains(keyID, "/") {
+		return nil, errors.New("scoped key requires a key ID without '/'")
+	}
+	key, err := DeriveSigningKey(secret, scope)
+	if err != nil {
+		return nil, err
+	}
+	return &ScopedKey{KeyID: keyID, Scope: scope, Key: key}, nil
+}
+
+// Credential returns the credential header value "<key ID>/<scope>".
+func (k *ScopedKey) Credential() string {
+	return k.KeyID + "/" + k.Scope.String()
+}
+
+// Sign signs the request components. The timestamp, in TimestampFormat, must
+// fall on the scope date. The canonical string is
+// XGEN-HMAC-SHA256\nSCOPE\nMETHOD\nPATH\nTIMESTAMP\nNONCE\nBODY, with an empty
+// nonce line when nonce is empty.
+func (k *ScopedKey) Sign(method, path, timestamp, nonce, rawBody string) (string, error) {
+	if len(k.Key) == 0 || method == "" || path == "" || timestamp == "" {
+		return "", errors.New("missing required fields for signature")
+	}
+	ts, err := ParseSignatureTimestamp(timestamp, k.TimestampFormat)
+	if err != nil {
+		return "", err
+	}
+	if !k.Scope.Covers(ts) {
+		return "", fmt.Errorf("%w: timestamp is not on %s", ErrInvalidCredentialScope, k.Scope.Date.UTC().Format(credentialScopeDateFormat))
+	}
+	if strings.Contains(nonce, "\n") {
+		return "", errors.New("signature nonce must not contain a newline")
+	}
+	canonical := strings.Join([]string{scopedSignatureAlgorithm, k.Scope.String(), method, path, timestamp, nonce, rawBody}, "\n")
+	mac := hmac.New(sha256.New, k.Key)
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Verify checks a signature created by Sign with the same key.
+// Uses constant-time comparison to prevent timing attacks.
+func (k *ScopedKey) Verify(method, path, timestamp, nonce, rawBody, receivedSig string) bool {
+	expectedSig, err := k.Sign(method, path, timestamp, nonce, rawBody)
+	if err != nil {
+		return false
+	}
+	expected, _ := hex.DecodeString(expectedSig)
+	received, err := hex.DecodeString(receivedSig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, received)
+}
@@ -0,0 +1,214 @@
+package xgen
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveSigningKey(t *testing.T) {
+	scope := CredentialScope{Date: time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC), Region: "eu-west-1", Service: "orders"}
+	assert.Equal(t, "20240101/eu-west-1/orders/xgen_request", scope.String())
+
+	key, err := DeriveSigningKey("root-secret", scope)
+	require.NoError(t, err)
+	chain := []byte("XGENroot-secret")
+	for _, part := range []string{"20240101", "eu-west-1", "orders", "xgen_request"} {
+		mac := hmac.New(sha256.New, chain)
+		mac.Write([]byte(part))
+		chain = mac.Sum(nil)
+	}
+	assert.Equal(t, chain, key)
+
+	// Every scope component changes the key; the time of day and zone do not.
+	for _, other := range []CredentialScope{
+		{Date: scope.Date.AddDate(0, 0, 1), Region: "eu-west-1", Service: "orders"},
+		{Date: scope.Date, Region: "us-east-1", Service: "orders"},
+		{Date: scope.Date, Region: "eu-west-1", Service: "billing"},
+	} {
+		otherKey, err := DeriveSigningKey("root-secret", other)
+		require.NoError(t, err)
+		assert.NotEqual(t, key, otherKey, other.String())
+	}
+	sameDay, err := DeriveSigningKey("root-secret", CredentialScope{
+		Date: time.Date(2024, 1, 2, 6, 0, 0, 0, time.FixedZone("ICT", 7*3600)), Region: "eu-west-1", Service: "orders",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, key, sameDay)
+
+	_, err = DeriveSigningKey("", scope)
+	assert.Error(t, err)
+	_, err = DeriveSigningKey("s", CredentialScope{Date: scope.Date, Region: "eu-west-1"})
+	assert.Error(t, err)
+	_, err = DeriveSigningKey("s", CredentialScope{Date: scope.Date, Region: "eu/west", Service: "orders"})
+	assert.Error(t, err)
+}
+
+func TestParseCredential(t *testing.T) {
+	keyID, scope, err := ParseCredential("client-1/20240101/eu-west-1/orders/xgen_request")
+	require.NoError(t, err)
+	assert.Equal(t, "client-1", keyID)
+	assert.Equal(t, CredentialScope{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Region: "eu-west-1", Service: "orders"}, scope)
+
+	for _, credential := range []string{
+		"",
+		"client-1",
+		"/20240101/eu-west-1/orders/xgen_request",
+		"client-1/2024-01-01/eu-west-1/orders/xgen_request",
+		"client-1/20240101//orders/xgen_request",
+		"client-1/20240101/eu-west-1/orders/aws4_request",
+		"client-1/20240101/eu-west-1/orders/xgen_request/extra",
+	} {
+		_, _, err := ParseCredential(credential)
+		assert.ErrorIs(t, err, ErrInvalidCredentialScope, credential)
+		assert.ErrorIs(t, err, ErrInvalidSignature, credential)
+	}
+}
+
+func TestScopedKey_SignVerify(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	key, err := NewScopedKey("client-1", "root-secret", CredentialScope{Date: now, Region: "eu-west-1", Service: "orders"})
+	require.NoError(t, err)
+	assert.Equal(t, "client-1/20240101/eu-west-1/orders/xgen_request", key.Credential())
+
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sig, err := key.Sign("POST", "/orders", ts, "", "body")
+	require.NoError(t, err)
+	assert.Len(t, sig, 64)
+	assert.True(t, key.Verify("POST", "/orders", ts, "", "body", sig))
+	assert.False(t, key.Verify("POST", "/orders", ts, "", "other", sig))
+	assert.False(t, key.Verify("POST", "/orders", ts, "n-1", "body", sig))
+	assert.False(t, key.Verify("POST", "/orders", ts, "", "body", "zz"))
+	// Not interchangeable with a plain signature over the derived key.
+	plain, err := GenerateSignature(string(key.Key), "POST", "/orders", ts, "body")
+	require.NoError(t, err)
+	assert.NotEqual(t, plain, sig)
+
+	_, err = key.Sign("POST", "/orders", strconv.FormatInt(now.AddDate(0, 0, 1).Unix(), 10), "", "body")
+	assert.ErrorIs(t, err, ErrInvalidCredentialScope)
+	_, err = key.Sign("POST", "/orders", "not-a-time", "", "body")
+	assert.ErrorIs(t, err, ErrInvalidSignatureTimestamp)
+
+	// Timestamps use TimestampFormat, Unix seconds by default, like the
+	// middleware, so formats the server rejects are not signed.
+	for _, ts := range []string{now.Format(time.RFC3339), strconv.FormatInt(now.UnixMilli(), 10)} {
+		_, err = key.Sign("POST", "/orders", ts, "", "body")
+		assert.Error(t, err, ts)
+	}
+	key.TimestampFormat = TimestampFormatRFC3339
+	_, err = key.Sign("POST", "/orders", now.Format(time.RFC3339), "", "body")
+	assert.NoError(t, err)
+	_, err = key.Sign("POST", "/orders", ts, "", "body")
+	assert.ErrorIs(t, err, ErrInvalidSignatureTimestamp)
+	key.TimestampFormat = TimestampFormat(-1)
+	_, err = key.Sign("POST", "/orders", ts, "", "body")
+	assert.Error(t, err)
+
+	_, err = NewScopedKey("", "root-secret", key.Scope)
+	assert.Error(t, err)
+	_, err = NewScopedKey("a/b", "root-secret", key.Scope)
+	assert.Error(t, err)
+}
+
+func TestScopedSignature_MiddlewareTransport(t *testing.T) {
+	secrets := map[string]string{"client-1": "root-secret"}
+	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret:      MapSecretLookup(secrets),
+		CredentialRegion:  "eu-west-1",
+		CredentialService: "orders",
+		NonceStore:        NewMemoryNonceStore(MemoryNonceStoreOptions{}),
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(mw(echoHandler))
+	defer server.Close()
+
+	// The client only holds keys derived for the current day.
+	newClient := func(region string) *http.Client {
+		transport, err := NewSignatureTransport(SignatureTransportOptions{
+			ScopedKey: func(now time.Time) (*ScopedKey, error) {
+				return NewScopedKey("client-1", "root-secret", CredentialScope{Date: now, Region: region, Service: "orders"})
+			},
+			Nonce: true,
+		})
+		require.NoError(t, err)
+		return &http.Client{Transport: transport}
+	}
+
+	resp, err := newClient("eu-west-1").Post(server.URL+"/orders", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = newClient("us-east-1").Post(server.URL+"/orders", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// A key from another day is rejected even when the signature matches it.
+	yesterday := time.Now().AddDate(0, 0, -1)
+	old, err := NewScopedKey("client-1", "root-secret", CredentialScope{Date: yesterday, Region: "eu-west-1", Service: "orders"})
+	require.NoError(t, err)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	var got error
+	mw, err = NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret:      MapSecretLookup(secrets),
+		CredentialRegion:  "eu-west-1",
+		CredentialService: "orders",
+		ErrorHandler:      func(w http.ResponseWriter, r *http.Request, err error) { got = err },
+	})
+	require.NoError(t, err)
+	req := httptest.NewRequest("GET", "/orders", nil)
+	req.Header.Set(DefaultSignatureTimestampHeader, ts)
+	req.Header.Set(DefaultSignatureHeader, "00")
+	req.Header.Set(DefaultSignatureCredentialHeader, old.Credential())
+	mw(echoHandler).ServeHTTP(httptest.NewRecorder(), req)
+	assert.ErrorIs(t, got, ErrInvalidCredentialScope)
+
+	req.Header.Del(DefaultSignatureCredentialHeader)
+	mw(echoHandler).ServeHTTP(httptest.NewRecorder(), req)
+	assert.ErrorIs(t, got, ErrMissingSignature)
+
+	// Plain signatures are not accepted in scoped mode.
+	req = newSignedTestRequest(t, "root-secret", "client-1", "GET", "/orders", "")
+	current, err := NewScopedKey("client-1", "root-secret", CredentialScope{Date: time.Now(), Region: "eu-west-1", Service: "orders"})
+	require.NoError(t, err)
+	req.Header.Set(DefaultSignatureCredentialHeader, current.Credential())
+	mw(echoHandler).ServeHTTP(httptest.NewRecorder(), req)
+	assert.ErrorIs(t, got, ErrSignatureMismatch)
+
+	// The middleware verifies scoped signatures in its timestamp format.
+	rfc3339, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret:      MapSecretLookup(secrets),
+		CredentialRegion:  "eu-west-1",
+		CredentialService: "orders",
+		Timestamp:         TimestampPolicy{Format: TimestampFormatRFC3339},
+	})
+	require.NoError(t, err)
+	current.TimestampFormat = TimestampFormatRFC3339
+	ts = time.Now().UTC().Format(time.RFC3339)
+	sig, err := current.Sign("GET", "/orders", ts, "", "")
+	require.NoError(t, err)
+	req = httptest.NewRequest("GET", "/orders", nil)
+	req.Header.Set(DefaultSignatureTimestampHeader, ts)
+	req.Header.Set(DefaultSignatureHeader, sig)
+	req.Header.Set(DefaultSignatureCredentialHeader, current.Credential())
+	rec := httptest.NewRecorder()
+	rfc3339(echoHandler).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	_, err = NewSignatureMiddleware(SignatureMiddlewareOptions{LookupSecret: MapSecretLookup(secrets), CredentialService: "orders"})
+	assert.Error(t, err)
+	_, err = NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret: MapSecretLookup(secrets), CredentialRegion: "eu-west-1", CredentialService: "orders", Version: SignatureVersion2,
+	})
+	assert.Error(t, err)
+	_, err = NewSignatureTransport(SignatureTransportOptions{Secret: "s", ScopedKey: func(time.Time) (*ScopedKey, error) { return nil, nil }})
+	assert.Error(t, err)
+}
@@ -2,16 +2,17 @@ package xgen
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"strconv"
+	"strings"
 	"time"
 )
 
-// defaultSignatureDrift is the default allowed time drift for signature validation.
-const defaultSignatureDrift = 5 * time.Minute
+// DefaultSignatureDrift is the default allowed time drift for signature validation.
+const DefaultSignatureDrift = 5 * time.Minute
 
 // BuildSignatureCanonicalString formats request components into a deterministic string for signing.
 // Recommended structure: METHOD\nPATH\nTIMESTAMP\nBODY
@@ -19,6 +20,19 @@ func BuildSignatureCanonicalString(method, path, timestamp, rawBody string) stri
 	return fmt.Sprintf("%s\n%s\n%s\n%s", method, path, timestamp, rawBody)
 }
 
+// nonceSignatureVersion is the first line of the nonce canonical string. It
+// contains '/', which HTTP methods cannot, so a nonce canonical string never
+// equals one without a nonce: moving the first body line into the nonce
+// header cannot turn a plain signature into a nonce signature.
+const nonceSignatureVersion = "XGEN-NONCE/1"
+
+// BuildSignatureCanonicalStringWithNonce formats request components, including a
+// single-use nonce, into a deterministic string for signing.
+// Structure: XGEN-NONCE/1\nMETHOD\nPATH\nTIMESTAMP\nNONCE\nBODY
+func BuildSignatureCanonicalStringWithNonce(method, path, timestamp, nonce, rawBody string) string {
+	return fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s", nonceSignatureVersion, method, path, timestamp, nonce, rawBody)
+}
+
 // GenerateSignature creates a HMAC-SHA256 signature in hex format using the given secret and canonical string.
 func GenerateSignature(secret, method, path, timestamp, rawBody string) (string, error) {
 	if secret == "" || method == "" || path == "" || timestamp == "" {
@@ -45,23 +59,57 @@ func VerifySignature(secret, method, path, timestamp, rawBody, receivedSig strin
 	return hmac.Equal(expected, received)
 }
 
-// IsValidSignatureTimestamp checks if the given timestamp (in string, unix seconds)
-// is within the allowed TTL window, as specified by allowedDrift.
-func IsValidSignatureTimestamp(timestamp string, allowedDrift time.Duration) bool {
-	tsInt, err := strconv.ParseInt(timestamp, 10, 64)
+// GenerateSignatureNonce generates a random nonce (16 bytes = 32 hex chars) for replay protection.
+func GenerateSignatureNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GenerateSignatureWithNonce creates a HMAC-SHA256 signature in hex format that also covers a nonce.
+// Servers remember used nonces (see NonceStore) so a captured request cannot be replayed
+// within the timestamp window. The nonce must not contain a newline.
+func GenerateSignatureWithNonce(secret, method, path, timestamp, nonce, rawBody string) (string, error) {
+	if secret == "" || method == "" || path == "" || timestamp == "" || nonce == "" {
+		return "", errors.New("missing required fields for signature")
+	}
+	if strings.Contains(nonce, "\n") {
+		return "", errors.New("signature nonce must not contain a newline")
+	}
+	canonical := BuildSignatureCanonicalStringWithNonce(method, path, timestamp, nonce, rawBody)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifySignatureWithNonce compares the expected nonce signature with the received one.
+// Uses constant-time comparison to prevent timing attacks.
+func VerifySignatureWithNonce(secret, method, path, timestamp, nonce, rawBody, receivedSig string) bool {
+	expectedSig, err := GenerateSignatureWithNonce(secret, method, path, timestamp, nonce, rawBody)
 	if err != nil {
 		return false
 	}
-	ts := time.Unix(tsInt, 0)
-	now := time.Now()
-	diff := now.Sub(ts)
-	if diff < 0 {
-		diff = -diff
+	expected, err1 := hex.DecodeString(expectedSig)
+	received, err2 := hex.DecodeString(receivedSig)
+	if err1 != nil || err2 != nil {
+		return false
 	}
-	return diff <= allowedDrift
+	return hmac.Equal(expected, received)
+}
+
+// IsValidSignatureTimestamp checks if the given timestamp (in string, unix seconds)
+// is within the allowed TTL window, as specified by allowedDrift.
+// Use ValidateSignatureTimestamp for other formats, separate past and future
+// windows, or to learn why a timestamp was rejected.
+func IsValidSignatureTimestamp(timestamp string, allowedDrift time.Duration) bool {
+	policy := TimestampPolicy{Format: TimestampFormatUnix, MaxAge: allowedDrift, MaxFuture: allowedDrift, Now: time.Now}
+	_, _, err := policy.check(timestamp)
+	return err == nil
 }
 
 // IsValidSignatureTimestampDefault uses the default allowed TTL of ±5 minutes.
 func IsValidSignatureTimestampDefault(timestamp string) bool {
-	return IsValidSignatureTimestamp(timestamp, defaultSignatureDrift)
+	return IsValidSignatureTimestamp(timestamp, DefaultSignatureDrift)
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBuildSignatureCanonicalString(t *testing.T) {
@@ -92,3 +93,67 @@ func TestIsValidSignatureTimestampDefault(t *testing.T) {
 	isValid = IsValidSignatureTimestampDefault(fmt.Sprintf("%d", expiredTimestamp))
 	assert.False(t, isValid)
 }
+
+func TestBuildSignatureCanonicalStringWithNonce(t *testing.T) {
+	canonical := BuildSignatureCanonicalStringWithNonce("POST", "/api/resource", "1627849200", "abc123", "{\"key\":\"value\"}")
+	expected := "XGEN-NONCE/1\nPOST\n/api/resource\n1627849200\nabc123\n{\"key\":\"value\"}"
+	assert.Equal(t, expected, canonical)
+}
+
+func TestGenerateSignatureNonce(t *testing.T) {
+	nonce, err := GenerateSignatureNonce()
+	assert.NoError(t, err)
+	assert.Len(t, nonce, 32)
+
+	other, err := GenerateSignatureNonce()
+	assert.NoError(t, err)
+	assert.NotEqual(t, nonce, other)
+}
+
+func TestGenerateSignatureWithNonce_MissingFields(t *testing.T) {
+	_, err := GenerateSignatureWithNonce("secret", "POST", "/api/resource", "1627849200", "", "body")
+	assert.Error(t, err)
+
+	_, err = GenerateSignatureWithNonce("", "POST", "/api/resource", "1627849200", "nonce", "body")
+	assert.Error(t, err)
+}
+
+func TestVerifySignatureWithNonce(t *testing.T) {
+	secret := "test-secret"
+	timestamp := "1627849200"
+
+	signature, err := GenerateSignatureWithNonce(secret, "POST", "/api/resource", timestamp, "nonce-1", "body")
+	assert.NoError(t, err)
+
+	// Valid case
+	assert.True(t, VerifySignatureWithNonce(secret, "POST", "/api/resource", timestamp, "nonce-1", "body", signature))
+
+	// Different nonce
+	assert.False(t, VerifySignatureWithNonce(secret, "POST", "/api/resource", timestamp, "nonce-2", "body", signature))
+
+	// Nonce signatures are not valid without the nonce
+	assert.False(t, VerifySignature(secret, "POST", "/api/resource", timestamp, "body", signature))
+
+	// Invalid signature
+	assert.False(t, VerifySignatureWithNonce(secret, "POST", "/api/resource", timestamp, "nonce-1", "body", "invalid-signature"))
+}
+
+func TestVerifySignatureWithNonce_NoCrossFormCollision(t *testing.T) {
+	secret := "test-secret"
+	timestamp := "1627849200"
+
+	// Moving the first body line of a plain signature into the nonce must not
+	// verify, or an attacker could replay it with fresh nonces.
+	plain, err := GenerateSignature(secret, "POST", "/api/resource", timestamp, "nonce-1\nbody")
+	require.NoError(t, err)
+	assert.False(t, VerifySignatureWithNonce(secret, "POST", "/api/resource", timestamp, "nonce-1", "body", plain))
+
+	withNonce, err := GenerateSignatureWithNonce(secret, "POST", "/api/resource", timestamp, "nonce-1", "body")
+	require.NoError(t, err)
+	assert.False(t, VerifySignature(secret, "POST", "/api/resource", timestamp, "nonce-1\nbody", withNonce))
+
+	// Nonces with newlines would let body lines move into the nonce.
+	_, err = GenerateSignatureWithNonce(secret, "POST", "/api/resource", timestamp, "nonce-1\nbody", "")
+	assert.Error(t, err)
+	assert.False(t, VerifySignatureWithNonce(secret, "POST", "/api/resource", timestamp, "nonce-1\nextra", "body", withNonce))
+}
@@ -0,0 +1,119 @@
+package xgen
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignURL(t *testing.T) {
+	expires := time.Now().Add(time.Hour)
+	link, err := SignURL("https://files.example.com/reports/q3.pdf?b=2&a=1#page=4", expires, SignURLOptions{Secret: "secret"})
+	require.NoError(t, err)
+
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "page=4", u.Fragment)
+	assert.Equal(t, "2", u.Query().Get("b"))
+	assert.NotEmpty(t, u.Query().Get(SignedURLExpiresParam))
+	assert.Len(t, u.Query().Get(SignedURLSignatureParam), 64)
+	assert.NoError(t, VerifyURL(link, VerifyURLOptions{Secret: "secret"}))
+
+	// Equivalent encodings of the query verify.
+	same := strings.Replace(link, "?b=2&a=1", "?a=1&b=2", 1)
+	assert.NoError(t, VerifyURL(same, VerifyURLOptions{Secret: "secret"}))
+
+	// Paths are signed verbatim, so dot-segment and double-slash variants of
+	// the same path are rejected.
+	for _, variant := range []string{"/reports//q3.pdf", "/reports/./q3.pdf", "/x/../reports/q3.pdf", "/x/%2E%2E/reports/q3.pdf", "//reports/q3.pdf"} {
+		tampered := strings.Replace(link, "/reports/q3.pdf", variant, 1)
+		assert.ErrorIs(t, VerifyURL(tampered, VerifyURLOptions{Secret: "secret"}), ErrURLTampered, variant)
+	}
+
+	// An empty path signs as "/".
+	root, err := SignURL("https://files.example.com", expires, SignURLOptions{Secret: "secret"})
+	require.NoError(t, err)
+	assert.NoError(t, VerifyURL(strings.Replace(root, "files.example.com?", "files.example.com/?", 1), VerifyURLOptions{Secret: "secret"}))
+
+	_, err = SignURL("/relative", expires, SignURLOptions{Secret: "secret"})
+	assert.ErrorIs(t, err, ErrURLMalformed)
+	_, err = SignURL("https://example.com/?expires=1", expires, SignURLOptions{Secret: "secret"})
+	assert.ErrorIs(t, err, ErrURLMalformed)
+	_, err = SignURL("https://example.com/", expires, SignURLOptions{})
+	assert.Error(t, err)
+	_, err = SignURL("https://example.com/", expires, SignURLOptions{Secret: "secret", ClientIP: "not-an-ip"})
+	assert.Error(t, err)
+}
+
+func TestVerifyURL_Errors(t *testing.T) {
+	link, err := SignURL("https://files.example.com/reports/q3.pdf?user=7", time.Now().Add(time.Hour), SignURLOptions{Secret: "secret"})
+	require.NoError(t, err)
+	opts := VerifyURLOptions{Secret: "secret"}
+
+	for _, tampered := range []string{
+		strings.Replace(link, "https://", "http://", 1),
+		strings.Replace(link, "files.example.com", "evil.example.com", 1),
+		strings.Replace(link, "q3.pdf", "q4.pdf", 1),
+		strings.Replace(link, "user=7", "user=8", 1),
+		strings.Replace(link, "?user=7", "?user=7&admin=1", 1),
+		strings.Replace(link, "expires=", "expires=9", 1),
+	} {
+		assert.ErrorIs(t, VerifyURL(tampered, opts), ErrURLTampered, tampered)
+	}
+	assert.ErrorIs(t, VerifyURL(link, VerifyURLOptions{Secret: "other"}), ErrURLTampered)
+
+	for _, malformed := range []string{
+		"::not a url",
+		"/relative?expires=1&signature=00",
+		strings.Replace(link, "signature=", "sig=", 1),
+		strings.Replace(link, "expires=", "exp=", 1),
+		link + "&signature=00",
+		strings.Replace(link, "signature=", "signature=zz", 1),
+		strings.Replace(link, "expires=", "expires=x", 1),
+		strings.Replace(link, "user=7", "user=%zz", 1),
+	} {
+		assert.ErrorIs(t, VerifyURL(malformed, opts), ErrURLMalformed, malformed)
+	}
+
+	expired, err := SignURL("https://files.example.com/a", time.Now().Add(-time.Second), SignURLOptions{Secret: "secret"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, VerifyURL(expired, opts), ErrURLExpired)
+
+	assert.Error(t, VerifyURL(link, VerifyURLOptions{}))
+}
+
+func TestVerifyURL_Restrictions(t *testing.T) {
+	expires := time.Now().Add(time.Hour)
+	upload, err := SignURL("https://files.example.com/uploads/1", expires, SignURLOptions{Secret: "secret", Method: "put"})
+	require.NoError(t, err)
+	assert.NoError(t, VerifyURL(upload, VerifyURLOptions{Secret: "secret", Method: "PUT"}))
+	assert.ErrorIs(t, VerifyURL(upload, VerifyURLOptions{Secret: "secret", Method: "GET"}), ErrURLRestricted)
+	assert.ErrorIs(t, VerifyURL(strings.Replace(upload, "method=PUT", "method=GET", 1), VerifyURLOptions{Secret: "secret", Method: "GET"}), ErrURLTampered)
+
+	pinned, err := SignURL("https://files.example.com/a", expires, SignURLOptions{Secret: "secret", ClientIP: "2001:db8::1"})
+	require.NoError(t, err)
+	assert.NoError(t, VerifyURL(pinned, VerifyURLOptions{Secret: "secret", ClientIP: "2001:0db8:0:0::1"}))
+	assert.ErrorIs(t, VerifyURL(pinned, VerifyURLOptions{Secret: "secret", ClientIP: "2001:db8::2"}), ErrURLRestricted)
+	assert.ErrorIs(t, VerifyURL(pinned, VerifyURLOptions{Secret: "secret"}), ErrURLRestricted)
+}
+
+func TestVerifyURLRequest(t *testing.T) {
+	link, err := SignURL("http://example.com/download?id=1", time.Now().Add(time.Hour),
+		SignURLOptions{Secret: "secret", Method: "GET", ClientIP: "192.0.2.1"})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("GET", link, nil)
+	assert.NoError(t, VerifyURLRequest(r, "secret"))
+
+	r = httptest.NewRequest("POST", link, nil)
+	assert.ErrorIs(t, VerifyURLRequest(r, "secret"), ErrURLRestricted)
+
+	r = httptest.NewRequest("GET", link, nil)
+	r.RemoteAddr = "198.51.100.7:1234"
+	assert.ErrorIs(t, VerifyURLRequest(r, "secret"), ErrURLRestricted)
+}
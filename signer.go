@@ -0,0 +1,231 @@
+package xgen
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+)
+
+// SignatureAlgorithm names a signature algorithm. The name is part of the
+// canonical string built by BuildSignatureCanonicalStringWithAlgorithm.
+type SignatureAlgorithm string
+
+// Supported signature algorithms.
+const (
+	SignatureAlgorithmHMACSHA256 SignatureAlgorithm = "hmac-sha256"
+	SignatureAlgorithmHMACSHA512 SignatureAlgorithm = "hmac-sha512"
+	SignatureAlgorithmEd25519    SignatureAlgorithm = "ed25519"
+	SignatureAlgorithmECDSAP256  SignatureAlgorithm = "ecdsa-p256-sha256"
+)
+
+// Verifier checks signatures made with one algorithm and key.
+type Verifier interface {
+	// Algorithm returns the algorithm of the key.
+	Algorithm() SignatureAlgorithm
+	// Verify reports whether signature is valid for message.
+	Verify(message, signature []byte) bool
+}
+
+// Signer creates signatures with one algorithm and key.
+// Every Signer can also verify its own signatures.
+type Signer interface {
+	Verifier
+	// Sign returns the signature of message.
+	Sign(message []byte) ([]byte, error)
+}
+
+// BuildSignatureCanonicalStringWithAlgorithm formats request components into a
+// deterministic string for signing with a Signer. The algorithm name comes
+// first so a signature made with one algorithm never verifies under another.
+// Structure: ALGORITHM\nMETHOD\nPATH\nTIMESTAMP\nNONCE\nBODY (NONCE may be empty)
+func BuildSignatureCanonicalStringWithAlgorithm(algorithm SignatureAlgorithm, method, path, timestamp, nonce, rawBody string) string {
+	return fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s", algorithm, method, path, timestamp, nonce, rawBody)
+}
+
+// GenerateSignatureWithSigner signs the request components with signer and
+// returns the signature in hex format. The nonce is optional.
+//
+// Example:
+//
+//	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+//	signer, _ := NewEd25519Signer(priv)
+//	sig, err := GenerateSignatureWithSigner(signer, "POST", "/api/users", timestamp, "", body)
+func GenerateSignatureWithSigner(signer Signer, method, path, timestamp, nonce, rawBody string) (string, error) {
+	if signer == nil || method == "" || path == "" || timestamp == "" {
+		return "", errors.New("missing required fields for signature")
+	}
+	canonical := BuildSignatureCanonicalStringWithAlgorithm(signer.Algorithm(), method, path, timestamp, nonce, rawBody)
+	sig, err := signer.Sign([]byte(canonical))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sig), nil
+}
+
+// VerifySignatureWithVerifier checks a signature created by GenerateSignatureWithSigner.
+// The verifier's own algorithm is used to build the canonical string, so the
+// sender cannot choose a weaker one.
+func VerifySignatureWithVerifier(verifier Verifier, method, path, timestamp, nonce, rawBody, receivedSig string) bool {
+	if verifier == nil || method == "" || path == "" || timestamp == "" {
+		return false
+	}
+	received, err := hex.DecodeString(receivedSig)
+	if err != nil {
+		return false
+	}
+	canonical := BuildSignatureCanonicalStringWithAlgorithm(verifier.Algorithm(), method, path, timestamp, nonce, rawBody)
+	return verifier.Verify([]byte(canonical), received)
+}
+
+// hmacSigner signs with HMAC. It verifies with a constant-time comparison.
+type hmacSigner struct {
+	algorithm SignatureAlgorithm
+	hash      func() hash.Hash
+	secret    []byte
+}
+
+// NewHMACSigner returns a Signer for SignatureAlgorithmHMACSHA256 or
+// SignatureAlgorithmHMACSHA512 using secret.
+func NewHMACSigner(algorithm SignatureAlgorithm, secret string) (Signer, error) {
+	if secret == "" {
+		return nil, errors.New("HMAC signer requires a secret")
+	}
+	switch algorithm {
+	case SignatureAlgorithmHMACSHA256:
+		return &hmacSigner{algorithm: algorithm, hash: sha256.New, secret: []byte(secret)}, nil
+	case SignatureAlgorithmHMACSHA512:
+		return &hmacSigner{algorithm: algorithm, hash: sha512.New, secret: []byte(secret)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported HMAC algorithm %q", algorithm)
+	}
+}
+
+// Algorithm implements Verifier.
+func (s *hmacSigner) Algorithm() SignatureAlgorithm {
+	return s.algorithm
+}
+
+// Sign implements Signer.
+func (s *hmacSigner) Sign(message []byte) ([]byte, error) {
+	mac := hmac.New(s.hash, s.secret)
+	mac.Write(message)
+	return mac.Sum(nil), nil
+}
+
+// Verify implements Verifier.
+func (s *hmacSigner) Verify(message, signature []byte) bool {
+	expected, _ := s.Sign(message)
+	return hmac.Equal(expected, signature)
+}
+
+// ed25519Verifier verifies Ed25519 signatures.
+type ed25519Verifier struct {
+	public ed25519.PublicKey
+}
+
+// ed25519Signer signs with an Ed25519 private key.
+type ed25519Signer struct {
+	ed25519Verifier
+	private ed25519.PrivateKey
+}
+
+// NewEd25519Signer returns a Signer for SignatureAlgorithmEd25519.
+func NewEd25519Signer(key ed25519.PrivateKey) (Signer, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid Ed25519 private key size")
+	}
+	public := key.Public().(ed25519.PublicKey)
+	return &ed25519Signer{ed25519Verifier: ed25519Verifier{public: public}, private: key}, nil
+}
+
+// NewEd25519Verifier returns a Verifier for SignatureAlgorithmEd25519.
+func NewEd25519Verifier(key ed25519.PublicKey) (Verifier, error) {
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid Ed25519 public key size")
+	}
+	return &ed25519Verifier{public: key}, nil
+}
+
+// Algorithm implements Verifier.
+func (v *ed25519Verifier) Algorithm() SignatureAlgorithm {
+	return SignatureAlgorithmEd25519
+}
+
+// Verify implements Verifier.
+func (v *ed25519Verifier) Verify(message, signature []byte) bool {
+	return ed25519.Verify(v.public, message, signature)
+}
+
+// Sign implements Signer.
+func (s *ed25519Signer) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(s.private, message), nil
+}
+
+// ecdsaP256Size is the size of each of r and s in a P-256 signature.
+const ecdsaP256Size = 32
+
+// ecdsaP256Verifier verifies ECDSA P-256 signatures encoded as r || s.
+type ecdsaP256Verifier struct {
+	public *ecdsa.PublicKey
+}
+
+// ecdsaP256Signer signs with an ECDSA P-256 private key.
+type ecdsaP256Signer struct {
+	ecdsaP256Verifier
+	private *ecdsa.PrivateKey
+}
+
+// NewECDSAP256Signer returns a Signer for SignatureAlgorithmECDSAP256.
+// Signatures are the 64-byte concatenation of r and s over a SHA-256 digest.
+func NewECDSAP256Signer(key *ecdsa.PrivateKey) (Signer, error) {
+	if key == nil || key.Curve != elliptic.P256() {
+		return nil, errors.New("ECDSA signer requires a P-256 private key")
+	}
+	return &ecdsaP256Signer{ecdsaP256Verifier: ecdsaP256Verifier{public: &key.PublicKey}, private: key}, nil
+}
+
+// NewECDSAP256Verifier returns a Verifier for SignatureAlgorithmECDSAP256.
+func NewECDSAP256Verifier(key *ecdsa.PublicKey) (Verifier, error) {
+	if key == nil || key.Curve != elliptic.P256() {
+		return nil, errors.New("ECDSA verifier requires a P-256 public key")
+	}
+	return &ecdsaP256Verifier{public: key}, nil
+}
+
+// Algorithm implements Verifier.
+func (v *ecdsaP256Verifier) Algorithm() SignatureAlgorithm {
+	return SignatureAlgorithmECDSAP256
+}
+
+// Verify implements Verifier.
+func (v *ecdsaP256Verifier) Verify(message, signature []byte) bool {
+	if len(signature) != 2*ecdsaP256Size {
+		return false
+	}
+	digest := sha256.Sum256(message)
+	r := new(big.Int).SetBytes(signature[:ecdsaP256Size])
+	s := new(big.Int).SetBytes(signature[ecdsaP256Size:])
+	return ecdsa.Verify(v.public, digest[:], r, s)
+}
+
+// Sign implements Signer.
+func (s *ecdsaP256Signer) Sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	r, sv, err := ecdsa.Sign(rand.Reader, s.private, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	sig := make([]byte, 2*ecdsaP256Size)
+	r.FillBytes(sig[:ecdsaP256Size])
+	sv.FillBytes(sig[ecdsaP256Size:])
+	return sig, nil
+}
@@ -0,0 +1,204 @@
+package xgen
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSigners returns one signer for each supported algorithm.
+func newTestSigners(t *testing.T) []Signer {
+	t.Helper()
+	hmac256, err := NewHMACSigner(SignatureAlgorithmHMACSHA256, "secret")
+	require.NoError(t, err)
+	hmac512, err := NewHMACSigner(SignatureAlgorithmHMACSHA512, "secret")
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ed, err := NewEd25519Signer(edKey)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ec, err := NewECDSAP256Signer(ecKey)
+	require.NoError(t, err)
+	return []Signer{hmac256, hmac512, ed, ec}
+}
+
+func TestBuildSignatureCanonicalStringWithAlgorithm(t *testing.T) {
+	canonical := BuildSignatureCanonicalStringWithAlgorithm(SignatureAlgorithmEd25519, "POST", "/api/resource", "1627849200", "", "{}")
+	assert.Equal(t, "ed25519\nPOST\n/api/resource\n1627849200\n\n{}", canonical)
+}
+
+func TestSigners_KnownVectors(t *testing.T) {
+	// RFC 4231 test case 2
+	message := []byte("what do ya want for nothing?")
+	hmac256, err := NewHMACSigner(SignatureAlgorithmHMACSHA256, "Jefe")
+	require.NoError(t, err)
+	sig, err := hmac256.Sign(message)
+	require.NoError(t, err)
+	assert.Equal(t, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843", hex.EncodeToString(sig))
+
+	hmac512, err := NewHMACSigner(SignatureAlgorithmHMACSHA512, "Jefe")
+	require.NoError(t, err)
+	sig, err = hmac512.Sign(message)
+	require.NoError(t, err)
+	assert.Equal(t, "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea250554"+
+		"9758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737", hex.EncodeToString(sig))
+
+	// RFC 8032 section 7.1, test 1
+	seed, _ := hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+	ed, err := NewEd25519Signer(ed25519.NewKeyFromSeed(seed))
+	require.NoError(t, err)
+	sig, err = ed.Sign(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e06522490155"+
+		"5fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b", hex.EncodeToString(sig))
+}
+
+func TestGenerateSignatureWithSigner(t *testing.T) {
+	for _, signer := range newTestSigners(t) {
+		t.Run(string(signer.Algorithm()), func(t *testing.T) {
+			sig, err := GenerateSignatureWithSigner(signer, "POST", "/api", "1700000000", "nonce", "body")
+			require.NoError(t, err)
+
+			assert.True(t, VerifySignatureWithVerifier(signer, "POST", "/api", "1700000000", "nonce", "body", sig))
+			assert.False(t, VerifySignatureWithVerifier(signer, "POST", "/api", "1700000000", "other", "body", sig))
+			assert.False(t, VerifySignatureWithVerifier(signer, "POST", "/api", "1700000000", "nonce", "tampered", sig))
+			assert.False(t, VerifySignatureWithVerifier(signer, "POST", "/api", "1700000000", "nonce", "body", "not-hex"))
+			assert.False(t, VerifySignatureWithVerifier(signer, "POST", "/api", "1700000000", "nonce", "body", ""))
+		})
+	}
+
+	_, err := GenerateSignatureWithSigner(nil, "POST", "/api", "1700000000", "", "")
+	assert.Error(t, err)
+	_, err = GenerateSignatureWithSigner(newTestSigners(t)[0], "", "/api", "1700000000", "", "")
+	assert.Error(t, err)
+	assert.False(t, VerifySignatureWithVerifier(nil, "POST", "/api", "1700000000", "", "", "00"))
+}
+
+func TestSigners_NoDowngrade(t *testing.T) {
+	// The same secret under a different algorithm, or the legacy format, must not verify.
+	hmac256, err := NewHMACSigner(SignatureAlgorithmHMACSHA256, "secret")
+	require.NoError(t, err)
+	hmac512, err := NewHMACSigner(SignatureAlgorithmHMACSHA512, "secret")
+	require.NoError(t, err)
+
+	sig512, err := GenerateSignatureWithSigner(hmac512, "GET", "/", "1700000000", "", "")
+	require.NoError(t, err)
+	assert.False(t, VerifySignatureWithVerifier(hmac256, "GET", "/", "1700000000", "", "", sig512[:64]))
+
+	sig256, err := GenerateSignatureWithSigner(hmac256, "GET", "/", "1700000000", "", "")
+	require.NoError(t, err)
+	assert.False(t, VerifySignature("secret", "GET", "/", "1700000000", "", sig256))
+
+	legacy, err := GenerateSignature("secret", "GET", "/", "1700000000", "")
+	require.NoError(t, err)
+	assert.False(t, VerifySignatureWithVerifier(hmac256, "GET", "/", "1700000000", "", "", legacy))
+}
+
+func TestSigners_PublicKeyVerifiers(t *testing.T) {
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edSigner, err := NewEd25519Signer(edKey)
+	require.NoError(t, err)
+	edVerifier, err := NewEd25519Verifier(edPub)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecSigner, err := NewECDSAP256Signer(ecKey)
+	require.NoError(t, err)
+	ecVerifier, err := NewECDSAP256Verifier(&ecKey.PublicKey)
+	require.NoError(t, err)
+
+	for _, pair := range []struct {
+		signer   Signer
+		verifier Verifier
+	}{{edSigner, edVerifier}, {ecSigner, ecVerifier}} {
+		sig, err := GenerateSignatureWithSigner(pair.signer, "PUT", "/items/1", "1700000000", "", "data")
+		require.NoError(t, err)
+		assert.True(t, VerifySignatureWithVerifier(pair.verifier, "PUT", "/items/1", "1700000000", "", "data", sig))
+	}
+
+	// Each verifier rejects the other algorithm's signatures.
+	sig, err := GenerateSignatureWithSigner(edSigner, "PUT", "/items/1", "1700000000", "", "data")
+	require.NoError(t, err)
+	assert.False(t, VerifySignatureWithVerifier(ecVerifier, "PUT", "/items/1", "1700000000", "", "data", sig))
+}
+
+func TestSigners_InvalidKeys(t *testing.T) {
+	_, err := NewHMACSigner(SignatureAlgorithmHMACSHA256, "")
+	assert.Error(t, err)
+	_, err = NewHMACSigner(SignatureAlgorithmEd25519, "secret")
+	assert.Error(t, err)
+	_, err = NewEd25519Signer(ed25519.PrivateKey("short"))
+	assert.Error(t, err)
+	_, err = NewEd25519Verifier(ed25519.PublicKey("short"))
+	assert.Error(t, err)
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	_, err = NewECDSAP256Signer(p384)
+	assert.Error(t, err)
+	_, err = NewECDSAP256Verifier(&p384.PublicKey)
+	assert.Error(t, err)
+	_, err = NewECDSAP256Signer(nil)
+	assert.Error(t, err)
+}
+
+func TestSigners_KeyringMiddlewareTransport(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := NewEd25519Signer(priv)
+	require.NoError(t, err)
+	verifier, err := NewEd25519Verifier(pub)
+	require.NoError(t, err)
+
+	// The server only holds the partner's public key.
+	serverKeys, err := NewKeyring(SigningKey{ID: "partner", Verifier: verifier})
+	require.NoError(t, err)
+	assert.Error(t, serverKeys.SetState("partner", KeyStatePrimary))
+	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+		Keyring:    serverKeys,
+		NonceStore: NewMemoryNonceStore(MemoryNonceStoreOptions{}),
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(mw(echoHandler))
+	defer server.Close()
+
+	transport, err := NewSignatureTransport(SignatureTransportOptions{KeyID: "partner", Signer: signer, Nonce: true})
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: transport}).Post(server.URL+"/api", "text/plain", strings.NewReader("data"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	got, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "partner:data", string(got))
+
+	// A keyring with an Ed25519 primary signs with it.
+	clientKeys, err := NewKeyring(SigningKey{ID: "partner", Signer: signer, State: KeyStatePrimary})
+	require.NoError(t, err)
+	keyID, sig, err := clientKeys.Sign("GET", "/", "1700000000", "")
+	require.NoError(t, err)
+	matched, ok := serverKeys.Verify(keyID, "GET", "/", "1700000000", "", sig)
+	assert.True(t, ok)
+	assert.Equal(t, "partner", matched)
+
+	// Keys need exactly one of Secret, Signer or Verifier.
+	_, err = NewKeyring(SigningKey{ID: "both", Secret: "s", Signer: signer})
+	assert.Error(t, err)
+	_, err = NewKeyring(SigningKey{ID: "verify-only", Verifier: verifier, State: KeyStatePrimary})
+	assert.Error(t, err)
+	_, err = NewSignatureTransport(SignatureTransportOptions{Secret: "s", Signer: signer})
+	assert.Error(t, err)
+}
@@ -0,0 +1,124 @@
+package xgen
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+)
+
+// SignatureWriter computes a GenerateSignature signature over a body that is
+// written to it in chunks, so large bodies need not be held in memory.
+// It implements hash.Hash; Sum returns the raw HMAC-SHA256.
+type SignatureWriter struct {
+	mac    hash.Hash
+	prefix []byte
+}
+
+// NewSignatureWriter returns a SignatureWriter for the request components.
+// After the whole body has been written, Signature returns the same value as
+// GenerateSignature(secret, method, path, timestamp, body).
+//
+// Example:
+//
+//	w, err := NewSignatureWriter(secret, "PUT", "/files/1", timestamp)
+//	_, err = io.Copy(w, file)
+//	signature := w.Signature()
+func NewSignatureWriter(secret, method, path, timestamp string) (*SignatureWriter, error) {
+	if secret == "" || method == "" || path == "" || timestamp == "" {
+		return nil, errors.New("missing required fields for signature")
+	}
+	w := &SignatureWriter{
+		mac:    hmac.New(sha256.New, []byte(secret)),
+		prefix: []byte(BuildSignatureCanonicalString(method, path, timestamp, "")),
+	}
+	w.mac.Write(w.prefix)
+	return w, nil
+}
+
+// Write adds body bytes to the signature. It never returns an error.
+func (w *SignatureWriter) Write(p []byte) (int, error) {
+	return w.mac.Write(p)
+}
+
+// Sum appends the raw signature of the body written so far to b.
+func (w *SignatureWriter) Sum(b []byte) []byte {
+	return w.mac.Sum(b)
+}
+
+// Reset discards the body written so far, keeping the request components.
+func (w *SignatureWriter) Reset() {
+	w.mac.Reset()
+	w.mac.Write(w.prefix)
+}
+
+// Size returns the length of the raw signature in bytes.
+func (w *SignatureWriter) Size() int {
+	return w.mac.Size()
+}
+
+// BlockSize returns the block size of the underlying hash.
+func (w *SignatureWriter) BlockSize() int {
+	return w.mac.BlockSize()
+}
+
+// Signature returns the hex signature of the body written so far.
+func (w *SignatureWriter) Signature() string {
+	return hex.EncodeToString(w.mac.Sum(nil))
+}
+
+// Verify reports whether receivedSig matches the body written so far.
+// Uses constant-time comparison to prevent timing attacks.
+func (w *SignatureWriter) Verify(receivedSig string) bool {
+	received, err := hex.DecodeString(receivedSig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(w.mac.Sum(nil), received)
+}
+
+// signatureReader verifies a body against its signature as it is read.
+type signatureReader struct {
+	r           io.Reader
+	w           *SignatureWriter
+	receivedSig string
+	err         error
+}
+
+// NewSignatureReader returns a reader that reads the body from r and, at EOF,
+// returns ErrInvalidSignature instead of io.EOF unless receivedSig is the
+// GenerateSignature signature of the body and request components.
+//
+// Consumers must not act on the body before reading it to EOF.
+//
+// Example:
+//
+//	body, err := NewSignatureReader(r.Body, secret, r.Method, r.URL.Path, timestamp, signature)
+//	if _, err := io.Copy(dst, body); errors.Is(err, ErrInvalidSignature) {
+//		// discard dst
+//	}
+func NewSignatureReader(r io.Reader, secret, method, path, timestamp, receivedSig string) (io.Reader, error) {
+	w, err := NewSignatureWriter(secret, method, path, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	return &signatureReader{r: r, w: w, receivedSig: receivedSig}, nil
+}
+
+// Read reads from the body and checks the signature at EOF.
+func (s *signatureReader) Read(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	n, err := s.r.Read(p)
+	s.w.Write(p[:n])
+	if err == io.EOF && !s.w.Verify(s.receivedSig) {
+		err = ErrInvalidSignature
+	}
+	if err != nil {
+		s.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,75 @@
+package xgen
+
+import (
+	"hash"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureWriter(t *testing.T) {
+	body := strings.Repeat(`{"chunk":"data"}`, 1000)
+	expected, err := GenerateSignature("secret", "PUT", "/files/1", "1700000000", body)
+	require.NoError(t, err)
+
+	w, err := NewSignatureWriter("secret", "PUT", "/files/1", "1700000000")
+	require.NoError(t, err)
+	var _ hash.Hash = w
+	_, err = io.Copy(w, iotest.OneByteReader(strings.NewReader(body)))
+	require.NoError(t, err)
+	assert.Equal(t, expected, w.Signature())
+	assert.True(t, w.Verify(expected))
+	assert.False(t, w.Verify("not-hex"))
+	assert.Equal(t, 32, w.Size())
+	assert.Equal(t, 32, len(w.Sum(nil)))
+
+	// Reset keeps the request components.
+	w.Reset()
+	empty, err := GenerateSignature("secret", "PUT", "/files/1", "1700000000", "")
+	require.NoError(t, err)
+	assert.Equal(t, empty, w.Signature())
+	w.Write([]byte("x"))
+	assert.False(t, w.Verify(expected))
+
+	_, err = NewSignatureWriter("", "PUT", "/files/1", "1700000000")
+	assert.Error(t, err)
+}
+
+func TestSignatureReader(t *testing.T) {
+	body := strings.Repeat("payload ", 4096)
+	sig, err := GenerateSignature("secret", "POST", "/upload", "1700000000", body)
+	require.NoError(t, err)
+
+	r, err := NewSignatureReader(strings.NewReader(body), "secret", "POST", "/upload", "1700000000", sig)
+	require.NoError(t, err)
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(got))
+
+	// Mismatches are reported at EOF, and on every later read.
+	r, err = NewSignatureReader(strings.NewReader(body+"!"), "secret", "POST", "/upload", "1700000000", sig)
+	require.NoError(t, err)
+	got, err = io.ReadAll(r)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	assert.Equal(t, body+"!", string(got))
+	_, err = r.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	r, err = NewSignatureReader(strings.NewReader(body), "secret", "POST", "/upload", "1700000000", "zz")
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	// Read errors other than EOF are passed through.
+	r, err = NewSignatureReader(errReader{}, "secret", "POST", "/upload", "1700000000", sig)
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	assert.EqualError(t, err, "read failed")
+
+	_, err = NewSignatureReader(strings.NewReader(body), "secret", "", "/upload", "1700000000", sig)
+	assert.Error(t, err)
+}
@@ -0,0 +1,140 @@
+package xgen
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TimestampFormat selects how signature timestamps are parsed.
+type TimestampFormat int
+
+const (
+	// TimestampFormatUnix accepts Unix seconds only, as produced by the signing
+	// helpers. It is the zero value, so policies default to it.
+	TimestampFormatUnix TimestampFormat = iota
+	// TimestampFormatUnixMilli accepts Unix milliseconds only.
+	TimestampFormatUnixMilli
+	// TimestampFormatRFC3339 accepts RFC 3339 times only, e.g. "2024-01-01T00:00:00Z".
+	TimestampFormatRFC3339
+	// TimestampFormatAuto accepts Unix seconds, Unix milliseconds or RFC 3339.
+	// Integers of 1e11 or more are read as milliseconds. It must be chosen
+	// explicitly, for clients that do not agree on one format.
+	TimestampFormatAuto
+)
+
+// unixMilliThreshold separates seconds from milliseconds in TimestampFormatAuto.
+// Unix seconds reach it in the year 5138; Unix milliseconds passed it in 1973.
+const unixMilliThreshold = 100_000_000_000
+
+// TimestampPolicy configures how signature timestamps are parsed and how far
+// they may be from the current time. Past and future windows are separate,
+// so slow or retried requests can be accepted without trusting clients whose
+// clocks run ahead.
+type TimestampPolicy struct {
+	// Format is the accepted timestamp format. Defaults to TimestampFormatUnix.
+	Format TimestampFormat
+	// MaxAge is how far in the past a timestamp may be.
+	// Defaults to 5 minutes, or AllowedDrift where one is configured.
+	MaxAge time.Duration
+	// MaxFuture is how far in the future a timestamp may be.
+	// Defaults to 5 minutes, or AllowedDrift where one is configured.
+	MaxFuture time.Duration
+	// Now returns the current time. Defaults to time.Now; override it in tests.
+	Now func() time.Time
+}
+
+// ParseSignatureTimestamp parses a signature timestamp in the given format.
+// It returns ErrInvalidSignatureTimestamp when the timestamp does not match.
+//
+// Example:
+//
+//	t, err := ParseSignatureTimestamp("1704067200000", TimestampFormatAuto) // 2024-01-01T00:00:00Z
+func ParseSignatureTimestamp(timestamp string, format TimestampFormat) (time.Time, error) {
+	switch format {
+	case TimestampFormatAuto:
+		if n, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
+			if n >= unixMilliThreshold || n <= -unixMilliThreshold {
+				return time.UnixMilli(n), nil
+			}
+			return time.Unix(n, 0), nil
+		}
+		return ParseSignatureTimestamp(timestamp, TimestampFormatRFC3339)
+	case TimestampFormatUnix, TimestampFormatUnixMilli:
+		n, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return time.Time{}, ErrInvalidSignatureTimestamp
+		}
+		if format == TimestampFormatUnixMilli {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	case TimestampFormatRFC3339:
+		t, err := time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			return time.Time{}, ErrInvalidSignatureTimestamp
+		}
+		return t, nil
+	}
+	return time.Time{}, ValidateTimestampFormat(format)
+}
+
+// ValidateSignatureTimestamp checks a timestamp against policy and returns its
+// skew, the current time minus the timestamp. It returns
+// ErrInvalidSignatureTimestamp when the timestamp cannot be parsed and a
+// *TimestampSkewError (ErrTimestampExpired or ErrTimestampInFuture) when it
+// is outside the window.
+//
+// Example:
+//
+//	skew, err := ValidateSignatureTimestamp(r.Header.Get("X-Timestamp"), TimestampPolicy{
+//		MaxAge:    15 * time.Minute,
+//		MaxFuture: 30 * time.Second,
+//	})
+func ValidateSignatureTimestamp(timestamp string, policy TimestampPolicy) (time.Duration, error) {
+	if err := ValidateTimestampFormat(policy.Format); err != nil {
+		return 0, err
+	}
+	_, skew, err := policy.withDrift(DefaultSignatureDrift).check(timestamp)
+	return skew, err
+}
+
+// ValidateTimestampFormat returns an error when format is not a known
+// TimestampFormat, for validating options before any timestamp is parsed.
+func ValidateTimestampFormat(format TimestampFormat) error {
+	if format < TimestampFormatUnix || format > TimestampFormatAuto {
+		return fmt.Errorf("unsupported timestamp format %d", int(format))
+	}
+	return nil
+}
+
+// withDrift returns p with unset windows set to drift and the clock defaulted.
+func (p TimestampPolicy) withDrift(drift time.Duration) TimestampPolicy {
+	if p.MaxAge <= 0 {
+		p.MaxAge = drift
+	}
+	if p.MaxFuture <= 0 {
+		p.MaxFuture = drift
+	}
+	if p.Now == nil {
+		p.Now = time.Now
+	}
+	return p
+}
+
+// check parses timestamp and returns it with its skew from p.Now, or a
+// *TimestampSkewError when it is outside the window. Windows and clock must be set.
+func (p TimestampPolicy) check(timestamp string) (time.Time, time.Duration, error) {
+	ts, err := ParseSignatureTimestamp(timestamp, p.Format)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidSignatureTimestamp
+	}
+	skew := p.Now().Sub(ts)
+	switch {
+	case skew > p.MaxAge:
+		return ts, skew, &TimestampSkewError{Err: ErrTimestampExpired, Skew: skew, AllowedDrift: p.MaxAge}
+	case skew < -p.MaxFuture:
+		return ts, skew, &TimestampSkewError{Err: ErrTimestampInFuture, Skew: skew, AllowedDrift: p.MaxFuture}
+	}
+	return ts, skew, nil
+}
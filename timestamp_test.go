@@ -0,0 +1,183 @@
+package xgen
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSignatureTimestamp(t *testing.T) {
+	want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		timestamp string
+		format    TimestampFormat
+		ok        bool
+	}{
+		{"1704067200", TimestampFormatAuto, true},
+		{"1704067200000", TimestampFormatAuto, true},
+		{"2024-01-01T00:00:00Z", TimestampFormatAuto, true},
+		{"2024-01-01T07:00:00+07:00", TimestampFormatAuto, true},
+		{"1704067200", TimestampFormatUnix, true},
+		{"1704067200000", TimestampFormatUnixMilli, true},
+		{"2024-01-01T00:00:00Z", TimestampFormatRFC3339, true},
+		{"2024-01-01T00:00:00Z", TimestampFormatUnix, false},
+		{"1704067200", TimestampFormatRFC3339, false},
+		{"2024-01-01", TimestampFormatAuto, false},
+		{"", TimestampFormatAuto, false},
+		{"1.5", TimestampFormatAuto, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSignatureTimestamp(tt.timestamp, tt.format)
+		if !tt.ok {
+			assert.ErrorIs(t, err, ErrInvalidSignatureTimestamp, tt.timestamp)
+			continue
+		}
+		require.NoError(t, err, tt.timestamp)
+		assert.True(t, want.Equal(got), tt.timestamp)
+	}
+
+	got, err := ParseSignatureTimestamp("2024-01-01T00:00:00.250Z", TimestampFormatAuto)
+	require.NoError(t, err)
+	assert.Equal(t, 250*time.Millisecond, got.Sub(want))
+
+	_, err = ParseSignatureTimestamp("1704067200", TimestampFormat(99))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidSignatureTimestamp)
+}
+
+func TestValidateTimestampFormat(t *testing.T) {
+	for _, format := range []TimestampFormat{TimestampFormatUnix, TimestampFormatUnixMilli, TimestampFormatRFC3339, TimestampFormatAuto} {
+		assert.NoError(t, ValidateTimestampFormat(format))
+	}
+	assert.Error(t, ValidateTimestampFormat(TimestampFormat(-1)))
+	assert.Error(t, ValidateTimestampFormat(TimestampFormatAuto+1))
+}
+
+func TestValidateSignatureTimestamp(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := TimestampPolicy{
+		Format:    TimestampFormatAuto,
+		MaxAge:    15 * time.Minute,
+		MaxFuture: 30 * time.Second,
+		Now:       func() time.Time { return now },
+	}
+
+	skew, err := ValidateSignatureTimestamp(strconv.FormatInt(now.Add(-10*time.Minute).UnixMilli(), 10), policy)
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, skew)
+	skew, err = ValidateSignatureTimestamp(now.Add(20*time.Second).Format(time.RFC3339), policy)
+	require.NoError(t, err)
+	assert.Equal(t, -20*time.Second, skew)
+
+	_, err = ValidateSignatureTimestamp(strconv.FormatInt(now.Add(-16*time.Minute).Unix(), 10), policy)
+	var skewErr *TimestampSkewError
+	require.True(t, errors.As(err, &skewErr))
+	assert.ErrorIs(t, err, ErrTimestampExpired)
+	assert.Equal(t, 16*time.Minute, skewErr.Skew)
+	assert.Equal(t, 15*time.Minute, skewErr.AllowedDrift)
+
+	_, err = ValidateSignatureTimestamp(strconv.FormatInt(now.Add(time.Minute).Unix(), 10), policy)
+	require.True(t, errors.As(err, &skewErr))
+	assert.ErrorIs(t, err, ErrTimestampInFuture)
+	assert.Equal(t, -time.Minute, skewErr.Skew)
+	assert.Equal(t, 30*time.Second, skewErr.AllowedDrift)
+
+	// Windows default to ±5 minutes.
+	policy = TimestampPolicy{Format: TimestampFormatAuto, Now: policy.Now}
+	_, err = ValidateSignatureTimestamp(now.Add(-4*time.Minute).Format(time.RFC3339), policy)
+	assert.NoError(t, err)
+	_, err = ValidateSignatureTimestamp(now.Add(6*time.Minute).Format(time.RFC3339), policy)
+	assert.ErrorIs(t, err, ErrTimestampInFuture)
+
+	// The format defaults to Unix seconds: other formats must be opted into.
+	policy = TimestampPolicy{Now: policy.Now}
+	_, err = ValidateSignatureTimestamp(strconv.FormatInt(now.Unix(), 10), policy)
+	assert.NoError(t, err)
+	_, err = ValidateSignatureTimestamp(now.Format(time.RFC3339), policy)
+	assert.ErrorIs(t, err, ErrInvalidSignatureTimestamp)
+	_, err = ValidateSignatureTimestamp(strconv.FormatInt(now.UnixMilli(), 10), policy)
+	assert.ErrorIs(t, err, ErrTimestampInFuture)
+	policy.Format = TimestampFormat(99)
+	_, err = ValidateSignatureTimestamp(strconv.FormatInt(now.Unix(), 10), policy)
+	assert.Error(t, err)
+}
+
+func TestVerifyRequest_TimestampPolicy(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts := strconv.FormatInt(now.Add(-20*time.Minute).UnixMilli(), 10)
+	sig, err := GenerateSignature("secret-1", "GET", "/", ts, "")
+	require.NoError(t, err)
+	req := SignedRequest{KeyID: "client-1", Method: "GET", Path: "/", Timestamp: ts, Signature: sig}
+	opts := VerifyRequestOptions{
+		LookupSecret: MapSecretLookup(map[string]string{"client-1": "secret-1"}),
+		Timestamp:    TimestampPolicy{Format: TimestampFormatAuto, MaxAge: time.Hour, Now: func() time.Time { return now }},
+	}
+
+	result, err := VerifyRequest(context.Background(), req, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 20*time.Minute, result.Skew)
+
+	// Without TimestampFormatAuto the milliseconds are read as seconds.
+	unix := opts
+	unix.Timestamp.Format = TimestampFormatUnix
+	_, err = VerifyRequest(context.Background(), req, unix)
+	assert.ErrorIs(t, err, ErrTimestampInFuture)
+
+	opts.Timestamp.MaxAge = 0
+	_, err = VerifyRequest(context.Background(), req, opts)
+	assert.ErrorIs(t, err, ErrTimestampExpired)
+
+	opts.Timestamp.Format = TimestampFormat(99)
+	_, err = VerifyRequest(context.Background(), req, opts)
+	assert.Error(t, err)
+}
+
+func TestNewSignatureMiddleware_TimestampPolicy(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryNonceStore(MemoryNonceStoreOptions{})
+	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret: MapSecretLookup(map[string]string{"client-1": "secret-1"}),
+		NonceStore:   store,
+		Timestamp: TimestampPolicy{
+			Format:    TimestampFormatAuto,
+			MaxAge:    time.Hour,
+			MaxFuture: time.Second,
+			Now:       func() time.Time { return now },
+		},
+	})
+	require.NoError(t, err)
+	handler := mw(echoHandler)
+
+	send := func(timestamp, nonce string) int {
+		sig, err := GenerateSignatureWithNonce("secret-1", "GET", "/", timestamp, nonce, "")
+		require.NoError(t, err)
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(DefaultSignatureHeader, sig)
+		req.Header.Set(DefaultSignatureTimestampHeader, timestamp)
+		req.Header.Set(DefaultSignatureKeyIDHeader, "client-1")
+		req.Header.Set(DefaultSignatureNonceHeader, nonce)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send(now.Add(-30*time.Minute).UTC().Format(time.RFC3339), "n-1"))
+	assert.Equal(t, http.StatusOK, send(strconv.FormatInt(now.Add(-30*time.Minute).UnixMilli(), 10), "n-2"))
+	assert.Equal(t, http.StatusUnauthorized, send(strconv.FormatInt(now.Add(-2*time.Hour).Unix(), 10), "n-3"))
+	assert.Equal(t, http.StatusUnauthorized, send(strconv.FormatInt(now.Add(time.Minute).Unix(), 10), "n-4"))
+	// Nonces stay remembered for the whole past window.
+	assert.Equal(t, http.StatusUnauthorized, send(strconv.FormatInt(now.Add(-59*time.Minute).Unix(), 10), "n-1"))
+
+	_, err = NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret: MapSecretLookup(nil),
+		Timestamp:    TimestampPolicy{Format: TimestampFormat(99)},
+	})
+	assert.Error(t, err)
+}
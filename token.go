@@ -0,0 +1,283 @@
+package xgen
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Token versions, the first field of every token.
+const (
+	tokenVersion          = "t1"
+	tokenVersionEncrypted = "t1e"
+)
+
+// tokenSignaturePrefix is prepended to the signed part of a token, so token
+// signatures cannot be confused with request or URL signatures.
+const tokenSignaturePrefix = "xgen-token\n"
+
+// Errors returned by VerifyToken.
+var (
+	// ErrTokenMalformed is returned when a token cannot be parsed, or is not
+	// encrypted as the verifier expects.
+	ErrTokenMalformed = errors.New("malformed token")
+	// ErrTokenInvalid is returned when the token signature does not match.
+	ErrTokenInvalid = errors.New("invalid token signature")
+	// ErrTokenExpired is returned when the token's expiry time has passed.
+	ErrTokenExpired = errors.New("token expired")
+	// ErrTokenPurposeMismatch is returned when the token was issued for
+	// another purpose, e.g. an email verification token used to reset a password.
+	ErrTokenPurposeMismatch = errors.New("token purpose mismatch")
+)
+
+// TokenClaims are the claims carried by a token.
+type TokenClaims struct {
+	// ID uniquely identifies the token. IssueToken generates one when empty.
+	ID string
+	// Subject is who the token is for, e.g. a user ID.
+	Subject string
+	// Purpose is what the token may be used for, e.g. "password-reset". Required.
+	Purpose string
+	// IssuedAt is when the token was issued. IssueToken sets it.
+	IssuedAt time.Time
+	// ExpiresAt is when the token stops being valid. Required.
+	ExpiresAt time.Time
+	// Data holds additional application claims.
+	Data map[string]string
+}
+
+// tokenPayload is the JSON encoding of TokenClaims.
+type tokenPayload struct {
+	ID        string            `json:"jti"`
+	Subject   string            `json:"sub,omitempty"`
+	Purpose   string            `json:"pur"`
+	IssuedAt  int64             `json:"iat"`
+	ExpiresAt int64             `json:"exp"`
+	Data      map[string]string `json:"dat,omitempty"`
+}
+
+// IssueTokenOptions configures IssueToken.
+type IssueTokenOptions struct {
+	// Secret is the HMAC-SHA256 signing secret.
+	// Exactly one of Secret or Keyring is required.
+	Secret string
+	// KeyID is sent in the token with Secret so verifiers can select the secret.
+	KeyID string
+	// Keyring signs with its primary key and sends that key's ID.
+	Keyring *Keyring
+	// Encryption, when set, encrypts the claims with its primary key so they
+	// cannot be read from the token.
+	Encryption *EncryptionKeyring
+	// Now returns the issue time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// VerifyTokenOptions configures VerifyToken.
+type VerifyTokenOptions struct {
+	// Purpose is the purpose the token must have been issued for. Required.
+	Purpose string
+	// LookupSecret resolves the secret for the key ID in the token.
+	// Exactly one of LookupSecret or Keyring is required.
+	LookupSecret SecretLookupFunc
+	// Keyring verifies against its active keys instead of LookupSecret.
+	Keyring *Keyring
+	// Encryption decrypts the claims. When set, unencrypted tokens are
+	// rejected; when nil, encrypted tokens are rejected.
+	Encryption *EncryptionKeyring
+	// NonceStore, when set, makes tokens single use: a token ID seen before
+	// is rejected with ErrReplayedNonce.
+	NonceStore NonceStore
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// IssueToken returns a compact, URL-safe token carrying claims, signed with
+// HMAC-SHA256 (or the Signer of the keyring's primary key).
+//
+// The token is "t1.<key ID>.<payload>.<signature>" with base64url JSON
+// claims, or "t1e.<key ID>.<envelope>.<signature>" with claims sealed by
+// Encryption. It is a bearer credential: keep expiry times short.
+//
+// Example:
+//
+//	token, err := IssueToken(TokenClaims{
+//		Subject:   userID,
+//		Purpose:   "password-reset",
+//		ExpiresAt: time.Now().Add(30 * time.Minute),
+//	}, IssueTokenOptions{Secret: secret})
+func IssueToken(claims TokenClaims, opts IssueTokenOptions) (string, error) {
+	if (opts.Secret == "") == (opts.Keyring == nil) {
+		return "", errors.New("token issuing requires exactly one of Secret or Keyring")
+	}
+	if claims.Purpose == "" || claims.ExpiresAt.IsZero() {
+		return "", errors.New("token requires Purpose and ExpiresAt")
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	var signer Signer
+	keyID := opts.KeyID
+	if opts.Keyring != nil {
+		key, err := opts.Keyring.Primary()
+		if err != nil {
+			return "", err
+		}
+		if signer, err = KeySigner(key); err != nil {
+			return "", err
+		}
+		keyID = key.ID
+	} else {
+		var err error
+		if signer, err = NewHMACSigner(SignatureAlgorithmHMACSHA256, opts.Secret); err != nil {
+			return "", err
+		}
+	}
+	if strings.Contains(keyID, ".") {
+		return "", fmt.Errorf("token key ID %q must not contain '.'", keyID)
+	}
+
+	if claims.ID == "" {
+		id, err := GenerateSignatureNonce()
+		if err != nil {
+			return "", err
+		}
+		claims.ID = id
+	}
+	payload, err := json.Marshal(tokenPayload{
+		ID:        claims.ID,
+		Subject:   claims.Subject,
+		Purpose:   claims.Purpose,
+		IssuedAt:  opts.Now().Unix(),
+		ExpiresAt: claims.ExpiresAt.Unix(),
+		Data:      claims.Data,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	version, encoded := tokenVersion, base64.RawURLEncoding.EncodeToString(payload)
+	if opts.Encryption != nil {
+		version = tokenVersionEncrypted
+		if encoded, err = opts.Encryption.Seal(payload, []byte(tokenSignaturePrefix+keyID)); err != nil {
+			return "", err
+		}
+	}
+	signed := version + "." + keyID + "." + encoded
+	signature, err := signer.Sign([]byte(tokenSignaturePrefix + signed))
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyToken checks a token created by IssueToken and returns its claims.
+// The signature is checked before the claims are decoded. Errors match:
+//
+//   - ErrTokenMalformed when the token cannot be parsed
+//   - ErrUnknownKey, or any error of LookupSecret, when the key is not found
+//   - ErrTokenInvalid when the signature does not match
+//   - ErrTokenPurposeMismatch when the token was issued for another purpose
+//   - ErrTokenExpired when the token has expired
+//   - ErrReplayedNonce when NonceStore has seen the token before
+//
+// Example:
+//
+//	claims, err := VerifyToken(ctx, r.URL.Query().Get("token"), VerifyTokenOptions{
+//		Purpose:      "password-reset",
+//		LookupSecret: StaticSecretLookup(secret),
+//		NonceStore:   store,
+//	})
+func VerifyToken(ctx context.Context, token string, opts VerifyTokenOptions) (*TokenClaims, error) {
+	if (opts.LookupSecret == nil) == (opts.Keyring == nil) {
+		return nil, errors.New("token verification requires exactly one of LookupSecret or Keyring")
+	}
+	if opts.Purpose == "" {
+		return nil, errors.New("token verification requires Purpose")
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	// Split from both ends: an encrypted payload is an envelope containing dots.
+	version, rest, _ := strings.Cut(token, ".")
+	keyID, rest, _ := strings.Cut(rest, ".")
+	i := strings.LastIndex(rest, ".")
+	if i <= 0 {
+		return nil, ErrTokenMalformed
+	}
+	signed, encoded := token[:len(token)-len(rest)+i], rest[:i]
+	signature, err := base64.RawURLEncoding.DecodeString(rest[i+1:])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	switch {
+	case version == tokenVersion && opts.Encryption == nil:
+	case version == tokenVersionEncrypted && opts.Encryption != nil:
+	default:
+		return nil, ErrTokenMalformed
+	}
+
+	message := []byte(tokenSignaturePrefix + signed)
+	verify := func(key SigningKey) bool {
+		verifier := KeyVerifier(key)
+		return verifier != nil && verifier.Verify(message, signature)
+	}
+	if opts.Keyring != nil {
+		key, err := opts.Keyring.Match(keyID, verify)
+		if errors.Is(err, ErrSignatureMismatch) {
+			return nil, ErrTokenInvalid
+		}
+		if err != nil {
+			return nil, err
+		}
+		keyID = key.ID
+	} else {
+		secret, err := opts.LookupSecret(ctx, keyID)
+		if err != nil {
+			return nil, err
+		}
+		if secret == "" || !verify(SigningKey{Secret: secret}) {
+			return nil, ErrTokenInvalid
+		}
+	}
+
+	var payload []byte
+	if opts.Encryption != nil {
+		payload, err = opts.Encryption.Open(encoded, []byte(tokenSignaturePrefix+keyID))
+	} else {
+		payload, err = base64.RawURLEncoding.DecodeString(encoded)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenMalformed, err)
+	}
+	var p tokenPayload
+	if err := json.Unmarshal(payload, &p); err != nil || p.ID == "" {
+		return nil, ErrTokenMalformed
+	}
+
+	if p.Purpose != opts.Purpose {
+		return nil, ErrTokenPurposeMismatch
+	}
+	expiresAt := time.Unix(p.ExpiresAt, 0)
+	if !opts.Now().Before(expiresAt) {
+		return nil, ErrTokenExpired
+	}
+	if opts.NonceStore != nil {
+		if err := CheckNonce(ctx, opts.NonceStore, "token:"+p.Purpose, p.ID, expiresAt); err != nil {
+			return nil, err
+		}
+	}
+	return &TokenClaims{
+		ID:        p.ID,
+		Subject:   p.Subject,
+		Purpose:   p.Purpose,
+		IssuedAt:  time.Unix(p.IssuedAt, 0),
+		ExpiresAt: expiresAt,
+		Data:      p.Data,
+	}, nil
+}
@@ -0,0 +1,188 @@
+package xgen
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueVerifyToken(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	claims := TokenClaims{
+		Subject:   "user-42",
+		Purpose:   "password-reset",
+		ExpiresAt: now.Add(30 * time.Minute),
+		Data:      map[string]string{"email": "a@example.com"},
+	}
+
+	token, err := IssueToken(claims, IssueTokenOptions{Secret: "secret-1", KeyID: "k1", Now: clock})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, "t1.k1."), token)
+	assert.Equal(t, token, url64Safe(token))
+
+	opts := VerifyTokenOptions{Purpose: "password-reset", LookupSecret: MapSecretLookup(map[string]string{"k1": "secret-1"}), Now: clock}
+	got, err := VerifyToken(ctx, token, opts)
+	require.NoError(t, err)
+	assert.Len(t, got.ID, 32)
+	assert.Equal(t, "user-42", got.Subject)
+	assert.Equal(t, "password-reset", got.Purpose)
+	assert.Equal(t, now, got.IssuedAt)
+	assert.Equal(t, claims.ExpiresAt, got.ExpiresAt)
+	assert.Equal(t, claims.Data, got.Data)
+
+	// Each token gets a fresh ID.
+	other, err := IssueToken(claims, IssueTokenOptions{Secret: "secret-1", KeyID: "k1", Now: clock})
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+
+	wrongPurpose := opts
+	wrongPurpose.Purpose = "email-verification"
+	_, err = VerifyToken(ctx, token, wrongPurpose)
+	assert.ErrorIs(t, err, ErrTokenPurposeMismatch)
+
+	expired := opts
+	expired.Now = func() time.Time { return claims.ExpiresAt }
+	_, err = VerifyToken(ctx, token, expired)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+
+	_, err = VerifyToken(ctx, token, VerifyTokenOptions{Purpose: "password-reset", LookupSecret: StaticSecretLookup("secret-2"), Now: clock})
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+	_, err = VerifyToken(ctx, strings.Replace(token, "t1.k1.", "t1.k2.", 1), opts)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	// Changing the claims breaks the signature.
+	parts := strings.Split(token, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	parts[2] = base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(payload), "user-42", "user-43", 1)))
+	_, err = VerifyToken(ctx, strings.Join(parts, "."), opts)
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+
+	for _, bad := range []string{"", "t1", "t1.k1", "t1.k1.payload", "t1.k1.payload.!", "t2" + token[2:], "t1e" + token[2:]} {
+		_, err = VerifyToken(ctx, bad, opts)
+		assert.ErrorIs(t, err, ErrTokenMalformed, bad)
+	}
+}
+
+func TestVerifyToken_SingleUse(t *testing.T) {
+	ctx := context.Background()
+	token, err := IssueToken(TokenClaims{Purpose: "magic-link", ExpiresAt: time.Now().Add(time.Minute)}, IssueTokenOptions{Secret: "s"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, "t1.."), token)
+
+	opts := VerifyTokenOptions{Purpose: "magic-link", LookupSecret: StaticSecretLookup("s"), NonceStore: NewMemoryNonceStore(MemoryNonceStoreOptions{})}
+	_, err = VerifyToken(ctx, token, opts)
+	require.NoError(t, err)
+	_, err = VerifyToken(ctx, token, opts)
+	assert.ErrorIs(t, err, ErrReplayedNonce)
+}
+
+func TestIssueVerifyToken_Keyring(t *testing.T) {
+	ctx := context.Background()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edSigner, err := NewEd25519Signer(priv)
+	require.NoError(t, err)
+	keyring, err := NewKeyring(
+		SigningKey{ID: "2024-01", Secret: "old-secret"},
+		SigningKey{ID: "2024-06", Signer: edSigner, State: KeyStatePrimary},
+	)
+	require.NoError(t, err)
+	claims := TokenClaims{Subject: "user-42", Purpose: "email-verification", ExpiresAt: time.Now().Add(time.Hour)}
+
+	token, err := IssueToken(claims, IssueTokenOptions{Keyring: keyring})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, "t1.2024-06."), token)
+	got, err := VerifyToken(ctx, token, VerifyTokenOptions{Purpose: "email-verification", Keyring: keyring})
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", got.Subject)
+
+	// Tokens signed with an older key verify until that key is disabled.
+	old, err := IssueToken(claims, IssueTokenOptions{Secret: "old-secret", KeyID: "2024-01"})
+	require.NoError(t, err)
+	_, err = VerifyToken(ctx, old, VerifyTokenOptions{Purpose: "email-verification", Keyring: keyring})
+	require.NoError(t, err)
+	require.NoError(t, keyring.SetState("2024-01", KeyStateDisabled))
+	_, err = VerifyToken(ctx, old, VerifyTokenOptions{Purpose: "email-verification", Keyring: keyring})
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	// A signature from one key does not verify under another key ID.
+	_, err = VerifyToken(ctx, strings.Replace(old, "t1.2024-01.", "t1.2024-06.", 1), VerifyTokenOptions{Purpose: "email-verification", Keyring: keyring})
+	assert.ErrorIs(t, err, ErrTokenInvalid)
+}
+
+func TestIssueVerifyToken_Encrypted(t *testing.T) {
+	ctx := context.Background()
+	encryption, err := NewEncryptionKeyring(newTestEncryptionKey(t, "e1", EncryptionXChaCha20Poly1305, KeyStatePrimary))
+	require.NoError(t, err)
+	claims := TokenClaims{Subject: "user-42", Purpose: "invite", ExpiresAt: time.Now().Add(time.Hour), Data: map[string]string{"email": "a@example.com"}}
+
+	token, err := IssueToken(claims, IssueTokenOptions{Secret: "s", KeyID: "k1", Encryption: encryption})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, "t1e.k1.v1.xchacha20-poly1305.e1."), token)
+	assert.NotContains(t, token, "user-42")
+
+	opts := VerifyTokenOptions{Purpose: "invite", LookupSecret: StaticSecretLookup("s"), Encryption: encryption}
+	got, err := VerifyToken(ctx, token, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", got.Subject)
+	assert.Equal(t, claims.Data, got.Data)
+
+	// Encrypted and plain tokens are not interchangeable.
+	_, err = VerifyToken(ctx, token, VerifyTokenOptions{Purpose: "invite", LookupSecret: StaticSecretLookup("s")})
+	assert.ErrorIs(t, err, ErrTokenMalformed)
+	plain, err := IssueToken(claims, IssueTokenOptions{Secret: "s", KeyID: "k1"})
+	require.NoError(t, err)
+	_, err = VerifyToken(ctx, plain, opts)
+	assert.ErrorIs(t, err, ErrTokenMalformed)
+
+	// A validly signed token whose envelope cannot be opened is rejected.
+	other, err := NewEncryptionKeyring(newTestEncryptionKey(t, "e2", "", KeyStatePrimary))
+	require.NoError(t, err)
+	foreign, err := IssueToken(claims, IssueTokenOptions{Secret: "s", KeyID: "k1", Encryption: other})
+	require.NoError(t, err)
+	_, err = VerifyToken(ctx, foreign, opts)
+	assert.ErrorIs(t, err, ErrTokenMalformed)
+}
+
+func TestTokenOptions(t *testing.T) {
+	ctx := context.Background()
+	claims := TokenClaims{Purpose: "p", ExpiresAt: time.Now().Add(time.Minute)}
+	for _, tt := range []struct {
+		claims TokenClaims
+		opts   IssueTokenOptions
+	}{
+		{claims, IssueTokenOptions{}},
+		{claims, IssueTokenOptions{Secret: "s", Keyring: &Keyring{}}},
+		{claims, IssueTokenOptions{Secret: "s", KeyID: "a.b"}},
+		{claims, IssueTokenOptions{Keyring: &Keyring{now: time.Now}}},
+		{TokenClaims{ExpiresAt: claims.ExpiresAt}, IssueTokenOptions{Secret: "s"}},
+		{TokenClaims{Purpose: "p"}, IssueTokenOptions{Secret: "s"}},
+	} {
+		_, err := IssueToken(tt.claims, tt.opts)
+		assert.Error(t, err)
+	}
+
+	_, err := VerifyToken(ctx, "t1..e30.AA", VerifyTokenOptions{Purpose: "p"})
+	assert.Error(t, err)
+	_, err = VerifyToken(ctx, "t1..e30.AA", VerifyTokenOptions{LookupSecret: StaticSecretLookup("s")})
+	assert.Error(t, err)
+}
+
+// url64Safe drops every character outside the URL-safe base64 alphabet and '.'.
+func url64Safe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,279 @@
+package xgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureTransportOptions configures NewSignatureTransport.
+type SignatureTransportOptions struct {
+	// Secret is the HMAC-SHA256 signing secret used with GenerateSignature.
+	// Exactly one of Secret, Signer or Keyring is required.
+	Secret string
+	// Signer signs requests with GenerateSignatureWithSigner instead of Secret.
+	Signer Signer
+	// KeyID is sent in the key ID header when set.
+	KeyID string
+	// Keyring signs each request with its current primary key and sends that
+	// key's ID, instead of Secret and KeyID.
+	Keyring *Keyring
+	// ScopedKey returns the derived key for a request signed at now, for
+	// servers using the middleware's CredentialRegion and CredentialService.
+	// Its credential is sent in the credential header instead of a key ID.
+	// Implementations typically cache one key per day.
+	ScopedKey func(now time.Time) (*ScopedKey, error)
+	// CredentialHeader defaults to DefaultSignatureCredentialHeader.
+	CredentialHeader string
+	// Base is the underlying transport. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+	// SignatureHeader defaults to DefaultSignatureHeader.
+	SignatureHeader string
+	// TimestampHeader defaults to DefaultSignatureTimestampHeader.
+	TimestampHeader string
+	// KeyIDHeader defaults to DefaultSignatureKeyIDHeader.
+	KeyIDHeader string
+	// Nonce adds a fresh random nonce to every request and signs it with
+	// GenerateSignatureWithNonce, for servers that use a NonceStore.
+	Nonce bool
+	// NonceHeader defaults to DefaultSignatureNonceHeader.
+	NonceHeader string
+	// Version selects the canonical string format. Defaults to SignatureVersion1.
+	// SignatureVersion2 signs with GenerateSignatureV2; a Secret is used as an
+	// HMAC-SHA256 key.
+	Version SignatureVersion
+	// SignedHeaders lists the headers covered by a v2 signature, e.g. "host" and
+	// "content-type". Only headers in Request.Header, plus "host", can be signed.
+	SignedHeaders []string
+	// SignedHeadersHeader carries the v2 signed header list.
+	// Defaults to DefaultSignatureSignedHeadersHeader.
+	SignedHeadersHeader string
+	// ContentDigest, when set, sends an RFC 9530 Content-Digest header and signs
+	// it in place of the body, for servers using the middleware's ContentDigest
+	// option. The body is streamed rather than buffered when the request already
+	// has a Content-Digest header or a GetBody function to hash a second copy.
+	ContentDigest DigestAlgorithm
+	// CanonicalJSON signs the RFC 8785 canonical form of JSON bodies, for
+	// servers using the middleware's CanonicalJSON option. It applies to
+	// non-empty bodies with an application/json or "+json" Content-Type.
+	// The body is sent unchanged.
+	CanonicalJSON bool
+}
+
+// signatureTransport is the http.RoundTripper returned by NewSignatureTransport.
+type signatureTransport struct {
+	opts SignatureTransportOptions
+}
+
+// NewSignatureTransport returns an http.RoundTripper that signs every outgoing
+// request with GenerateSignature (or GenerateSignatureWithSigner when a Signer
+// is configured), for verification by NewSignatureMiddleware.
+//
+// The transport reads the request body, signs exactly those bytes together
+// with the method, URL path and current Unix timestamp, and sends a copy of
+// the request with the body replaced and the timestamp, signature and key ID
+// headers set. The copy has GetBody set, so the base transport can retry it
+// and each redirect hop is signed again for its own path. The caller's
+// request is not modified. With ContentDigest the Content-Digest header is
+// signed instead of the body, which is sent unchanged when possible.
+//
+// Example:
+//
+//	transport, err := NewSignatureTransport(SignatureTransportOptions{KeyID: "client-1", Secret: secret})
+//	client := &http.Client{Transport: transport}
+//	resp, err := client.Post(url, "application/json", body)
+func NewSignatureTransport(opts SignatureTransportOptions) (http.RoundTripper, error) {
+	if countSet(opts.Secret != "", opts.Signer != nil, opts.Keyring != nil, opts.ScopedKey != nil) != 1 {
+		return nil, errors.New("signature transport requires exactly one of Secret, Signer, Keyring or ScopedKey")
+	}
+	if opts.Base == nil {
+		opts.Base = http.DefaultTransport
+	}
+	if opts.SignatureHeader == "" {
+		opts.SignatureHeader = DefaultSignatureHeader
+	}
+	if opts.TimestampHeader == "" {
+		opts.TimestampHeader = DefaultSignatureTimestampHeader
+	}
+	if opts.KeyIDHeader == "" {
+		opts.KeyIDHeader = DefaultSignatureKeyIDHeader
+	}
+	if opts.NonceHeader == "" {
+		opts.NonceHeader = DefaultSignatureNonceHeader
+	}
+	if opts.SignedHeadersHeader == "" {
+		opts.SignedHeadersHeader = DefaultSignatureSignedHeadersHeader
+	}
+	if opts.CredentialHeader == "" {
+		opts.CredentialHeader = DefaultSignatureCredentialHeader
+	}
+	if opts.ScopedKey != nil && opts.Version != SignatureVersion1 {
+		return nil, errors.New("scoped signatures require SignatureVersion1")
+	}
+	switch opts.Version {
+	case SignatureVersion1:
+	case SignatureVersion2:
+		names, err := CanonicalHeaderNames(opts.SignedHeaders)
+		if err != nil {
+			return nil, err
+		}
+		opts.SignedHeaders = names
+	default:
+		return nil, fmt.Errorf("unsupported signature version %d", int(opts.Version))
+	}
+	if opts.ContentDigest != "" {
+		if _, err := newDigestHash(opts.ContentDigest); err != nil {
+			return nil, err
+		}
+		if opts.CanonicalJSON {
+			return nil, errors.New("signature transport cannot combine CanonicalJSON and ContentDigest")
+		}
+	}
+	return &signatureTransport{opts: opts}, nil
+}
+
+// RoundTrip signs a copy of req and sends it with the base transport.
+func (t *signatureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	signed, err := t.sign(req)
+	if err != nil {
+		// RoundTrippers must close the request body, even on errors.
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.opts.Base.RoundTrip(signed)
+}
+
+// sign returns a signed copy of req.
+func (t *signatureTransport) sign(req *http.Request) (*http.Request, error) {
+	var body []byte
+	var signedBody, digest string
+	var err error
+	if t.opts.ContentDigest != "" {
+		digest, body, err = outgoingContentDigest(req, t.opts.ContentDigest)
+		signedBody = digest
+	} else {
+		body, err = readOutgoingBody(req)
+		signedBody = string(body)
+		if err == nil && t.opts.CanonicalJSON && isJSONContentType(req.Header.Get("Content-Type")) {
+			signedBody, err = canonicalJSONBody(signedBody)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	key := SigningKey{ID: t.opts.KeyID, Secret: t.opts.Secret, Signer: t.opts.Signer}
+	if t.opts.Keyring != nil {
+		if key, err = t.opts.Keyring.Primary(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Set the body and digest before signing, so signed headers such as
+	// content-digest cover what is sent.
+	signed := req.Clone(req.Context())
+	if body != nil {
+		signed.Body = io.NopCloser(bytes.NewReader(body))
+		signed.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		signed.ContentLength = int64(len(body))
+	}
+	if digest != "" {
+		signed.Header.Set(ContentDigestHeader, digest)
+	}
+
+	// Servers see an empty path as "/", so sign it that way.
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	var nonce, signature, credential string
+	if t.opts.Nonce {
+		if nonce, err = GenerateSignatureNonce(); err != nil {
+			return nil, err
+		}
+	}
+	if t.opts.ScopedKey != nil {
+		var scoped *ScopedKey
+		if scoped, err = t.opts.ScopedKey(now); err == nil {
+			credential = scoped.Credential()
+			signature, err = scoped.Sign(req.Method, path, timestamp, nonce, signedBody)
+		}
+	} else if t.opts.Version == SignatureVersion2 {
+		var signer Signer
+		if signer, err = KeySigner(key); err == nil {
+			sreq := NewSignatureRequest(signed, t.opts.SignedHeaders, timestamp, nonce, signedBody)
+			signature, err = GenerateSignatureV2(signer, sreq)
+		}
+	} else {
+		signature, err = signWithKey(key, req.Method, path, timestamp, nonce, signedBody)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signed.Header.Set(t.opts.TimestampHeader, timestamp)
+	signed.Header.Set(t.opts.SignatureHeader, signature)
+	if key.ID != "" {
+		signed.Header.Set(t.opts.KeyIDHeader, key.ID)
+	}
+	if credential != "" {
+		signed.Header.Set(t.opts.CredentialHeader, credential)
+	}
+	if nonce != "" {
+		signed.Header.Set(t.opts.NonceHeader, nonce)
+	}
+	if t.opts.Version == SignatureVersion2 {
+		signed.Header.Set(t.opts.SignedHeadersHeader, strings.Join(t.opts.SignedHeaders, ";"))
+	}
+	return signed, nil
+}
+
+// outgoingContentDigest returns the Content-Digest of an outgoing request body.
+// A Content-Digest header set by the caller is used as is; otherwise the body
+// is hashed from a GetBody copy, or read into memory (and returned) when the
+// request has no GetBody.
+func outgoingContentDigest(req *http.Request, alg DigestAlgorithm) (string, []byte, error) {
+	if digest := req.Header.Get(ContentDigestHeader); digest != "" {
+		return digest, nil, nil
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		digest, err := ContentDigest(alg, http.NoBody)
+		return digest, nil, err
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return "", nil, err
+		}
+		defer body.Close()
+		digest, err := ContentDigest(alg, body)
+		return digest, nil, err
+	}
+	body, err := readOutgoingBody(req)
+	if err != nil {
+		return "", nil, err
+	}
+	digest, err := ContentDigest(alg, bytes.NewReader(body))
+	return digest, body, err
+}
+
+// readOutgoingBody reads and closes the body of an outgoing request.
+// It returns nil for requests without a body.
+func readOutgoingBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	// RoundTrippers must close the request body, even on errors.
+	defer req.Body.Close()
+	return io.ReadAll(req.Body)
+}
@@ -0,0 +1,224 @@
+package xgen
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// newSignedTestServer starts a server that verifies signatures for the given key IDs.
+func newSignedTestServer(t *testing.T, secrets map[string]string, handler http.Handler) *httptest.Server {
+	t.Helper()
+	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{LookupSecret: MapSecretLookup(secrets)})
+	require.NoError(t, err)
+	server := httptest.NewServer(mw(handler))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewSignatureTransport(t *testing.T) {
+	_, err := NewSignatureTransport(SignatureTransportOptions{})
+	assert.Error(t, err)
+
+	rt, err := NewSignatureTransport(SignatureTransportOptions{Secret: "secret"})
+	require.NoError(t, err)
+	assert.NotNil(t, rt)
+}
+
+func TestSignatureTransport_EndToEnd(t *testing.T) {
+	server := newSignedTestServer(t, map[string]string{"client-1": "secret-1"}, echoHandler)
+
+	transport, err := NewSignatureTransport(SignatureTransportOptions{KeyID: "client-1", Secret: "secret-1"})
+	require.NoError(t, err)
+	client := &http.Client{Transport: transport}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   io.Reader
+		want   string
+	}{
+		{"post with body", "POST", "/api/users", strings.NewReader(`{"name":"John"}`), `client-1:{"name":"John"}`},
+		{"get without body", "GET", "/api/orders", nil, "client-1:"},
+		{"empty path", "GET", "", nil, "client-1:"},
+		// A plain io.Reader has no length or GetBody; the transport must still sign and send it.
+		{"streamed body", "PUT", "/api/items/1", io.MultiReader(strings.NewReader("part1,"), strings.NewReader("part2")), "client-1:part1,part2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, tt.body)
+			require.NoError(t, err)
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			got, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, http.StatusOK, resp.StatusCode, string(got))
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+
+	t.Run("wrong secret rejected", func(t *testing.T) {
+		bad, err := NewSignatureTransport(SignatureTransportOptions{KeyID: "client-1", Secret: "wrong"})
+		require.NoError(t, err)
+		resp, err := (&http.Client{Transport: bad}).Get(server.URL + "/api/orders")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+func TestSignatureTransport_Redirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusTemporaryRedirect)
+	})
+	mux.Handle("/new", echoHandler)
+	server := newSignedTestServer(t, map[string]string{"client-1": "secret-1"}, mux)
+
+	transport, err := NewSignatureTransport(SignatureTransportOptions{KeyID: "client-1", Secret: "secret-1"})
+	require.NoError(t, err)
+	client := &http.Client{Transport: transport}
+
+	// A 307 redirect replays the body via GetBody; the new hop must be signed for /new.
+	resp, err := client.Post(server.URL+"/old", "text/plain", strings.NewReader("payload"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	got, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "client-1:payload", string(got))
+}
+
+func TestSignatureTransport_Nonce(t *testing.T) {
+	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret: MapSecretLookup(map[string]string{"client-1": "secret-1"}),
+		NonceStore:   NewMemoryNonceStore(MemoryNonceStoreOptions{}),
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(mw(echoHandler))
+	defer server.Close()
+
+	transport, err := NewSignatureTransport(SignatureTransportOptions{KeyID: "client-1", Secret: "secret-1", Nonce: true})
+	require.NoError(t, err)
+	client := &http.Client{Transport: transport}
+
+	// Every request gets a fresh nonce, so repeated identical requests succeed.
+	for i := 0; i < 3; i++ {
+		resp, err := client.Post(server.URL+"/pay", "text/plain", strings.NewReader("amount=10"))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// Without nonces the server rejects the request.
+	plain, err := NewSignatureTransport(SignatureTransportOptions{KeyID: "client-1", Secret: "secret-1"})
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: plain}).Post(server.URL+"/pay", "text/plain", strings.NewReader("amount=10"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestSignatureTransport_RequestHandling(t *testing.T) {
+	var sent *http.Request
+	base := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		sent = r
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})
+	transport, err := NewSignatureTransport(SignatureTransportOptions{
+		Secret:          "secret",
+		Base:            base,
+		SignatureHeader: "X-Sig",
+		TimestampHeader: "X-Ts",
+	})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("POST", "http://example.com/api", strings.NewReader("body"))
+	require.NoError(t, err)
+	_, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+
+	// The caller's request is left untouched.
+	assert.Empty(t, req.Header.Get("X-Sig"))
+	assert.Empty(t, req.Header.Get(DefaultSignatureKeyIDHeader))
+
+	// The sent copy carries the signature and a replayable body.
+	require.NotNil(t, sent)
+	assert.True(t, VerifySignature("secret", "POST", "/api", sent.Header.Get("X-Ts"), "body", sent.Header.Get("X-Sig")))
+	assert.Empty(t, sent.Header.Get(DefaultSignatureKeyIDHeader))
+	assert.Equal(t, int64(4), sent.ContentLength)
+	for i := 0; i < 2; i++ {
+		rc, err := sent.GetBody()
+		require.NoError(t, err)
+		b, _ := io.ReadAll(rc)
+		assert.Equal(t, "body", string(b))
+	}
+}
+
+func TestSignatureTransport_V2ContentDigest(t *testing.T) {
+	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret:          MapSecretLookup(map[string]string{"client-1": "secret-1"}),
+		Version:               SignatureVersion2,
+		ContentDigest:         true,
+		RequiredSignedHeaders: []string{"content-digest"},
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(mw(echoHandler))
+	defer server.Close()
+
+	// The digest header the transport adds is the one it signs.
+	transport, err := NewSignatureTransport(SignatureTransportOptions{
+		KeyID:         "client-1",
+		Secret:        "secret-1",
+		Version:       SignatureVersion2,
+		SignedHeaders: []string{"host", "content-digest"},
+		ContentDigest: DigestAlgorithmSHA256,
+	})
+	require.NoError(t, err)
+	client := &http.Client{Transport: transport}
+	for _, body := range []io.Reader{strings.NewReader("upload"), io.MultiReader(strings.NewReader("upload"))} {
+		resp, err := client.Post(server.URL+"/upload", "text/plain", body)
+		require.NoError(t, err)
+		got, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, string(got))
+		assert.Equal(t, "client-1:upload", string(got))
+	}
+}
+
+func TestSignatureTransport_BodyReadError(t *testing.T) {
+	transport, err := NewSignatureTransport(SignatureTransportOptions{
+		Secret: "secret",
+		Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			t.Fatal("base transport should not be called")
+			return nil, nil
+		}),
+	})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("POST", "http://example.com/api", io.NopCloser(errReader{}))
+	require.NoError(t, err)
+	_, err = transport.RoundTrip(req)
+	assert.Error(t, err)
+}
+
+// errReader always fails to read.
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
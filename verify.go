@@ -0,0 +1,148 @@
+package xgen
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Detailed verification errors. Each wraps ErrInvalidSignatureTimestamp or
+// ErrInvalidSignature, so checks against those keep working.
+var (
+	// ErrTimestampExpired is returned when the timestamp is older than the allowed drift.
+	ErrTimestampExpired = fmt.Errorf("%w: expired", ErrInvalidSignatureTimestamp)
+	// ErrTimestampInFuture is returned when the timestamp is further ahead than the allowed drift.
+	ErrTimestampInFuture = fmt.Errorf("%w: in the future", ErrInvalidSignatureTimestamp)
+	// ErrMalformedSignature is returned when the signature is not valid hex.
+	ErrMalformedSignature = fmt.Errorf("%w: malformed", ErrInvalidSignature)
+	// ErrSignatureMismatch is returned when a well-formed signature does not match the request.
+	ErrSignatureMismatch = fmt.Errorf("%w: mismatch", ErrInvalidSignature)
+)
+
+// TimestampSkewError reports a timestamp outside the allowed drift together
+// with the measured clock skew. It wraps ErrTimestampExpired or ErrTimestampInFuture.
+type TimestampSkewError struct {
+	// Err is ErrTimestampExpired or ErrTimestampInFuture.
+	Err error
+	// Skew is the server time minus the request timestamp: positive for old
+	// requests, negative for requests from the future.
+	Skew time.Duration
+	// AllowedDrift is the window the timestamp exceeded: the maximum age for
+	// expired timestamps, the maximum lead for future ones.
+	AllowedDrift time.Duration
+}
+
+// Error returns the wrapped error with the skew and allowed drift.
+func (e *TimestampSkewError) Error() string {
+	return fmt.Sprintf("%v (skew %v, allowed %v)", e.Err, e.Skew, e.AllowedDrift)
+}
+
+// Unwrap returns ErrTimestampExpired or ErrTimestampInFuture.
+func (e *TimestampSkewError) Unwrap() error {
+	return e.Err
+}
+
+// SignedRequest holds the components of a request signed with GenerateSignature,
+// GenerateSignatureWithNonce or GenerateSignatureWithSigner.
+type SignedRequest struct {
+	// KeyID selects the secret or keyring key. It may be empty.
+	KeyID string
+	// Method is the HTTP method.
+	Method string
+	// Path is the URL path.
+	Path string
+	// Timestamp is the signed timestamp, in a format accepted by the policy.
+	Timestamp string
+	// Nonce is the optional single-use nonce.
+	Nonce string
+	// Body is the raw request body.
+	Body string
+	// Signature is the received hex signature.
+	Signature string
+}
+
+// VerifyRequestOptions configures VerifyRequest.
+type VerifyRequestOptions struct {
+	// LookupSecret resolves the secret for the key ID.
+	// Exactly one of LookupSecret or Keyring is required.
+	LookupSecret SecretLookupFunc
+	// Keyring verifies against its active keys instead of LookupSecret.
+	Keyring *Keyring
+	// AllowedDrift is the accepted timestamp drift. Defaults to ±5 minutes.
+	AllowedDrift time.Duration
+	// Timestamp sets the timestamp format, separate past and future windows
+	// (defaulting to AllowedDrift) and the clock.
+	Timestamp TimestampPolicy
+}
+
+// VerifiedRequest describes a request accepted by VerifyRequest.
+type VerifiedRequest struct {
+	// KeyID is the ID of the key that verified the signature.
+	KeyID string
+	// Skew is the server time minus the request timestamp.
+	Skew time.Duration
+}
+
+// VerifyRequest checks the timestamp and signature of a request and reports
+// why it was rejected, unlike VerifySignature:
+//
+//   - ErrMissingSignature when the signature or timestamp is empty
+//   - ErrInvalidSignatureTimestamp when the timestamp cannot be parsed
+//   - *TimestampSkewError (ErrTimestampExpired or ErrTimestampInFuture) when
+//     it is outside the allowed drift
+//   - ErrMalformedSignature when the signature is not hex
+//   - ErrUnknownKey, or any error of LookupSecret, when the key is not found
+//   - ErrSignatureMismatch when the signature does not match
+//
+// Example:
+//
+//	result, err := VerifyRequest(ctx, SignedRequest{KeyID: keyID, Method: r.Method, Path: r.URL.Path,
+//		Timestamp: ts, Body: body, Signature: sig}, VerifyRequestOptions{LookupSecret: lookup})
+//	var skew *TimestampSkewError
+//	if errors.As(err, &skew) {
+//		log.Printf("rejected request with clock skew %v", skew.Skew)
+//	}
+func VerifyRequest(ctx context.Context, req SignedRequest, opts VerifyRequestOptions) (*VerifiedRequest, error) {
+	if (opts.LookupSecret == nil) == (opts.Keyring == nil) {
+		return nil, errors.New("request verification requires exactly one of LookupSecret or Keyring")
+	}
+	if err := ValidateTimestampFormat(opts.Timestamp.Format); err != nil {
+		return nil, err
+	}
+	if opts.AllowedDrift <= 0 {
+		opts.AllowedDrift = DefaultSignatureDrift
+	}
+	if req.Signature == "" || req.Timestamp == "" {
+		return nil, ErrMissingSignature
+	}
+	_, skew, err := opts.Timestamp.withDrift(opts.AllowedDrift).check(req.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := hex.DecodeString(req.Signature); err != nil {
+		return nil, ErrMalformedSignature
+	}
+
+	verify := func(key SigningKey) bool {
+		return verifyWithKey(key, req.Method, req.Path, req.Timestamp, req.Nonce, req.Body, req.Signature)
+	}
+	keyID := req.KeyID
+	if opts.Keyring != nil {
+		key, err := opts.Keyring.Match(keyID, verify)
+		if err != nil {
+			return nil, err
+		}
+		keyID = key.ID
+	} else {
+		secret, err := opts.LookupSecret(ctx, keyID)
+		if err != nil {
+			return nil, err
+		}
+		if !verify(SigningKey{Secret: secret}) {
+			return nil, ErrSignatureMismatch
+		}
+	}
+	return &VerifiedRequest{KeyID: keyID, Skew: skew}, nil
+}
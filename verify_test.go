@@ -0,0 +1,173 @@
+package xgen
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSignedRequest signs a SignedRequest with secret at timestamp.
+func newSignedRequest(t *testing.T, secret, keyID string, timestamp time.Time) SignedRequest {
+	t.Helper()
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	sig, err := GenerateSignature(secret, "POST", "/api/orders", ts, `{"id":1}`)
+	require.NoError(t, err)
+	return SignedRequest{KeyID: keyID, Method: "POST", Path: "/api/orders", Timestamp: ts, Body: `{"id":1}`, Signature: sig}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	ctx := context.Background()
+	opts := VerifyRequestOptions{LookupSecret: MapSecretLookup(map[string]string{"client-1": "secret-1"})}
+
+	result, err := VerifyRequest(ctx, newSignedRequest(t, "secret-1", "client-1", time.Now().Add(-time.Minute)), opts)
+	require.NoError(t, err)
+	assert.Equal(t, "client-1", result.KeyID)
+	assert.InDelta(t, time.Minute, result.Skew, float64(5*time.Second))
+
+	t.Run("missing signature", func(t *testing.T) {
+		req := newSignedRequest(t, "secret-1", "client-1", time.Now())
+		req.Signature = ""
+		_, err := VerifyRequest(ctx, req, opts)
+		assert.ErrorIs(t, err, ErrMissingSignature)
+	})
+
+	t.Run("invalid timestamp", func(t *testing.T) {
+		req := newSignedRequest(t, "secret-1", "client-1", time.Now())
+		req.Timestamp = "yesterday"
+		_, err := VerifyRequest(ctx, req, opts)
+		assert.ErrorIs(t, err, ErrInvalidSignatureTimestamp)
+		assert.NotErrorIs(t, err, ErrTimestampExpired)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := VerifyRequest(ctx, newSignedRequest(t, "secret-1", "client-1", time.Now().Add(-10*time.Minute)), opts)
+		assert.ErrorIs(t, err, ErrTimestampExpired)
+		assert.ErrorIs(t, err, ErrInvalidSignatureTimestamp)
+
+		var skewErr *TimestampSkewError
+		require.True(t, errors.As(err, &skewErr))
+		assert.InDelta(t, 10*time.Minute, skewErr.Skew, float64(5*time.Second))
+		assert.Equal(t, DefaultSignatureDrift, skewErr.AllowedDrift)
+		assert.Contains(t, err.Error(), "expired")
+	})
+
+	t.Run("in the future", func(t *testing.T) {
+		_, err := VerifyRequest(ctx, newSignedRequest(t, "secret-1", "client-1", time.Now().Add(time.Hour)), opts)
+		assert.ErrorIs(t, err, ErrTimestampInFuture)
+
+		var skewErr *TimestampSkewError
+		require.True(t, errors.As(err, &skewErr))
+		assert.Less(t, skewErr.Skew, -59*time.Minute)
+	})
+
+	t.Run("custom drift", func(t *testing.T) {
+		req := newSignedRequest(t, "secret-1", "client-1", time.Now().Add(-10*time.Minute))
+		_, err := VerifyRequest(ctx, req, VerifyRequestOptions{LookupSecret: opts.LookupSecret, AllowedDrift: time.Hour})
+		assert.NoError(t, err)
+	})
+
+	t.Run("malformed signature", func(t *testing.T) {
+		req := newSignedRequest(t, "secret-1", "client-1", time.Now())
+		req.Signature = "not-hex"
+		_, err := VerifyRequest(ctx, req, opts)
+		assert.ErrorIs(t, err, ErrMalformedSignature)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		_, err := VerifyRequest(ctx, newSignedRequest(t, "secret-1", "client-9", time.Now()), opts)
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		req := newSignedRequest(t, "secret-1", "client-1", time.Now())
+		req.Body = `{"id":2}`
+		_, err := VerifyRequest(ctx, req, opts)
+		assert.ErrorIs(t, err, ErrSignatureMismatch)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+		assert.NotErrorIs(t, err, ErrMalformedSignature)
+	})
+
+	t.Run("nonce", func(t *testing.T) {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		sig, err := GenerateSignatureWithNonce("secret-1", "GET", "/", ts, "n-1", "")
+		require.NoError(t, err)
+		req := SignedRequest{KeyID: "client-1", Method: "GET", Path: "/", Timestamp: ts, Nonce: "n-1", Signature: sig}
+		_, err = VerifyRequest(ctx, req, opts)
+		assert.NoError(t, err)
+		req.Nonce = "n-2"
+		_, err = VerifyRequest(ctx, req, opts)
+		assert.ErrorIs(t, err, ErrSignatureMismatch)
+	})
+
+	t.Run("options", func(t *testing.T) {
+		req := newSignedRequest(t, "secret-1", "client-1", time.Now())
+		_, err := VerifyRequest(ctx, req, VerifyRequestOptions{})
+		assert.Error(t, err)
+		keyring, err := NewKeyring(SigningKey{ID: "k1", Secret: "s"})
+		require.NoError(t, err)
+		_, err = VerifyRequest(ctx, req, VerifyRequestOptions{LookupSecret: opts.LookupSecret, Keyring: keyring})
+		assert.Error(t, err)
+	})
+}
+
+func TestVerifyRequest_Keyring(t *testing.T) {
+	ctx := context.Background()
+	keyring, err := NewKeyring(
+		SigningKey{ID: "k1", Secret: "secret-1", State: KeyStatePrimary},
+		SigningKey{ID: "k2", Secret: "secret-2"},
+	)
+	require.NoError(t, err)
+	opts := VerifyRequestOptions{Keyring: keyring}
+
+	result, err := VerifyRequest(ctx, newSignedRequest(t, "secret-2", "", time.Now()), opts)
+	require.NoError(t, err)
+	assert.Equal(t, "k2", result.KeyID)
+
+	_, err = VerifyRequest(ctx, newSignedRequest(t, "secret-2", "k3", time.Now()), opts)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	_, err = VerifyRequest(ctx, newSignedRequest(t, "secret-2", "k1", time.Now()), opts)
+	assert.ErrorIs(t, err, ErrSignatureMismatch)
+	_, err = VerifyRequest(ctx, newSignedRequest(t, "secret-3", "", time.Now()), opts)
+	assert.ErrorIs(t, err, ErrSignatureMismatch)
+}
+
+func TestNewSignatureMiddleware_DetailedErrors(t *testing.T) {
+	var got error
+	mw, err := NewSignatureMiddleware(SignatureMiddlewareOptions{
+		LookupSecret: MapSecretLookup(map[string]string{"client-1": "secret-1"}),
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			got = err
+			DefaultSignatureErrorHandler(w, r, err)
+		},
+	})
+	require.NoError(t, err)
+	handler := mw(echoHandler)
+
+	req := newSignedTestRequest(t, "secret-1", "client-1", "POST", "/api", "body")
+	req.Header.Set(DefaultSignatureTimestampHeader, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.ErrorIs(t, got, ErrTimestampExpired)
+
+	req = newSignedTestRequest(t, "secret-1", "client-1", "POST", "/api", "body")
+	req.Header.Set(DefaultSignatureHeader, "zz")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.ErrorIs(t, got, ErrMalformedSignature)
+
+	req = newSignedTestRequest(t, "secret-2", "client-1", "POST", "/api", "body")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.ErrorIs(t, got, ErrSignatureMismatch)
+}
@@ -0,0 +1,240 @@
+package xgen
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook signature header names.
+const (
+	// WebhookSignatureHeader carries the "t=<ts>,v1=<sig>" header of SignWebhook.
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// StripeSignatureHeader is Stripe's name for the same format.
+	StripeSignatureHeader = "Stripe-Signature"
+	// GitHubSignatureHeader carries GitHub's "sha256=<sig>" body signature.
+	GitHubSignatureHeader = "X-Hub-Signature-256"
+	// Standard Webhooks (standardwebhooks.com) headers.
+	StandardWebhookIDHeader        = "webhook-id"
+	StandardWebhookTimestampHeader = "webhook-timestamp"
+	StandardWebhookSignatureHeader = "webhook-signature"
+)
+
+// standardWebhookSecretPrefix prefixes base64 Standard Webhooks secrets.
+const standardWebhookSecretPrefix = "whsec_"
+
+// SignWebhook signs a webhook payload and returns the signature header value
+// "t=<unix seconds>,v1=<hex HMAC-SHA256>". Each secret adds a v1 entry, so
+// receivers can rotate secrets without missing deliveries.
+//
+// The signed content is "<timestamp>.<payload>", the scheme used by Stripe, so
+// the header can also be verified by Stripe-compatible receivers.
+//
+// Example:
+//
+//	header, err := SignWebhook(payload, time.Now(), secret)
+//	req.Header.Set(WebhookSignatureHeader, header)
+func SignWebhook(payload []byte, timestamp time.Time, secrets ...string) (string, error) {
+	if len(secrets) == 0 {
+		return "", errors.New("missing required fields for signature")
+	}
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	parts := []string{"t=" + ts}
+	for _, secret := range secrets {
+		if secret == "" {
+			return "", errors.New("missing required fields for signature")
+		}
+		parts = append(parts, "v1="+hex.EncodeToString(webhookMAC([]byte(secret), ts+".", payload)))
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// VerifyWebhook verifies a header created by SignWebhook, or a Stripe-Signature
+// header, against the payload. It succeeds when any v1 signature matches any
+// of the secrets and the timestamp is within tolerance (±5 minutes when zero).
+//
+// It returns ErrMissingSignature for an empty header or one without v1
+// signatures, ErrInvalidSignatureTimestamp for a missing or stale timestamp
+// and ErrInvalidSignature when no signature matches.
+func VerifyWebhook(header string, payload []byte, tolerance time.Duration, secrets ...string) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+	var ts string
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+		}
+		switch key {
+		case "t":
+			if ts != "" {
+				return fmt.Errorf("%w: repeated timestamp", ErrInvalidSignature)
+			}
+			ts = value
+		case "v1":
+			// Undecodable entries are skipped so one bad entry cannot mask a valid one.
+			if sig, err := hex.DecodeString(value); err == nil {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+	if len(sigs) == 0 {
+		return ErrMissingSignature
+	}
+	if err := checkWebhookTimestamp(ts, tolerance); err != nil {
+		return err
+	}
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		expected := webhookMAC([]byte(secret), ts+".", payload)
+		for _, sig := range sigs {
+			if hmac.Equal(expected, sig) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// SignStandardWebhook signs a payload following the Standard Webhooks
+// specification and returns the webhook-signature header value ("v1,<base64>",
+// space-separated for several secrets). Secrets are base64 keys, optionally
+// prefixed with "whsec_".
+//
+// Example:
+//
+//	sig, err := SignStandardWebhook(msgID, time.Now(), payload, secret)
+//	req.Header.Set(StandardWebhookIDHeader, msgID)
+//	req.Header.Set(StandardWebhookTimestampHeader, strconv.FormatInt(ts.Unix(), 10))
+//	req.Header.Set(StandardWebhookSignatureHeader, sig)
+func SignStandardWebhook(id string, timestamp time.Time, payload []byte, secrets ...string) (string, error) {
+	if id == "" || len(secrets) == 0 {
+		return "", errors.New("missing required fields for signature")
+	}
+	prefix := id + "." + strconv.FormatInt(timestamp.Unix(), 10) + "."
+	var sigs []string
+	for _, secret := range secrets {
+		key, err := decodeStandardWebhookSecret(secret)
+		if err != nil {
+			return "", err
+		}
+		sigs = append(sigs, "v1,"+base64.StdEncoding.EncodeToString(webhookMAC(key, prefix, payload)))
+	}
+	return strings.Join(sigs, " "), nil
+}
+
+// VerifyStandardWebhook verifies the webhook-id, webhook-timestamp and
+// webhook-signature headers of a Standard Webhooks delivery. It succeeds when
+// any v1 signature matches any of the secrets and the timestamp is within
+// tolerance (±5 minutes when zero). Errors are those of VerifyWebhook.
+// Receivers should also deduplicate deliveries by webhook-id.
+func VerifyStandardWebhook(header http.Header, payload []byte, tolerance time.Duration, secrets ...string) error {
+	id := header.Get(StandardWebhookIDHeader)
+	ts := header.Get(StandardWebhookTimestampHeader)
+	signature := header.Get(StandardWebhookSignatureHeader)
+	if id == "" || signature == "" {
+		return ErrMissingSignature
+	}
+	if err := checkWebhookTimestamp(ts, tolerance); err != nil {
+		return err
+	}
+	var sigs [][]byte
+	for _, entry := range strings.Fields(signature) {
+		version, value, _ := strings.Cut(entry, ",")
+		if version != "v1" {
+			continue
+		}
+		if sig, err := base64.StdEncoding.DecodeString(value); err == nil {
+			sigs = append(sigs, sig)
+		}
+	}
+	if len(sigs) == 0 {
+		return ErrMissingSignature
+	}
+	prefix := id + "." + ts + "."
+	for _, secret := range secrets {
+		key, err := decodeStandardWebhookSecret(secret)
+		if err != nil {
+			return err
+		}
+		expected := webhookMAC(key, prefix, payload)
+		for _, sig := range sigs {
+			if hmac.Equal(expected, sig) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// SignGitHubWebhook returns the X-Hub-Signature-256 header value
+// ("sha256=<hex HMAC-SHA256 of the payload>") used by GitHub.
+func SignGitHubWebhook(payload []byte, secret string) (string, error) {
+	if secret == "" {
+		return "", errors.New("missing required fields for signature")
+	}
+	return "sha256=" + hex.EncodeToString(webhookMAC([]byte(secret), "", payload)), nil
+}
+
+// VerifyGitHubWebhook verifies an X-Hub-Signature-256 header against the
+// payload and any of the secrets. GitHub signatures carry no timestamp, so
+// receivers should deduplicate deliveries by the X-GitHub-Delivery header.
+func VerifyGitHubWebhook(header string, payload []byte, secrets ...string) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+	value, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return fmt.Errorf("%w: unsupported algorithm", ErrInvalidSignature)
+	}
+	sig, err := hex.DecodeString(value)
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	for _, secret := range secrets {
+		if secret != "" && hmac.Equal(webhookMAC([]byte(secret), "", payload), sig) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// webhookMAC returns the HMAC-SHA256 of prefix followed by payload.
+func webhookMAC(key []byte, prefix string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(prefix))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// checkWebhookTimestamp checks a Unix seconds timestamp against tolerance,
+// defaulting to DefaultSignatureDrift.
+func checkWebhookTimestamp(ts string, tolerance time.Duration) error {
+	if tolerance <= 0 {
+		tolerance = DefaultSignatureDrift
+	}
+	if ts == "" || !IsValidSignatureTimestamp(ts, tolerance) {
+		return ErrInvalidSignatureTimestamp
+	}
+	return nil
+}
+
+// decodeStandardWebhookSecret decodes a base64 secret with an optional "whsec_" prefix.
+func decodeStandardWebhookSecret(secret string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, standardWebhookSecretPrefix))
+	if err != nil || len(key) == 0 {
+		return nil, errors.New("invalid Standard Webhooks secret: expected base64 with optional whsec_ prefix")
+	}
+	return key, nil
+}
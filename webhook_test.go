@@ -0,0 +1,133 @@
+package xgen
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignWebhook(t *testing.T) {
+	payload := []byte(`{"event":"invoice.paid"}`)
+	header, err := SignWebhook(payload, time.Unix(1700000000, 0), "secret")
+	require.NoError(t, err)
+	// HMAC-SHA256("secret", "1700000000." + payload)
+	assert.Equal(t, "t=1700000000,v1="+hexHMAC("secret", "1700000000."+string(payload)), header)
+
+	header, err = SignWebhook(payload, time.Unix(1700000000, 0), "old", "new")
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(header, "v1="))
+
+	_, err = SignWebhook(payload, time.Now())
+	assert.Error(t, err)
+	_, err = SignWebhook(payload, time.Now(), "")
+	assert.Error(t, err)
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	payload := []byte(`{"event":"invoice.paid"}`)
+	now := time.Now()
+
+	// Receivers accept either secret during a rotation.
+	header, err := SignWebhook(payload, now, "old", "new")
+	require.NoError(t, err)
+	assert.NoError(t, VerifyWebhook(header, payload, 0, "new"))
+	assert.NoError(t, VerifyWebhook(header, payload, 0, "old"))
+	assert.NoError(t, VerifyWebhook(header, payload, 0, "other", "new"))
+	assert.ErrorIs(t, VerifyWebhook(header, payload, 0, "other"), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyWebhook(header, []byte("{}"), 0, "new"), ErrInvalidSignature)
+
+	// Stripe headers may carry other schemes and spaces.
+	stripe := "t=" + strconv.FormatInt(now.Unix(), 10) + ", v0=abc, v1=zz, v1=" +
+		hexHMAC("whsec_test", strconv.FormatInt(now.Unix(), 10)+"."+string(payload))
+	assert.NoError(t, VerifyWebhook(stripe, payload, time.Minute, "whsec_test"))
+
+	stale, err := SignWebhook(payload, now.Add(-10*time.Minute), "new")
+	require.NoError(t, err)
+	assert.ErrorIs(t, VerifyWebhook(stale, payload, 0, "new"), ErrInvalidSignatureTimestamp)
+	assert.NoError(t, VerifyWebhook(stale, payload, time.Hour, "new"))
+
+	for header, want := range map[string]error{
+		"":                        ErrMissingSignature,
+		"t=1700000000":            ErrMissingSignature,
+		"v1=00":                   ErrInvalidSignatureTimestamp,
+		"t=1,t=2,v1=00":           ErrInvalidSignature,
+		"garbage":                 ErrInvalidSignature,
+		"t=notanumber,v1=00":      ErrInvalidSignatureTimestamp,
+		"t=1700000000,v1=not-hex": ErrMissingSignature,
+	} {
+		assert.ErrorIs(t, VerifyWebhook(header, payload, 0, "new"), want, header)
+	}
+}
+
+func TestStandardWebhook(t *testing.T) {
+	// Test vector from the Standard Webhooks reference libraries.
+	secret := "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+	payload := []byte(`{"test": 2432232314}`)
+	sig, err := SignStandardWebhook("msg_p5jXN8AQM9LWM0D4loKWxJek", time.Unix(1614265330, 0), payload, secret)
+	require.NoError(t, err)
+	assert.Equal(t, "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=", sig)
+
+	now := time.Now()
+	sig, err = SignStandardWebhook("msg_1", now, payload, "whsec_b2xk", secret)
+	require.NoError(t, err)
+	header := http.Header{}
+	header.Set(StandardWebhookIDHeader, "msg_1")
+	header.Set(StandardWebhookTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	header.Set(StandardWebhookSignatureHeader, "v2,ignored "+sig)
+	assert.NoError(t, VerifyStandardWebhook(header, payload, 0, secret))
+	assert.NoError(t, VerifyStandardWebhook(header, payload, 0, strings.TrimPrefix(secret, "whsec_")))
+	assert.ErrorIs(t, VerifyStandardWebhook(header, []byte("{}"), 0, secret), ErrInvalidSignature)
+
+	// The ID and timestamp are signed.
+	tampered := header.Clone()
+	tampered.Set(StandardWebhookIDHeader, "msg_2")
+	assert.ErrorIs(t, VerifyStandardWebhook(tampered, payload, 0, secret), ErrInvalidSignature)
+	tampered = header.Clone()
+	tampered.Set(StandardWebhookTimestampHeader, strconv.FormatInt(now.Unix()+1, 10))
+	assert.ErrorIs(t, VerifyStandardWebhook(tampered, payload, 0, secret), ErrInvalidSignature)
+	tampered.Set(StandardWebhookTimestampHeader, "1614265330")
+	assert.ErrorIs(t, VerifyStandardWebhook(tampered, payload, 0, secret), ErrInvalidSignatureTimestamp)
+	tampered = header.Clone()
+	tampered.Del(StandardWebhookSignatureHeader)
+	assert.ErrorIs(t, VerifyStandardWebhook(tampered, payload, 0, secret), ErrMissingSignature)
+	tampered.Set(StandardWebhookSignatureHeader, "v1a,AAAA")
+	assert.ErrorIs(t, VerifyStandardWebhook(tampered, payload, 0, secret), ErrMissingSignature)
+
+	assert.Error(t, VerifyStandardWebhook(header, payload, 0, "whsec_not base64"))
+	_, err = SignStandardWebhook("msg_1", now, payload, "whsec_")
+	assert.Error(t, err)
+	_, err = SignStandardWebhook("", now, payload, secret)
+	assert.Error(t, err)
+}
+
+func TestGitHubWebhook(t *testing.T) {
+	// Example from GitHub's "Validating webhook deliveries" documentation.
+	payload := []byte("Hello, World!")
+	sig, err := SignGitHubWebhook(payload, "It's a Secret to Everybody")
+	require.NoError(t, err)
+	assert.Equal(t, "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", sig)
+
+	assert.NoError(t, VerifyGitHubWebhook(sig, payload, "old", "It's a Secret to Everybody"))
+	assert.ErrorIs(t, VerifyGitHubWebhook(sig, []byte("Hello"), "It's a Secret to Everybody"), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyGitHubWebhook("", payload, "s"), ErrMissingSignature)
+	assert.ErrorIs(t, VerifyGitHubWebhook("sha1=abc", payload, "s"), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyGitHubWebhook("sha256=zz", payload, "s"), ErrInvalidSignature)
+
+	_, err = SignGitHubWebhook(payload, "")
+	assert.Error(t, err)
+}
+
+// hexHMAC returns the hex HMAC-SHA256 of message.
+func hexHMAC(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,562 @@
+// Package xgengrpc provides gRPC client and server interceptors that sign
+// calls with the keys, keyrings and nonce stores of package xgen.
+//
+// Unary calls are authenticated in full. Streams are authenticated when they
+// open, over their metadata and first message only: later messages on a
+// verified stream are accepted without a signature, so rely on the transport
+// (TLS) for their integrity, or sign them at the application level.
+package xgengrpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hotfixfirst/go-xgen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Metadata keys used for signed gRPC calls. gRPC metadata keys are lower-case.
+const (
+	SignatureMetadataKey = "x-signature"
+	TimestampMetadataKey = "x-signature-timestamp"
+	KeyIDMetadataKey     = "x-signature-key-id"
+	NonceMetadataKey     = "x-signature-nonce"
+	// SignedMetadataKey lists the metadata keys covered by the signature.
+	SignedMetadataKey = "x-signature-metadata"
+)
+
+// signaturePrefix is the first line of the canonical string of gRPC calls,
+// so gRPC signatures cannot be confused with request, URL or token signatures.
+const signaturePrefix = "xgen-grpc\n"
+
+// ClientOptions configures the gRPC client interceptors.
+type ClientOptions struct {
+	// Secret is the HMAC-SHA256 signing secret.
+	// Exactly one of Secret, Signer or Keyring is required.
+	Secret string
+	// Signer signs calls instead of Secret, e.g. with Ed25519.
+	Signer xgen.Signer
+	// KeyID is sent in the key ID metadata when set.
+	KeyID string
+	// Keyring signs each call with its current primary key and sends that
+	// key's ID, instead of Secret and KeyID.
+	Keyring *xgen.Keyring
+	// Nonce adds a fresh random nonce to every call, for servers that use a NonceStore.
+	Nonce bool
+	// SignedMetadata lists outgoing metadata keys covered by the signature,
+	// e.g. "x-tenant-id". Keys missing from a call are signed as empty.
+	SignedMetadata []string
+}
+
+// ServerOptions configures the gRPC server interceptors.
+type ServerOptions struct {
+	// LookupSecret resolves the secret for the call's key ID.
+	// Exactly one of LookupSecret or Keyring is required.
+	LookupSecret xgen.SecretLookupFunc
+	// Keyring verifies calls against its active keys instead of LookupSecret.
+	// Calls without a key ID are tried against every active key.
+	Keyring *xgen.Keyring
+	// NonceStore, when set, requires a nonce on every call and rejects nonces
+	// already used by the same key ID within the past timestamp window.
+	NonceStore xgen.NonceStore
+	// RequiredMetadata lists metadata keys every call must sign. Calls that
+	// omit one fail with xgen.ErrUnsignedHeader.
+	RequiredMetadata []string
+	// AllowedDrift is the accepted timestamp drift. Defaults to ±5 minutes.
+	AllowedDrift time.Duration
+	// Timestamp sets the timestamp format, separate past and future windows
+	// (defaulting to AllowedDrift) and the clock.
+	Timestamp xgen.TimestampPolicy
+	// ErrorHandler converts verification errors into the status returned to
+	// the client. Defaults to DefaultErrorHandler.
+	ErrorHandler func(ctx context.Context, err error) error
+}
+
+// NewUnaryClientInterceptor returns a client interceptor that signs every
+// unary call for verification by NewUnaryServerInterceptor.
+//
+// The signature covers the full method name, the current Unix timestamp, an
+// optional nonce, the SignedMetadata keys and the request message serialized
+// with deterministic protobuf encoding, in the canonical string:
+//
+//	xgen-grpc\nALGORITHM\n/package.Service/Method\nTIMESTAMP\nNONCE\nSIGNED_KEYS\nkey:value\n...MESSAGE
+//
+// NONCE is empty when Nonce is false.
+//
+// Example:
+//
+//	interceptor, err := NewUnaryClientInterceptor(ClientOptions{KeyID: "client-1", Secret: secret})
+//	conn, err := grpc.NewClient(target, grpc.WithUnaryInterceptor(interceptor), ...)
+func NewUnaryClientInterceptor(opts ClientOptions) (grpc.UnaryClientInterceptor, error) {
+	opts, err := validateClientOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		message, err := marshalMessage(req)
+		if err != nil {
+			return err
+		}
+		if ctx, err = signCall(ctx, opts, method, message); err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, callOpts...)
+	}, nil
+}
+
+// NewStreamClientInterceptor returns a client interceptor that signs every
+// streaming call. Metadata is only sent once per stream, so the stream is
+// opened when the first message is sent and the signature covers that message
+// like a unary request; later messages are not signed, so only the stream
+// open is authenticated. A stream opened by RecvMsg, Header or CloseSend before any message is sent is signed
+// with an empty message. This covers the request of server-streaming calls;
+// client-streaming and bidirectional calls must send their first message
+// before receiving.
+func NewStreamClientInterceptor(opts ClientOptions) (grpc.StreamClientInterceptor, error) {
+	opts, err := validateClientOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		open := func(message []byte) (grpc.ClientStream, error) {
+			ctx, err := signCall(ctx, opts, method, message)
+			if err != nil {
+				return nil, err
+			}
+			return streamer(ctx, desc, cc, method, callOpts...)
+		}
+		return &signedClientStream{ctx: ctx, open: open}, nil
+	}, nil
+}
+
+// NewUnaryServerInterceptor returns a server interceptor that verifies unary
+// calls signed by NewUnaryClientInterceptor, with the key selection, timestamp
+// and nonce rules of xgen.NewSignatureMiddleware. The verified key ID is
+// stored in the context (see xgen.SignatureKeyIDFromContext).
+//
+// The request message is re-encoded with deterministic protobuf encoding, so
+// clients and servers must use the same message definitions.
+//
+// Example:
+//
+//	interceptor, err := NewUnaryServerInterceptor(ServerOptions{
+//		LookupSecret: xgen.MapSecretLookup(map[string]string{"client-1": secret}),
+//	})
+//	server := grpc.NewServer(grpc.UnaryInterceptor(interceptor))
+func NewUnaryServerInterceptor(opts ServerOptions) (grpc.UnaryServerInterceptor, error) {
+	opts, err := validateServerOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		message, err := marshalMessage(req)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		keyID, err := verifyCall(ctx, opts, info.FullMethod, message)
+		if err != nil {
+			return nil, opts.ErrorHandler(ctx, err)
+		}
+		return handler(xgen.ContextWithSignatureKeyID(ctx, keyID), req)
+	}, nil
+}
+
+// NewStreamServerInterceptor returns a server interceptor that verifies
+// streams opened by NewStreamClientInterceptor when the handler receives the
+// first message, which the signature covers. Until then the stream context
+// carries no key ID and sending fails, so handlers must receive before they
+// send. Only the stream open is authenticated: messages received after the
+// first are passed to the handler without verification.
+func NewStreamServerInterceptor(opts ServerOptions) (grpc.StreamServerInterceptor, error) {
+	opts, err := validateServerOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		// Reject unsigned streams before the handler runs.
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		if firstMetadataValue(md, SignatureMetadataKey) == "" {
+			return opts.ErrorHandler(ss.Context(), xgen.ErrMissingSignature)
+		}
+		return handler(srv, &signedServerStream{ServerStream: ss, opts: opts, method: info.FullMethod, ctx: ss.Context()})
+	}, nil
+}
+
+// DefaultErrorHandler returns Unavailable for xgen.ErrNonceStoreFull,
+// Internal for secret lookup and nonce store failures and Unauthenticated for
+// every other verification error. Details stay on the server.
+func DefaultErrorHandler(_ context.Context, err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, xgen.ErrNonceStoreFull):
+		code = codes.Unavailable
+	case errors.Is(err, xgen.ErrMissingSignature),
+		errors.Is(err, xgen.ErrInvalidSignatureTimestamp),
+		errors.Is(err, xgen.ErrInvalidSignature),
+		errors.Is(err, xgen.ErrUnknownKey),
+		errors.Is(err, xgen.ErrMissingNonce),
+		errors.Is(err, xgen.ErrReplayedNonce),
+		errors.Is(err, xgen.ErrUnsignedHeader):
+		code = codes.Unauthenticated
+	}
+	return status.Error(code, code.String())
+}
+
+// errStreamNotVerified is returned when a server handler sends on a signed
+// stream before receiving the first message.
+var errStreamNotVerified = status.Error(codes.FailedPrecondition, "signed stream must receive a message before sending")
+
+// signedClientStream opens the underlying stream when the first message is
+// sent, so the signature can cover that message.
+type signedClientStream struct {
+	ctx  context.Context
+	open func(message []byte) (grpc.ClientStream, error)
+
+	mu     sync.Mutex
+	stream grpc.ClientStream
+	err    error
+}
+
+// opened returns the underlying stream, opening it signed over message if needed.
+func (s *signedClientStream) opened(message []byte) (grpc.ClientStream, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stream == nil && s.err == nil {
+		s.stream, s.err = s.open(message)
+	}
+	return s.stream, s.err
+}
+
+// SendMsg signs the first message when opening the stream, then sends m.
+func (s *signedClientStream) SendMsg(m any) error {
+	s.mu.Lock()
+	stream := s.stream
+	s.mu.Unlock()
+	if stream == nil {
+		message, err := marshalMessage(m)
+		if err != nil {
+			return err
+		}
+		if stream, err = s.opened(message); err != nil {
+			return err
+		}
+	}
+	return stream.SendMsg(m)
+}
+
+// RecvMsg receives a message, opening the stream first if needed.
+func (s *signedClientStream) RecvMsg(m any) error {
+	stream, err := s.opened(nil)
+	if err != nil {
+		return err
+	}
+	return stream.RecvMsg(m)
+}
+
+// Header returns the header metadata, opening the stream first if needed.
+func (s *signedClientStream) Header() (metadata.MD, error) {
+	stream, err := s.opened(nil)
+	if err != nil {
+		return nil, err
+	}
+	return stream.Header()
+}
+
+// Trailer returns the trailer metadata, or nil before the stream is opened.
+func (s *signedClientStream) Trailer() metadata.MD {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stream == nil {
+		return nil
+	}
+	return s.stream.Trailer()
+}
+
+// CloseSend closes the send direction, opening the stream first if needed.
+func (s *signedClientStream) CloseSend() error {
+	stream, err := s.opened(nil)
+	if err != nil {
+		return err
+	}
+	return stream.CloseSend()
+}
+
+// Context returns the stream context, or the call context before the stream is opened.
+func (s *signedClientStream) Context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stream == nil {
+		return s.ctx
+	}
+	return s.stream.Context()
+}
+
+// signedServerStream verifies a stream when its first message is received
+// and then overrides the context with the verified key ID.
+type signedServerStream struct {
+	grpc.ServerStream
+	opts   ServerOptions
+	method string
+
+	mu       sync.Mutex
+	ctx      context.Context
+	verified bool
+	err      error
+}
+
+// RecvMsg receives a message and verifies the signature against the first one.
+// A stream closed before any message is verified against an empty message.
+// Later messages are returned unverified.
+func (s *signedServerStream) RecvMsg(m any) error {
+	s.mu.Lock()
+	err := s.err
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	recvErr := s.ServerStream.RecvMsg(m)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.verified || (recvErr != nil && !errors.Is(recvErr, io.EOF)) {
+		return recvErr
+	}
+	var message []byte
+	if recvErr == nil {
+		if message, err = marshalMessage(m); err != nil {
+			s.err = status.Error(codes.Internal, err.Error())
+			return s.err
+		}
+	}
+	keyID, err := verifyCall(s.ctx, s.opts, s.method, message)
+	if err != nil {
+		s.err = s.opts.ErrorHandler(s.ctx, err)
+		return s.err
+	}
+	s.ctx = xgen.ContextWithSignatureKeyID(s.ctx, keyID)
+	s.verified = true
+	return recvErr
+}
+
+// SendMsg sends m once the stream has been verified.
+func (s *signedServerStream) SendMsg(m any) error {
+	s.mu.Lock()
+	verified, err := s.verified, s.err
+	s.mu.Unlock()
+	if !verified {
+		if err == nil {
+			err = errStreamNotVerified
+		}
+		return err
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// Context returns the context carrying the verified key ID once the first
+// message has been received.
+func (s *signedServerStream) Context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ctx
+}
+
+// validateClientOptions checks opts and canonicalizes the signed metadata keys.
+func validateClientOptions(opts ClientOptions) (ClientOptions, error) {
+	set := 0
+	for _, ok := range []bool{opts.Secret != "", opts.Signer != nil, opts.Keyring != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return opts, errors.New("gRPC signature interceptor requires exactly one of Secret, Signer or Keyring")
+	}
+	names, err := xgen.CanonicalHeaderNames(opts.SignedMetadata)
+	if err != nil {
+		return opts, err
+	}
+	opts.SignedMetadata = names
+	return opts, nil
+}
+
+// validateServerOptions checks opts and applies defaults.
+func validateServerOptions(opts ServerOptions) (ServerOptions, error) {
+	if (opts.LookupSecret == nil) == (opts.Keyring == nil) {
+		return opts, errors.New("gRPC signature interceptor requires exactly one of LookupSecret or Keyring")
+	}
+	if err := xgen.ValidateTimestampFormat(opts.Timestamp.Format); err != nil {
+		return opts, err
+	}
+	if opts.AllowedDrift <= 0 {
+		opts.AllowedDrift = xgen.DefaultSignatureDrift
+	}
+	if opts.Timestamp.MaxAge <= 0 {
+		opts.Timestamp.MaxAge = opts.AllowedDrift
+	}
+	if opts.Timestamp.MaxFuture <= 0 {
+		opts.Timestamp.MaxFuture = opts.AllowedDrift
+	}
+	if opts.Timestamp.Now == nil {
+		opts.Timestamp.Now = time.Now
+	}
+	if opts.ErrorHandler == nil {
+		opts.ErrorHandler = DefaultErrorHandler
+	}
+	return opts, nil
+}
+
+// signCall returns ctx with the signature metadata of a call added to its
+// outgoing metadata.
+func signCall(ctx context.Context, opts ClientOptions, method string, message []byte) (context.Context, error) {
+	key := xgen.SigningKey{ID: opts.KeyID, Secret: opts.Secret, Signer: opts.Signer}
+	if opts.Keyring != nil {
+		var err error
+		if key, err = opts.Keyring.Primary(); err != nil {
+			return nil, err
+		}
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	var nonce string
+	if opts.Nonce {
+		var err error
+		if nonce, err = xgen.GenerateSignatureNonce(); err != nil {
+			return nil, err
+		}
+	}
+	signer, err := xgen.KeySigner(key)
+	if err != nil {
+		return nil, err
+	}
+	body := signedBody(md, opts.SignedMetadata, message)
+	sig, err := signer.Sign([]byte(canonicalString(signer.Algorithm(), method, timestamp, nonce, body)))
+	if err != nil {
+		return nil, err
+	}
+	signature := hex.EncodeToString(sig)
+
+	md.Set(TimestampMetadataKey, timestamp)
+	md.Set(SignatureMetadataKey, signature)
+	md.Delete(KeyIDMetadataKey)
+	if key.ID != "" {
+		md.Set(KeyIDMetadataKey, key.ID)
+	}
+	md.Delete(NonceMetadataKey)
+	if nonce != "" {
+		md.Set(NonceMetadataKey, nonce)
+	}
+	md.Set(SignedMetadataKey, strings.Join(opts.SignedMetadata, ";"))
+	return metadata.NewOutgoingContext(ctx, md), nil
+}
+
+// verifyCall checks the signature metadata of an incoming call and returns the verified key ID.
+func verifyCall(ctx context.Context, opts ServerOptions, method string, message []byte) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	signature := firstMetadataValue(md, SignatureMetadataKey)
+	timestamp := firstMetadataValue(md, TimestampMetadataKey)
+	if signature == "" || timestamp == "" {
+		return "", xgen.ErrMissingSignature
+	}
+	if _, err := xgen.ValidateSignatureTimestamp(timestamp, opts.Timestamp); err != nil {
+		return "", err
+	}
+	signedAt, _ := xgen.ParseSignatureTimestamp(timestamp, opts.Timestamp.Format)
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return "", xgen.ErrMalformedSignature
+	}
+	nonce := firstMetadataValue(md, NonceMetadataKey)
+	if opts.NonceStore != nil && nonce == "" {
+		return "", xgen.ErrMissingNonce
+	}
+	names, err := xgen.RequireSignedHeaders(firstMetadataValue(md, SignedMetadataKey), opts.RequiredMetadata)
+	if err != nil {
+		return "", err
+	}
+
+	keyID := firstMetadataValue(md, KeyIDMetadataKey)
+	body := signedBody(md, names, message)
+	verify := func(key xgen.SigningKey) bool {
+		verifier := xgen.KeyVerifier(key)
+		return verifier != nil && verifier.Verify([]byte(canonicalString(verifier.Algorithm(), method, timestamp, nonce, body)), sig)
+	}
+	if opts.Keyring != nil {
+		key, err := opts.Keyring.Match(keyID, verify)
+		if err != nil {
+			return "", err
+		}
+		keyID = key.ID
+	} else {
+		secret, err := opts.LookupSecret(ctx, keyID)
+		if err != nil {
+			return "", err
+		}
+		if !verify(xgen.SigningKey{Secret: secret}) {
+			return "", xgen.ErrSignatureMismatch
+		}
+	}
+
+	// Only remember nonces of authentic calls, so forged ones cannot fill the store.
+	if opts.NonceStore != nil {
+		if err := xgen.CheckNonce(ctx, opts.NonceStore, keyID, nonce, signedAt.Add(opts.Timestamp.MaxAge)); err != nil {
+			return "", err
+		}
+	}
+	return keyID, nil
+}
+
+// canonicalString returns the string signed for a gRPC call.
+func canonicalString(algorithm xgen.SignatureAlgorithm, method, timestamp, nonce, body string) string {
+	return signaturePrefix + string(algorithm) + "\n" + method + "\n" + timestamp + "\n" + nonce + "\n" + body
+}
+
+// signedBody returns the signed part of a call after the nonce: the
+// ";"-separated signed keys, one "key:value" line per key, then the message.
+// Repeated values are joined with ","; binary ("-bin") values are base64-encoded.
+func signedBody(md metadata.MD, names []string, message []byte) string {
+	var b strings.Builder
+	b.WriteString(strings.Join(names, ";") + "\n")
+	for _, name := range names {
+		values := md.Get(name)
+		normalized := make([]string, len(values))
+		for i, v := range values {
+			if strings.HasSuffix(name, "-bin") {
+				normalized[i] = base64.StdEncoding.EncodeToString([]byte(v))
+			} else {
+				normalized[i] = strings.Join(strings.Fields(v), " ")
+			}
+		}
+		b.WriteString(name + ":" + strings.Join(normalized, ",") + "\n")
+	}
+	b.Write(message)
+	return b.String()
+}
+
+// marshalMessage encodes a protobuf message deterministically.
+func marshalMessage(m any) ([]byte, error) {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("gRPC request signing requires a protobuf message, got %T", m)
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+}
+
+// firstMetadataValue returns the first value of key in md, or "".
+func firstMetadataValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,231 @@
+package xgengrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/hotfixfirst/go-xgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// keyIDHealthServer is a health server that fails calls without a verified key ID.
+type keyIDHealthServer struct {
+	healthpb.UnimplementedHealthServer
+}
+
+func (keyIDHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if _, ok := xgen.SignatureKeyIDFromContext(ctx); !ok {
+		return nil, status.Error(codes.Internal, "no key ID")
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (keyIDHealthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	if _, ok := xgen.SignatureKeyIDFromContext(stream.Context()); !ok {
+		return status.Error(codes.Internal, "no key ID")
+	}
+	return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+}
+
+// newSignedGRPCServer starts an in-process health server with the signature
+// interceptors and returns a function that dials it with client options, or
+// without signing when they are nil, and any extra dial options.
+func newSignedGRPCServer(t *testing.T, serverOpts ServerOptions) func(*ClientOptions, ...grpc.DialOption) healthpb.HealthClient {
+	t.Helper()
+	unary, err := NewUnaryServerInterceptor(serverOpts)
+	require.NoError(t, err)
+	stream, err := NewStreamServerInterceptor(serverOpts)
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream))
+	healthpb.RegisterHealthServer(server, keyIDHealthServer{})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return func(clientOpts *ClientOptions, extra ...grpc.DialOption) healthpb.HealthClient {
+		dialOpts := []grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		}
+		if clientOpts != nil {
+			unary, err := NewUnaryClientInterceptor(*clientOpts)
+			require.NoError(t, err)
+			stream, err := NewStreamClientInterceptor(*clientOpts)
+			require.NoError(t, err)
+			dialOpts = append(dialOpts, grpc.WithUnaryInterceptor(unary), grpc.WithStreamInterceptor(stream))
+		}
+		dialOpts = append(dialOpts, extra...)
+		conn, err := grpc.NewClient("passthrough:///bufnet", dialOpts...)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return healthpb.NewHealthClient(conn)
+	}
+}
+
+func TestGRPCSignatureInterceptors(t *testing.T) {
+	ctx := context.Background()
+	dial := newSignedGRPCServer(t, ServerOptions{
+		LookupSecret:     xgen.MapSecretLookup(map[string]string{"client-1": "secret-1"}),
+		RequiredMetadata: []string{"x-tenant-id"},
+	})
+	tenantCtx := metadata.AppendToOutgoingContext(ctx, "x-tenant-id", "acme", "x-trace-bin", "\x00\xff")
+	req := &healthpb.HealthCheckRequest{Service: "orders"}
+
+	client := dial(&ClientOptions{KeyID: "client-1", Secret: "secret-1", SignedMetadata: []string{"X-Tenant-Id", "x-trace-bin"}})
+	resp, err := client.Check(tenantCtx, req)
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	watch, err := client.Watch(tenantCtx, req)
+	require.NoError(t, err)
+	resp, err = watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	// Unsigned required metadata, wrong secrets and unknown keys are rejected.
+	for _, opts := range []*ClientOptions{
+		{KeyID: "client-1", Secret: "secret-1"},
+		{KeyID: "client-1", Secret: "secret-2", SignedMetadata: []string{"x-tenant-id"}},
+		{KeyID: "client-9", Secret: "secret-1", SignedMetadata: []string{"x-tenant-id"}},
+		nil,
+	} {
+		_, err = dial(opts).Check(tenantCtx, req)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		watch, err := dial(opts).Watch(tenantCtx, req)
+		require.NoError(t, err)
+		_, err = watch.Recv()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+
+	// The first stream message is signed: changing it after signing is rejected.
+	tamper := grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		return &tamperedClientStream{ClientStream: stream}, err
+	})
+	watch, err = dial(&ClientOptions{KeyID: "client-1", Secret: "secret-1", SignedMetadata: []string{"x-tenant-id"}}, tamper).Watch(tenantCtx, req)
+	require.NoError(t, err)
+	_, err = watch.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+// tamperedClientStream replaces every health check request it sends.
+type tamperedClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *tamperedClientStream) SendMsg(m any) error {
+	return s.ClientStream.SendMsg(&healthpb.HealthCheckRequest{Service: "admin"})
+}
+
+func TestGRPCSignatureInterceptors_Keyring(t *testing.T) {
+	ctx := context.Background()
+	serverKeys, err := xgen.NewKeyring(
+		xgen.SigningKey{ID: "k1", Secret: "secret-1"},
+		xgen.SigningKey{ID: "k2", Secret: "secret-2"},
+	)
+	require.NoError(t, err)
+	store := xgen.NewMemoryNonceStore(xgen.MemoryNonceStoreOptions{})
+	dial := newSignedGRPCServer(t, ServerOptions{Keyring: serverKeys, NonceStore: store})
+
+	clientKeys, err := xgen.NewKeyring(xgen.SigningKey{ID: "k2", Secret: "secret-2", State: xgen.KeyStatePrimary})
+	require.NoError(t, err)
+	client := dial(&ClientOptions{Keyring: clientKeys, Nonce: true})
+	for range 3 {
+		_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+	}
+
+	// Without a nonce the call is rejected.
+	_, err = dial(&ClientOptions{KeyID: "k2", Secret: "secret-2"}).Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestVerifyGRPCCall(t *testing.T) {
+	serverOpts, err := validateServerOptions(ServerOptions{
+		LookupSecret: xgen.MapSecretLookup(map[string]string{"client-1": "secret-1"}),
+		NonceStore:   xgen.NewMemoryNonceStore(xgen.MemoryNonceStoreOptions{}),
+	})
+	require.NoError(t, err)
+	clientOpts, err := validateClientOptions(ClientOptions{
+		KeyID: "client-1", Secret: "secret-1", Nonce: true, SignedMetadata: []string{"x-tenant-id"},
+	})
+	require.NoError(t, err)
+	message, err := marshalMessage(&healthpb.HealthCheckRequest{Service: "orders"})
+	require.NoError(t, err)
+
+	// incoming signs a call and returns the metadata the server receives.
+	incoming := func(tenant string) metadata.MD {
+		ctx, err := signCall(metadata.NewOutgoingContext(context.Background(), metadata.Pairs("x-tenant-id", tenant)), clientOpts, "/svc/Method", message)
+		require.NoError(t, err)
+		md, _ := metadata.FromOutgoingContext(ctx)
+		return md
+	}
+	verify := func(md metadata.MD, method string, message []byte) error {
+		_, err := verifyCall(metadata.NewIncomingContext(context.Background(), md), serverOpts, method, message)
+		return err
+	}
+
+	md := incoming("acme")
+	assert.NoError(t, verify(md, "/svc/Method", message))
+	assert.ErrorIs(t, verify(md, "/svc/Method", message), xgen.ErrReplayedNonce)
+
+	md = incoming("acme")
+	assert.ErrorIs(t, verify(md, "/svc/Other", message), xgen.ErrSignatureMismatch)
+	assert.ErrorIs(t, verify(md, "/svc/Method", append(message, 0)), xgen.ErrSignatureMismatch)
+	tampered := md.Copy()
+	tampered.Set("x-tenant-id", "other")
+	assert.ErrorIs(t, verify(tampered, "/svc/Method", message), xgen.ErrSignatureMismatch)
+	tampered = md.Copy()
+	tampered.Set(SignedMetadataKey, "")
+	assert.ErrorIs(t, verify(tampered, "/svc/Method", message), xgen.ErrSignatureMismatch)
+	tampered = md.Copy()
+	tampered.Set(TimestampMetadataKey, "1700000000")
+	assert.ErrorIs(t, verify(tampered, "/svc/Method", message), xgen.ErrTimestampExpired)
+	tampered = md.Copy()
+	tampered.Set(SignatureMetadataKey, "zz")
+	assert.ErrorIs(t, verify(tampered, "/svc/Method", message), xgen.ErrMalformedSignature)
+	tampered = md.Copy()
+	tampered.Delete(NonceMetadataKey)
+	assert.ErrorIs(t, verify(tampered, "/svc/Method", message), xgen.ErrMissingNonce)
+	assert.ErrorIs(t, verify(metadata.MD{}, "/svc/Method", message), xgen.ErrMissingSignature)
+	// A gRPC signature is not a valid HTTP signature for the same path, with
+	// any method.
+	md = incoming("acme")
+	ts, nonce, sig := md.Get(TimestampMetadataKey)[0], md.Get(NonceMetadataKey)[0], md.Get(SignatureMetadataKey)[0]
+	body := signedBody(md, []string{"x-tenant-id"}, message)
+	for _, method := range []string{"GRPC", "POST", "xgen-grpc"} {
+		assert.False(t, xgen.VerifySignatureWithNonce("secret-1", method, "/svc/Method", ts, nonce, body, sig), method)
+		assert.False(t, xgen.VerifySignature("secret-1", method, "/svc/Method", ts, nonce+"\n"+body, sig), method)
+	}
+	assert.NoError(t, verify(md, "/svc/Method", message))
+}
+
+func TestGRPCSignatureOptions(t *testing.T) {
+	_, err := NewUnaryClientInterceptor(ClientOptions{})
+	assert.Error(t, err)
+	_, err = NewStreamClientInterceptor(ClientOptions{Secret: "s", SignedMetadata: []string{"bad key"}})
+	assert.Error(t, err)
+	_, err = NewUnaryServerInterceptor(ServerOptions{})
+	assert.Error(t, err)
+	_, err = NewStreamServerInterceptor(ServerOptions{
+		LookupSecret: xgen.StaticSecretLookup("s"), Timestamp: xgen.TimestampPolicy{Format: xgen.TimestampFormat(99)},
+	})
+	assert.Error(t, err)
+
+	_, err = marshalMessage("not a message")
+	assert.Error(t, err)
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(DefaultErrorHandler(context.Background(), xgen.ErrTimestampInFuture)))
+	assert.Equal(t, codes.Unavailable, status.Code(DefaultErrorHandler(context.Background(), xgen.ErrNonceStoreFull)))
+	assert.Equal(t, codes.Internal, status.Code(DefaultErrorHandler(context.Background(), context.DeadlineExceeded)))
+}